@@ -0,0 +1,62 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package adeia
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"time"
+)
+
+// AttachmentTypes is the list of content-types (as sniffed from their contents)
+// that can be uploaded as Attachments, like scans of medical certificates.
+var AttachmentTypes = []string{"application/pdf", "image/jpeg", "image/png"}
+
+// Attachment represents a supporting document that a User uploaded. The contents
+// are stored in the blob store, at Key.
+type Attachment struct {
+	// ID is the auto-incremented primary key of the Attachment.
+	ID int `db:"id" json:"id"`
+
+	// UserID is the ID of the User that uploaded the Attachment.
+	UserID int `db:"user_id" json:"-"`
+
+	// Key is the key of the contents in the blob store.
+	Key string `db:"key" json:"-"`
+
+	// Filename is the name of the file, as sent by the client.
+	Filename string `db:"filename" json:"filename"`
+
+	// ContentType is the content-type of the file, sniffed from its contents.
+	ContentType string `db:"content_type" json:"content_type"`
+
+	// Size is the size of the file (in bytes).
+	Size int64 `db:"size" json:"size"`
+
+	// Checksum is the hex-encoded SHA256 checksum of the file.
+	Checksum string `db:"checksum" json:"checksum"`
+
+	// CreatedAt is the time at which the Attachment was uploaded.
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+
+	// DownloadURL is a signed, time-limited link to download the file. It is not
+	// stored, and is only set when the Attachment is fetched.
+	DownloadURL string `db:"-" json:"download_url,omitempty"`
+}
+
+// AttachmentRepo is the interface for all the repository functions on the
+// Attachment model.
+type AttachmentRepo interface {
+	GetByID(ctx context.Context, id int) (*Attachment, error)
+	Insert(ctx context.Context, a *Attachment) (lastInsertID int, err error)
+}
+
+// AttachmentService is the interface for all the business rules of Attachments.
+type AttachmentService interface {
+	GetAttachment(ctx context.Context, u *User, id int, viewAll bool) (*Attachment, url.Values, error)
+	OpenAttachment(ctx context.Context, id int, q url.Values) (*Attachment, io.ReadCloser, error)
+	UploadAttachment(ctx context.Context, u *User, filename, contentType, checksum string, content []byte) (*Attachment, error)
+}
@@ -0,0 +1,160 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package adeia
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"adeia/pkg/query"
+)
+
+// Actions that are recorded in the audit log.
+const (
+	AuditUserCreate            = "user.create"
+	AuditUserUpdate            = "user.update"
+	AuditUserDeactivate        = "user.deactivate"
+	AuditUserDelete            = "user.delete"
+	AuditUserRestore           = "user.restore"
+	AuditUserUnlock            = "user.unlock"
+	AuditUserPreferencesUpdate = "user_preferences.update"
+	AuditLogLevelUpdate        = "log_level.update"
+	AuditRoleUpdate            = "role.update"
+)
+
+// Types of the targets of AuditEvents.
+const (
+	AuditTargetUser     = "user"
+	AuditTargetLogLevel = "log_level"
+	AuditTargetRole     = "role"
+)
+
+// AuditEvent represents an administrative action, recorded in the audit log. The
+// audit log is append-only, and each AuditEvent is chained to the previous one by
+// its hash, so that deleting or editing an AuditEvent is detected.
+type AuditEvent struct {
+	// ID is the auto-incremented primary key of the AuditEvent. It is also the
+	// order of the hash chain.
+	ID int `db:"id" json:"id"`
+
+	// PrevHash is the Hash of the previous AuditEvent; it is empty for the first one.
+	PrevHash []byte `db:"prev_hash" json:"prev_hash"`
+
+	// Hash is the hash of PrevHash and the rest of the fields of the AuditEvent,
+	// except the ID.
+	Hash []byte `db:"hash" json:"hash"`
+
+	// Actor is the employee ID of the User who performed the action. It is empty
+	// for actions performed by the system.
+	Actor string `db:"actor" json:"actor"`
+
+	// Action is the action that was performed, like AuditUserUpdate.
+	Action string `db:"action" json:"action"`
+
+	// TargetType is the type of the resource that was acted upon, like AuditTargetUser.
+	TargetType string `db:"target_type" json:"target_type"`
+
+	// TargetID identifies the resource that was acted upon, like the employee ID
+	// of a User.
+	TargetID string `db:"target_id" json:"target_id"`
+
+	// Before has the fields of the target that were changed, as they were before
+	// the action. It is nil for actions that create the target.
+	Before json.RawMessage `db:"before" json:"before"`
+
+	// After has the fields of the target that were changed, as they are after the
+	// action. It is nil for actions that delete the target.
+	After json.RawMessage `db:"after" json:"after"`
+
+	// IP is the IP address that the action was requested from.
+	IP string `db:"ip" json:"ip"`
+
+	// RequestID is the ID of the request that performed the action, so that it
+	// can be matched with the logs.
+	RequestID string `db:"request_id" json:"request_id"`
+
+	// CreatedAt is the time (in UTC) at which the action was performed.
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// AuditVerification represents the result of verifying the hash chain of the
+// audit log.
+type AuditVerification struct {
+	// Checked is the no. of AuditEvents that were checked.
+	Checked int `json:"checked"`
+
+	// OK represents whether the chain is intact.
+	OK bool `json:"ok"`
+
+	// BrokenAt is the ID of the first AuditEvent that does not match the chain,
+	// when it is not OK.
+	BrokenAt int `json:"broken_at,omitempty"`
+
+	// Reason describes why the chain is broken at BrokenAt.
+	Reason string `json:"reason,omitempty"`
+
+	// Head is the (hex-encoded) Hash of the last AuditEvent that was checked.
+	// Deleting the latest AuditEvents does not break the chain, so the Head of an
+	// earlier verification can be passed to Verify, to detect it.
+	Head string `json:"head"`
+}
+
+// AuditActor represents who requested an action, for the audit log.
+type AuditActor struct {
+	EmployeeID string
+	IP         string
+	RequestID  string
+}
+
+type auditActorCtxKey struct{}
+
+// NewAuditActorContext returns a copy of ctx that carries the AuditActor of the
+// request.
+func NewAuditActorContext(ctx context.Context, a *AuditActor) context.Context {
+	return context.WithValue(ctx, auditActorCtxKey{}, a)
+}
+
+// AuditActorFromContext returns the AuditActor carried by ctx. nil is returned if
+// there is none.
+func AuditActorFromContext(ctx context.Context) *AuditActor {
+	if a, ok := ctx.Value(auditActorCtxKey{}).(*AuditActor); ok {
+		return a
+	}
+	return nil
+}
+
+// AuditEventQueryOptions represents the fields that a list of AuditEvents can be
+// sorted and filtered on.
+var AuditEventQueryOptions = &query.Options{
+	Sortable: map[string]string{
+		"id":         "id",
+		"created_at": "created_at",
+	},
+	Filterable: map[string]query.Field{
+		"actor":       {Column: "actor"},
+		"action":      {Column: "action"},
+		"target_type": {Column: "target_type"},
+		"target_id":   {Column: "target_id"},
+		"request_id":  {Column: "request_id"},
+	},
+	DefaultSort: "-id",
+}
+
+// AuditRepo is the interface for all the repository functions on the AuditEvent
+// model. AuditEvents can only be inserted, never updated or deleted.
+type AuditRepo interface {
+	GetAll(ctx context.Context, spec *query.Spec, since, until *time.Time) (events []*AuditEvent, nextCursor string, err error)
+	GetAfter(ctx context.Context, id, limit int) ([]*AuditEvent, error)
+	GetLast(ctx context.Context) (*AuditEvent, error)
+	Insert(ctx context.Context, e *AuditEvent) (lastInsertID int, err error)
+}
+
+// AuditService is the interface for all the business rules of the audit log.
+type AuditService interface {
+	GetAllEvents(ctx context.Context, spec *query.Spec, since, until *time.Time) ([]*AuditEvent, string, error)
+	Record(ctx context.Context, action, targetType, targetID string, before, after interface{})
+	Verify(ctx context.Context, head string) (*AuditVerification, error)
+}
@@ -0,0 +1,70 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"adeia/internal/config"
+	"adeia/internal/repo"
+	"adeia/internal/service"
+	"adeia/internal/store/pg"
+	"adeia/pkg/log/zap"
+	"adeia/pkg/util/ioutil"
+)
+
+// usage lists the commands of adeia, other than serving the API (which is the
+// default, when there are no args).
+const usage = `usage: adeia [command]
+
+commands:
+  audit verify [head]    verify the hash chain of the audit log; if head (printed
+                         by an earlier verification) is given, the chain must
+                         still contain it`
+
+// runCommand runs the command in args.
+func runCommand(conf *config.Config, args []string, w io.Writer) error {
+	if len(args) >= 2 && len(args) <= 3 && args[0] == "audit" && args[1] == "verify" {
+		head := ""
+		if len(args) == 3 {
+			head = args[2]
+		}
+		return auditVerify(conf, head, w)
+	}
+
+	return fmt.Errorf("unknown command %q\n%s", strings.Join(args, " "), usage)
+}
+
+// auditVerify verifies the hash chain of the audit log, and that it contains head
+// (if it is not empty), and writes the result to w. An error is returned if the
+// chain is broken.
+func auditVerify(conf *config.Config, head string, w io.Writer) (err error) {
+	logger, err := zap.New(&conf.LoggerConfig)
+	if err != nil {
+		return err
+	}
+
+	dbConn, err := pg.New(&conf.DBConfig)
+	if err != nil {
+		return fmt.Errorf("cannot initialize connection to db: %v", err)
+	}
+	defer ioutil.CheckCloseErr(dbConn, &err)
+
+	v, err := service.NewAuditService(logger, repo.NewAuditRepo(dbConn)).Verify(context.Background(), head)
+	if err != nil {
+		return err
+	}
+
+	if !v.OK {
+		_, _ = fmt.Fprintf(w, "audit log is broken at event %d (%s), after checking %d events\n", v.BrokenAt, v.Reason, v.Checked)
+		return errors.New("audit log verification failed")
+	}
+	_, _ = fmt.Fprintf(w, "audit log is intact; checked %d events, head is %s\n", v.Checked, v.Head)
+	return nil
+}
@@ -5,16 +5,26 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"time"
+	_ "time/tzdata" // embed the time zone database, to validate the time zones of users
 
+	"adeia/internal/blob"
+	"adeia/internal/blob/fs"
 	"adeia/internal/cache/redis"
 	"adeia/internal/config"
 	"adeia/internal/http"
 	"adeia/internal/http/server"
+	"adeia/internal/mailer/smtp"
+	"adeia/internal/metrics"
+	"adeia/internal/notifier"
 	"adeia/internal/repo"
 	"adeia/internal/service"
 	"adeia/internal/store/pg"
+	"adeia/pkg/http/middleware"
+	"adeia/pkg/i18n"
 	"adeia/pkg/log/zap"
 	"adeia/pkg/util/constants"
 	"adeia/pkg/util/ioutil"
@@ -30,6 +40,10 @@ func main() {
 	conf, err := config.Load(confFile)
 	checkErr(err)
 
+	if len(os.Args) > 1 {
+		checkErr(runCommand(conf, os.Args[1:], os.Stdout))
+		return
+	}
 	checkErr(run(conf))
 }
 
@@ -52,23 +66,132 @@ func run(conf *config.Config) error {
 		ioutil.CheckCloseErr(cacheConn, &err)
 	}()
 
+	catalogue, err := i18n.Load(conf.I18nConfig.LocalesPath)
+	if err != nil {
+		logger.Debugf("failed to load message catalogues: %v", err)
+		return err
+	}
+
+	mailer, err := smtp.New(&conf.MailerConfig, catalogue)
+	if err != nil {
+		logger.Debugf("failed to initialize mailer: %v", err)
+		return err
+	}
+
+	blobs, err := newBlobStore(&conf.BlobConfig)
+	if err != nil {
+		logger.Debugf("failed to initialize blob store: %v", err)
+		return err
+	}
+
 	// init repos
 	logger.Debug("initializing repositories...")
 	userRepo := repo.NewUserRepo(dbConn)
+	userPrefsRepo := repo.NewUserPreferencesRepo(dbConn)
+	roleRepo := repo.NewRoleRepo(dbConn)
+	sessionRepo := repo.NewSessionRepo(dbConn)
+	totpRepo := repo.NewTOTPRepo(dbConn)
+	auditRepo := repo.NewAuditRepo(dbConn)
+	attachmentRepo := repo.NewAttachmentRepo(dbConn)
+	pendingNotificationRepo := repo.NewPendingNotificationRepo(dbConn)
+
+	// the pending digests are stored, so they are not lost on shutdown, but are sent
+	// anyway, so that they are not delayed until the next flush
+	n := notifier.New(logger, mailer, pendingNotificationRepo)
+	stopDigests := make(chan struct{})
+	defer func() {
+		close(stopDigests)
+		logger.Debug("sending pending digests...")
+		if err := n.FlushDigests(context.Background()); err != nil {
+			logger.Errorf("cannot send digest emails: %v", err)
+		}
+	}()
+	go n.Run(constants.DigestInterval*time.Second, stopDigests)
 
 	// init services
 	logger.Debug("initializing services...")
-	userService := service.NewUserService(logger, userRepo)
+	auditService := service.NewAuditService(logger, auditRepo)
+	userService := service.NewUserService(logger, userRepo, userPrefsRepo, cacheConn, n, auditService, dbConn)
+	roleService := service.NewRoleService(logger, roleRepo, sessionRepo, auditService, dbConn)
+	attachmentService := service.NewAttachmentService(logger, attachmentRepo, blobs, blob.NewSigner(&conf.BlobConfig))
+	authService := service.NewAuthService(
+		logger,
+		userRepo,
+		userPrefsRepo,
+		roleRepo,
+		sessionRepo,
+		totpRepo,
+		cacheConn,
+		n,
+		&conf.AuthConfig,
+		conf.ServerConfig.JWTSecret,
+		dbConn,
+	)
 
 	// init controllers
 	logger.Debug("initializing controllers...")
 	userController := http.NewUserController(logger, userService)
+	errorController := http.NewErrorController(logger)
+	authController := http.NewAuthController(logger, authService)
+	meController := http.NewMeController(logger, authService, userService)
+	adminController := http.NewAdminController(logger, logger, auditService)
+	attachmentController := http.NewAttachmentController(logger, attachmentService)
+	roleController := http.NewRoleController(logger, roleService)
+
+	clientIP, err := http.ClientIP(&conf.ServerConfig)
+	if err != nil {
+		logger.Debugf("failed to initialize client ip resolver: %v", err)
+		return err
+	}
+
+	rateLimit, err := http.RateLimit(logger, &conf.ServerConfig, cacheConn)
+	if err != nil {
+		logger.Debugf("failed to initialize rate-limiter: %v", err)
+		return err
+	}
 
-	srv := server.New(&conf.ServerConfig, logger, userController)
+	// the last middleware is the outermost; the standard ones wrap the others, the
+	// client ip is resolved before it is used, the locale is set from
+	// Accept-Language before anything else can respond, requests are rate-limited
+	// before they are authenticated, and the locale is switched to the saved locale
+	// of the user once they are authenticated
+	chain := middleware.NewChain(
+		i18n.Middleware(catalogue, http.SavedLocale),
+		http.Authenticate(logger, authService),
+		rateLimit,
+		i18n.Middleware(catalogue, nil),
+		clientIP,
+	)
+	chain = chain.Append(http.StandardMiddlewares(logger, &conf.MiddlewareConfig)...)
+
+	srv := server.New(
+		&conf.ServerConfig,
+		logger,
+		chain,
+		userController,
+		errorController,
+		authController,
+		meController,
+		adminController,
+		attachmentController,
+		roleController,
+	)
 	srv.BindControllers()
-	srv.Serve()
 
-	return nil
+	reg, err := metrics.NewRegistry(metrics.NewDBCollector(dbConn), metrics.NewCacheCollector(cacheConn))
+	if err != nil {
+		logger.Debugf("failed to initialize metrics: %v", err)
+		return err
+	}
+	srv.HandleAdmin("/metrics", metrics.Handler(reg))
+
+	srv.AddReadinessCheck("postgres", dbConn.PingContext)
+	srv.AddReadinessCheck("redis", cacheConn.PingContext)
+	srv.AddReadinessCheck("migrations", func(ctx context.Context) error {
+		return dbConn.CheckSchema(ctx, repo.Schema)
+	})
+
+	return srv.Serve()
 }
 
 func initConnections(conf *config.Config) (*pg.PostgresDB, *redis.Redis, error) {
@@ -85,6 +208,15 @@ func initConnections(conf *config.Config) (*pg.PostgresDB, *redis.Redis, error)
 	return dbConn, cacheConn, nil
 }
 
+// newBlobStore creates the BlobStore of the configured driver.
+func newBlobStore(conf *config.BlobConfig) (blob.BlobStore, error) {
+	switch conf.Driver {
+	case "fs":
+		return fs.New(conf)
+	}
+	return nil, fmt.Errorf("unsupported storage driver %q", conf.Driver)
+}
+
 func checkErr(err error) {
 	if err != nil {
 		panic(err.Error())
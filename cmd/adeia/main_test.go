@@ -5,6 +5,7 @@
 package main
 
 import (
+	"io/ioutil"
 	"os"
 	"testing"
 
@@ -31,3 +32,15 @@ func TestGetEnv(t *testing.T) {
 		assert.Equal(t, want, got)
 	})
 }
+
+func TestRunCommand(t *testing.T) {
+	t.Run("return error on unknown command", func(t *testing.T) {
+		err := runCommand(nil, []string{"foo"}, ioutil.Discard)
+		assert.Error(t, err)
+	})
+
+	t.Run("return error on extra args to audit verify", func(t *testing.T) {
+		err := runCommand(nil, []string{"audit", "verify", "abc", "def"}, ioutil.Discard)
+		assert.Error(t, err)
+	})
+}
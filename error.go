@@ -12,62 +12,179 @@ import (
 
 var (
 	// ErrInvalidRequestBody is the error returned when the request body is not JSON.
-	ErrInvalidRequestBody = errs.ResponseError{
+	ErrInvalidRequestBody = errs.Register(errs.ResponseError{
 		StatusCode: http.StatusUnsupportedMediaType,
 		ErrorCode:  "INVALID_REQUEST_BODY",
 		Message:    "Request body must be JSON",
-	}
+	}, "The request body is not of the expected content-type (usually JSON).")
 
 	// ErrInvalidJSON is the error returned when the request body contains invalid JSON.
-	ErrInvalidJSON = errs.ResponseError{
+	ErrInvalidJSON = errs.Register(errs.ResponseError{
 		StatusCode: http.StatusBadRequest,
 		ErrorCode:  "INVALID_JSON",
 		Message:    "An error occurred while parsing the request body",
-	}
+	}, "The request body is empty, contains malformed JSON or contains more than one JSON object.")
 
 	// ErrValidationFailed is the error returned when some of the fields do not conform to
 	// the validation rules. Add a list of ValidationErrors to this to specify the fields
 	// that are failing.
-	ErrValidationFailed = errs.ResponseError{
+	ErrValidationFailed = errs.Register(errs.ResponseError{
 		StatusCode: http.StatusUnprocessableEntity,
 		ErrorCode:  "VALIDATION_FAILED",
 		Message:    "Validation failed for some fields",
-	}
+	}, "Some of the fields do not conform to their validation rules. The failing fields are listed in validation_errors.")
 
 	// ErrRequestBodyTooLarge is the error returned when the request body is too large.
-	ErrRequestBodyTooLarge = errs.ResponseError{
+	ErrRequestBodyTooLarge = errs.Register(errs.ResponseError{
 		StatusCode: http.StatusRequestEntityTooLarge,
 		ErrorCode:  "REQUEST_BODY_TOO_LARGE",
 		Message:    "Request body is too large",
-	}
+	}, "The request body (or uploaded file) exceeds the maximum allowed size.")
 
 	// ErrUnknownField is the error returned when the request body contains an unknown field.
-	ErrUnknownField = errs.ResponseError{
+	ErrUnknownField = errs.Register(errs.ResponseError{
 		StatusCode: http.StatusBadRequest,
 		ErrorCode:  "UNKNOWN_FIELD",
 		Message:    "An unknown field is present in the request body",
-	}
+	}, "The request body contains a field that is not accepted by the endpoint.")
 
 	// ErrDatabaseError is the error returned when a database error occurs.
 	// It is made as generic as possible, so that internals are not revealed outside.
-	ErrDatabaseError = errs.ResponseError{
+	ErrDatabaseError = errs.Register(errs.ResponseError{
 		StatusCode: http.StatusInternalServerError,
 		ErrorCode:  "DATABASE_ERROR",
-	}
+	}, "An internal error occurred while accessing the database.")
 
 	// ErrResourceAlreadyExists is the error returned when a resource already
 	// exists with the specified fields.
-	ErrResourceAlreadyExists = errs.ResponseError{
+	ErrResourceAlreadyExists = errs.Register(errs.ResponseError{
 		StatusCode: http.StatusBadRequest,
 		ErrorCode:  "RESOURCE_ALREADY_EXISTS",
 		Message:    "A resource already exists with the specified fields",
-	}
+	}, "A resource already exists with the specified unique fields, like email.")
+
+	// ErrResourceConflict is the error returned when a resource cannot be changed,
+	// as it would conflict with another resource.
+	ErrResourceConflict = errs.Register(errs.ResponseError{
+		StatusCode: http.StatusConflict,
+		ErrorCode:  "RESOURCE_CONFLICT",
+		Message:    "The resource conflicts with an existing resource",
+	}, "The resource cannot be changed, as it would conflict with an existing resource. For example, a deleted user cannot be restored once another user has taken their email or employee ID.")
+
+	// ErrMissingFile is the error returned when a multipart request does not contain
+	// the expected file.
+	ErrMissingFile = errs.Register(errs.ResponseError{
+		StatusCode: http.StatusBadRequest,
+		ErrorCode:  "MISSING_FILE",
+		Message:    "A file must be attached to the request",
+	}, "A multipart request does not contain the expected file.")
+
+	// ErrUnsupportedFileType is the error returned when the content-type of an uploaded
+	// file (as sniffed from its contents) is not one of the allowed types.
+	ErrUnsupportedFileType = errs.Register(errs.ResponseError{
+		StatusCode: http.StatusUnsupportedMediaType,
+		ErrorCode:  "UNSUPPORTED_FILE_TYPE",
+		Message:    "The type of the uploaded file is not supported",
+	}, "The type of the uploaded file, as detected from its contents, is not allowed by the endpoint.")
+
+	// ErrInvalidDownloadLink is the error returned when a download link is malformed,
+	// has been tampered with, or has expired.
+	ErrInvalidDownloadLink = errs.Register(errs.ResponseError{
+		StatusCode: http.StatusForbidden,
+		ErrorCode:  "INVALID_DOWNLOAD_LINK",
+		Message:    "The download link is invalid or has expired",
+	}, "The download link is malformed, has been tampered with, or has expired. Fetch the resource again for a new link.")
+
+	// ErrResourceNotFound is the error returned when the requested resource does not exist.
+	ErrResourceNotFound = errs.Register(errs.ResponseError{
+		StatusCode: http.StatusNotFound,
+		ErrorCode:  "RESOURCE_NOT_FOUND",
+		Message:    "The requested resource was not found",
+	}, "The requested resource does not exist (or has been deleted).")
 
 	// ErrParseReqBodyFailed is the error returned when the request body is okay,
 	// but something else happened and we cannot parse it.
-	ErrParseReqBodyFailed = errs.ResponseError{
+	ErrParseReqBodyFailed = errs.Register(errs.ResponseError{
 		StatusCode: http.StatusInternalServerError,
 		ErrorCode:  "PARSE_REQUEST_BODY_FAILED",
 		Message:    "An error occurred while parsing the request body",
-	}
+	}, "The request body is well-formed, but an internal error occurred while parsing it.")
+
+	// ErrInvalidCredentials is the error returned when the email or password is
+	// incorrect. The same error is returned for both, so that the existence of an
+	// account is not revealed.
+	ErrInvalidCredentials = errs.Register(errs.ResponseError{
+		StatusCode: http.StatusUnauthorized,
+		ErrorCode:  "INVALID_CREDENTIALS",
+		Message:    "The email or password is incorrect",
+	}, "The email or password is incorrect, or the account is not activated.")
+
+	// ErrUnauthenticated is the error returned when a request that requires a
+	// logged-in user is made without an access token.
+	ErrUnauthenticated = errs.Register(errs.ResponseError{
+		StatusCode: http.StatusUnauthorized,
+		ErrorCode:  "UNAUTHENTICATED",
+		Message:    "You must be logged in to access this resource",
+	}, "The request requires a logged-in user, but does not have an access token in the Authorization header.")
+
+	// ErrPermissionDenied is the error returned when the logged-in user does not have
+	// the permission that a request requires.
+	ErrPermissionDenied = errs.Register(errs.ResponseError{
+		StatusCode: http.StatusForbidden,
+		ErrorCode:  "PERMISSION_DENIED",
+		Message:    "You do not have permission to perform this action",
+	}, "The logged-in user does not have the permission that the request requires. Permissions are granted through the role of the user.")
+
+	// ErrInvalidToken is the error returned when an access or refresh token is
+	// invalid, expired or revoked.
+	ErrInvalidToken = errs.Register(errs.ResponseError{
+		StatusCode: http.StatusUnauthorized,
+		ErrorCode:  "INVALID_TOKEN",
+		Message:    "The token is invalid or has expired",
+	}, "The access or refresh token is malformed, has expired, or its session has been revoked. Log in again to continue.")
+
+	// ErrInvalidOTP is the error returned when a TOTP or recovery code is incorrect,
+	// or has already been used.
+	ErrInvalidOTP = errs.Register(errs.ResponseError{
+		StatusCode: http.StatusUnauthorized,
+		ErrorCode:  "INVALID_OTP",
+		Message:    "The code is incorrect or has already been used",
+	}, "The TOTP code (from the authenticator app) or recovery code is incorrect, has expired, or has already been used.")
+
+	// ErrMFAMandatory is the error returned when a User tries to disable two-factor
+	// authentication, while their role requires it.
+	ErrMFAMandatory = errs.Register(errs.ResponseError{
+		StatusCode: http.StatusForbidden,
+		ErrorCode:  "MFA_MANDATORY",
+		Message:    "Two-factor authentication is required for your role",
+	}, "Two-factor authentication cannot be disabled, as it is required by the role of the user.")
+
+	// ErrAccountLocked is the error returned when an account (or the IP address of
+	// the client) is temporarily locked, due to too many failed login attempts.
+	ErrAccountLocked = errs.Register(errs.ResponseError{
+		StatusCode: http.StatusLocked,
+		ErrorCode:  "ACCOUNT_LOCKED",
+		Message:    "Too many failed login attempts. Try again later",
+	}, "The account, or the IP address of the client, is temporarily locked due to too many failed login attempts. An admin can unlock the account before the lockout expires.")
+
+	// ErrLoginThrottled is the error returned when a login is attempted too soon
+	// after a failed one.
+	ErrLoginThrottled = errs.Register(errs.ResponseError{
+		StatusCode: http.StatusTooManyRequests,
+		ErrorCode:  "LOGIN_THROTTLED",
+		Message:    "Please wait a few seconds before trying again",
+	}, "A login was attempted too soon after a failed one. The wait doubles with each failed attempt, until the account is locked.")
+
+	// ErrRateLimited is the error returned when a client makes too many requests.
+	ErrRateLimited = errs.Register(errs.ResponseError{
+		StatusCode: http.StatusTooManyRequests,
+		ErrorCode:  "RATE_LIMITED",
+		Message:    "Too many requests. Try again later",
+	}, "The client has exceeded the rate-limit. The Retry-After header has the no. of seconds to wait, and the RateLimit-* headers describe the limit.")
+
+	// ErrInternalError is the error returned when an unexpected internal error occurs.
+	ErrInternalError = errs.Register(errs.ResponseError{
+		StatusCode: http.StatusInternalServerError,
+		ErrorCode:  "INTERNAL_ERROR",
+	}, "An unexpected internal error occurred.")
 )
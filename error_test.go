@@ -0,0 +1,67 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package adeia
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"strings"
+	"testing"
+
+	"adeia/pkg/errs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestErrorsAreRegistered fails if an error is declared as a bare errs.ResponseError
+// literal, instead of being registered in the error catalogue using errs.Register.
+func TestErrorsAreRegistered(t *testing.T) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	assert.Nil(t, err)
+
+	for _, f := range pkgs["adeia"].Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			spec, ok := n.(*ast.ValueSpec)
+			if !ok {
+				return true
+			}
+			for i, v := range spec.Values {
+				if isResponseErrorLit(v) {
+					t.Errorf("%s: %s is not registered, wrap it with errs.Register",
+						fset.Position(v.Pos()), spec.Names[i].Name)
+				}
+			}
+			return false
+		})
+	}
+}
+
+func isResponseErrorLit(e ast.Expr) bool {
+	lit, ok := e.(*ast.CompositeLit)
+	if !ok {
+		return false
+	}
+	sel, ok := lit.Type.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == "ResponseError"
+}
+
+func TestErrorCatalogue(t *testing.T) {
+	for _, re := range []errs.ResponseError{
+		ErrInvalidRequestBody,
+		ErrValidationFailed,
+		ErrDatabaseError,
+		ErrResourceNotFound,
+	} {
+		e, ok := errs.Lookup(re.ErrorCode)
+		assert.True(t, ok)
+		assert.Equal(t, re.StatusCode, e.StatusCode)
+		assert.NotEmpty(t, e.Description)
+	}
+}
@@ -0,0 +1,28 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var (
+	// ErrNotFound is returned when a blob does not exist for the specified key.
+	ErrNotFound = errors.New("blob not found")
+
+	// ErrInvalidKey is returned when a key cannot be used to address a blob.
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// BlobStore is an interface for all blob-storage-related functions, that
+// implementations must implement. Blobs are addressed by slash-separated keys,
+// like "leaves/42/certificate".
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
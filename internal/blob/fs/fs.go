@@ -0,0 +1,119 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package fs
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"adeia/internal/blob"
+	"adeia/internal/config"
+)
+
+// FS represents a blob store backed by the local filesystem.
+type FS struct {
+	root string
+}
+
+// New creates a new *FS, creating the root directory if it does not exist.
+func New(conf *config.BlobConfig) (*FS, error) {
+	root, err := filepath.Abs(conf.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(root, 0750); err != nil {
+		return nil, err
+	}
+
+	return &FS{root}, nil
+}
+
+// Put writes the contents of r to the blob at key, replacing it if it exists.
+// The contents are first written to a temporary file and then renamed, so that
+// readers never see a partially-written blob.
+func (f *FS) Put(_ context.Context, key string, r io.Reader) (err error) {
+	path, err := f.path(key)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = io.Copy(tmp, r); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get opens the blob at key for reading. The caller must close the returned
+// io.ReadCloser.
+func (f *FS) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	path, err := f.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, blob.ErrNotFound
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// Delete deletes the blob at key.
+func (f *FS) Delete(_ context.Context, key string) error {
+	path, err := f.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return blob.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// path resolves key to a path inside the root directory. Keys that are empty,
+// absolute or that escape the root directory are rejected.
+func (f *FS) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) {
+		return "", blob.ErrInvalidKey
+	}
+
+	path := filepath.Join(f.root, filepath.FromSlash(key))
+	if !strings.HasPrefix(path, f.root+string(filepath.Separator)) {
+		return "", blob.ErrInvalidKey
+	}
+	return path, nil
+}
@@ -0,0 +1,93 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package fs
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"adeia/internal/blob"
+	"adeia/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setup(t *testing.T) *FS {
+	t.Parallel()
+	f, err := New(&config.BlobConfig{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestFS_Put(t *testing.T) {
+	t.Run("put and get blob", func(t *testing.T) {
+		f := setup(t)
+		ctx := context.Background()
+		want := "foobar"
+
+		err := f.Put(ctx, "leaves/1/cert", strings.NewReader(want))
+		assert.Nil(t, err)
+
+		rc, err := f.Get(ctx, "leaves/1/cert")
+		if assert.Nil(t, err) {
+			defer rc.Close()
+			got, _ := ioutil.ReadAll(rc)
+			assert.Equal(t, want, string(got))
+		}
+	})
+
+	t.Run("overwrite existing blob", func(t *testing.T) {
+		f := setup(t)
+		ctx := context.Background()
+
+		_ = f.Put(ctx, "foo", strings.NewReader("bar"))
+		_ = f.Put(ctx, "foo", strings.NewReader("baz"))
+
+		rc, err := f.Get(ctx, "foo")
+		if assert.Nil(t, err) {
+			defer rc.Close()
+			got, _ := ioutil.ReadAll(rc)
+			assert.Equal(t, "baz", string(got))
+		}
+	})
+
+	t.Run("invalid keys", func(t *testing.T) {
+		f := setup(t)
+		ctx := context.Background()
+		for _, key := range []string{"", "/etc/passwd", "../foo", "foo/../../bar", `foo\bar`} {
+			err := f.Put(ctx, key, strings.NewReader("bar"))
+			assert.Equal(t, blob.ErrInvalidKey, err, key)
+		}
+	})
+}
+
+func TestFS_Get(t *testing.T) {
+	t.Run("blob does not exist", func(t *testing.T) {
+		f := setup(t)
+		_, err := f.Get(context.Background(), "foo")
+		assert.Equal(t, blob.ErrNotFound, err)
+	})
+}
+
+func TestFS_Delete(t *testing.T) {
+	t.Run("delete existing blob", func(t *testing.T) {
+		f := setup(t)
+		ctx := context.Background()
+		_ = f.Put(ctx, "foo", strings.NewReader("bar"))
+
+		assert.Nil(t, f.Delete(ctx, "foo"))
+		_, err := f.Get(ctx, "foo")
+		assert.Equal(t, blob.ErrNotFound, err)
+	})
+
+	t.Run("blob does not exist", func(t *testing.T) {
+		f := setup(t)
+		assert.Equal(t, blob.ErrNotFound, f.Delete(context.Background(), "foo"))
+	})
+}
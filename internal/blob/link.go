@@ -0,0 +1,84 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package blob
+
+import (
+	"crypto/hmac"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"adeia/internal/config"
+	"adeia/pkg/util/crypto"
+)
+
+const (
+	expiresParam   = "expires"
+	signatureParam = "signature"
+)
+
+var (
+	// ErrLinkExpired is returned when a download link is past its expiry.
+	ErrLinkExpired = errors.New("download link has expired")
+
+	// ErrInvalidSignature is returned when a download link is malformed or its
+	// signature does not match.
+	ErrInvalidSignature = errors.New("download link has an invalid signature")
+)
+
+// Signer signs and verifies time-limited download links for blobs, so that a
+// blob can be downloaded only by those who were handed a link.
+type Signer struct {
+	secret []byte
+	expiry time.Duration
+	now    func() time.Time
+}
+
+// NewSigner creates a new *Signer.
+func NewSigner(conf *config.BlobConfig) *Signer {
+	return &Signer{
+		secret: []byte(conf.SigningSecret),
+		expiry: time.Duration(conf.LinkExpiry) * time.Second,
+		now:    time.Now,
+	}
+}
+
+// Sign returns the query parameters that authorize downloading the blob at key,
+// until the link expires.
+func (s *Signer) Sign(key string) url.Values {
+	expires := strconv.FormatInt(s.now().Add(s.expiry).Unix(), 10)
+	return url.Values{
+		expiresParam:   []string{expires},
+		signatureParam: []string{crypto.EncodeBase64(s.sign(key, expires))},
+	}
+}
+
+// Verify checks if the query parameters of a download link authorize downloading
+// the blob at key.
+func (s *Signer) Verify(key string, q url.Values) error {
+	expires := q.Get(expiresParam)
+	sig, err := crypto.DecodeBase64(q.Get(signatureParam))
+	if err != nil || expires == "" {
+		return ErrInvalidSignature
+	}
+
+	if !hmac.Equal(sig, s.sign(key, expires)) {
+		return ErrInvalidSignature
+	}
+
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if s.now().After(time.Unix(unix, 0)) {
+		return ErrLinkExpired
+	}
+	return nil
+}
+
+func (s *Signer) sign(key, expires string) []byte {
+	return crypto.HMAC(s.secret, []byte(key+"\n"+expires))
+}
@@ -0,0 +1,54 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package blob
+
+import (
+	"testing"
+	"time"
+
+	"adeia/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSigner(t *testing.T) {
+	setup := func(t *testing.T) *Signer {
+		t.Parallel()
+		return NewSigner(&config.BlobConfig{SigningSecret: "secret", LinkExpiry: 60})
+	}
+
+	t.Run("valid link", func(t *testing.T) {
+		s := setup(t)
+		q := s.Sign("leaves/1/cert")
+		assert.Nil(t, s.Verify("leaves/1/cert", q))
+	})
+
+	t.Run("link for a different key", func(t *testing.T) {
+		s := setup(t)
+		q := s.Sign("leaves/1/cert")
+		assert.Equal(t, ErrInvalidSignature, s.Verify("leaves/2/cert", q))
+	})
+
+	t.Run("tampered expiry", func(t *testing.T) {
+		s := setup(t)
+		q := s.Sign("leaves/1/cert")
+		q.Set(expiresParam, "99999999999")
+		assert.Equal(t, ErrInvalidSignature, s.Verify("leaves/1/cert", q))
+	})
+
+	t.Run("missing signature", func(t *testing.T) {
+		s := setup(t)
+		q := s.Sign("leaves/1/cert")
+		q.Del(signatureParam)
+		assert.Equal(t, ErrInvalidSignature, s.Verify("leaves/1/cert", q))
+	})
+
+	t.Run("expired link", func(t *testing.T) {
+		s := setup(t)
+		q := s.Sign("leaves/1/cert")
+		s.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+		assert.Equal(t, ErrLinkExpired, s.Verify("leaves/1/cert", q))
+	})
+}
@@ -8,6 +8,11 @@ package cache
 // must implement.
 type Cache interface {
 	Close() error
+	Delete(keys ...string) error
 	Get(dest interface{}, key string) error
+	GetDel(dest interface{}, key string) error
+	Incr(key string, seconds int) (int, error)
 	Set(key string, value string) error
+	SetWithExpiry(key, value string, seconds int) error
+	TTL(key string) (int, error)
 }
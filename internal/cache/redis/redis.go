@@ -5,31 +5,136 @@
 package redis
 
 import (
+	"context"
 	"strconv"
+	"sync/atomic"
 
 	"adeia/internal/config"
 
 	"github.com/mediocregopher/radix/v3"
+	"github.com/mediocregopher/radix/v3/trace"
 )
 
 // Redis represents the cache connection instance.
 type Redis struct {
 	*radix.Pool
+	size int
+
+	// counters of the pool, updated atomically
+	connsCreated  uint64
+	connsClosed   uint64
+	commands      uint64
+	commandErrors uint64
+}
+
+// PoolStats represents the stats of the connection pool.
+type PoolStats struct {
+	// Size is the configured no. of connections in the pool.
+	Size int
+
+	// Available is the no. of connections available in the pool.
+	Available int
+
+	// ConnsCreated is the no. of connections created.
+	ConnsCreated uint64
+
+	// ConnsClosed is the no. of connections closed.
+	ConnsClosed uint64
+
+	// Commands is the no. of commands (or pipelines) run.
+	Commands uint64
+
+	// CommandErrors is the no. of commands (or pipelines) that failed.
+	CommandErrors uint64
 }
 
 // New creates a new cache connection instance.
 func New(conf *config.CacheConfig) (*Redis, error) {
+	r := &Redis{size: conf.ConnSize}
+
 	// TODO: add cache auth
 	p, err := radix.NewPool(
 		conf.Network,
 		conf.Host+":"+strconv.Itoa(conf.Port),
 		conf.ConnSize,
+		radix.PoolWithTrace(r.trace()),
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Redis{p}, nil
+	r.Pool = p
+	return r, nil
+}
+
+// Stats returns the stats of the connection pool.
+func (r *Redis) Stats() PoolStats {
+	return PoolStats{
+		Size:          r.size,
+		Available:     r.NumAvailConns(),
+		ConnsCreated:  atomic.LoadUint64(&r.connsCreated),
+		ConnsClosed:   atomic.LoadUint64(&r.connsClosed),
+		Commands:      atomic.LoadUint64(&r.commands),
+		CommandErrors: atomic.LoadUint64(&r.commandErrors),
+	}
+}
+
+// PingContext checks if the cache is reachable. It returns as soon as ctx is done,
+// by closing the connection that the PING is blocked on, so that the connection is
+// discarded instead of being returned to the pool.
+func (r *Redis) PingContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return r.Do(radix.WithConn("", func(c radix.Conn) error {
+		done := make(chan struct{})
+		interrupted := make(chan bool, 1)
+		go func() {
+			select {
+			case <-ctx.Done():
+				_ = c.NetConn().Close()
+				interrupted <- true
+			case <-done:
+				interrupted <- false
+			}
+		}()
+
+		err := c.Do(radix.Cmd(nil, "PING"))
+		close(done)
+		if <-interrupted {
+			// closed again, so that the pool knows that it is closed
+			_ = c.Close()
+			return ctx.Err()
+		}
+		return err
+	}))
+}
+
+// Do runs the action on a connection from the pool, counting it in the stats.
+func (r *Redis) Do(a radix.Action) error {
+	atomic.AddUint64(&r.commands, 1)
+	err := r.Pool.Do(a)
+	if err != nil {
+		atomic.AddUint64(&r.commandErrors, 1)
+	}
+	return err
+}
+
+// trace returns the trace.PoolTrace that counts the connections of the pool. The
+// commands are counted by Do instead, since the pool traces each batch of commands
+// that it pipelines implicitly, along with the commands in it.
+func (r *Redis) trace() trace.PoolTrace {
+	return trace.PoolTrace{
+		ConnCreated: func(c trace.PoolConnCreated) {
+			if c.Err == nil {
+				atomic.AddUint64(&r.connsCreated, 1)
+			}
+		},
+		ConnClosed: func(trace.PoolConnClosed) {
+			atomic.AddUint64(&r.connsClosed, 1)
+		},
+	}
 }
 
 // Get gets the value of the specified key.
@@ -37,12 +142,27 @@ func (r *Redis) Get(dest interface{}, key string) error {
 	return r.Do(radix.Cmd(dest, "GET", key))
 }
 
+// getDelScript gets and deletes a key atomically, like GETDEL (that needs Redis
+// 6.2).
+var getDelScript = radix.NewEvalScript(1, `
+local v = redis.call("GET", KEYS[1])
+if v then
+	redis.call("DEL", KEYS[1])
+end
+return v
+`)
+
+// GetDel gets the value of the key and deletes it, atomically, so that only one
+// caller gets the value. dest is left unchanged if the key does not exist.
+func (r *Redis) GetDel(dest interface{}, key string) error {
+	return r.Do(getDelScript.Cmd(dest, key))
+}
+
 // Set sets the provided key:value pair.
 func (r *Redis) Set(key string, value string) error {
 	return r.Do(radix.Cmd(nil, "SET", key, value))
 }
 
-/*
 // Delete deletes the list of keys.
 func (r *Redis) Delete(keys ...string) error {
 	return r.Do(radix.Cmd(nil, "DEL", keys...))
@@ -53,6 +173,32 @@ func (r *Redis) SetWithExpiry(key, value string, seconds int) error {
 	return r.Do(radix.Cmd(nil, "SET", key, value, "EX", strconv.Itoa(seconds)))
 }
 
+// Incr increments the counter at the specified key, and returns its new value. The
+// TTL of the key is (re)set to the specified seconds, so that the counter expires
+// only when it is not incremented for that long.
+func (r *Redis) Incr(key string, seconds int) (int, error) {
+	var n int
+	err := r.Do(radix.Pipeline(
+		radix.Cmd(&n, "INCR", key),
+		radix.Cmd(nil, "EXPIRE", key, strconv.Itoa(seconds)),
+	))
+	return n, err
+}
+
+// TTL returns the remaining seconds of TTL of the specified key. 0 is returned if
+// the key does not exist, and -1 if it exists without an expiry.
+func (r *Redis) TTL(key string) (int, error) {
+	var ttl int
+	if err := r.Do(radix.Cmd(&ttl, "TTL", key)); err != nil {
+		return 0, err
+	}
+	if ttl == -2 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+/*
 // Expire sets the expiry for a given key.
 func (r *Redis) Expire(key string, seconds int) error {
 	return r.Do(radix.Cmd(nil, "EXPIRE", key, strconv.Itoa(seconds)))
@@ -5,12 +5,16 @@
 package redis
 
 import (
+	"context"
+	"net"
 	"strconv"
 	"testing"
+	"time"
 
 	"adeia/internal/config"
 
 	"github.com/alicebob/miniredis/v2"
+	"github.com/mediocregopher/radix/v3"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -91,3 +95,159 @@ func TestRedis_Set(t *testing.T) {
 		assert.Equal(t, want, got)
 	})
 }
+
+func TestRedis_SetWithExpiry(t *testing.T) {
+	t.Run("set value with expiry", func(t *testing.T) {
+		r, mock, c := setup(t)
+		defer c()
+
+		key := "foo"
+		want := "bar"
+		err := r.SetWithExpiry(key, want, 10)
+		got, _ := mock.Get(key)
+
+		assert.Nil(t, err)
+		assert.Equal(t, want, got)
+		assert.Equal(t, 10*time.Second, mock.TTL(key))
+	})
+}
+
+func TestRedis_GetDel(t *testing.T) {
+	t.Run("return and delete value when key exists", func(t *testing.T) {
+		r, mock, c := setup(t)
+		defer c()
+
+		_ = mock.Set("foo", "bar")
+		var got, again string
+		assert.Nil(t, r.GetDel(&got, "foo"))
+		assert.Nil(t, r.GetDel(&again, "foo"))
+
+		assert.Equal(t, "bar", got)
+		assert.Empty(t, again)
+		assert.False(t, mock.Exists("foo"))
+	})
+}
+
+func TestRedis_Delete(t *testing.T) {
+	t.Run("delete keys", func(t *testing.T) {
+		r, mock, c := setup(t)
+		defer c()
+
+		_ = mock.Set("foo", "bar")
+		_ = mock.Set("baz", "qux")
+		err := r.Delete("foo", "baz", "unknown")
+
+		assert.Nil(t, err)
+		assert.False(t, mock.Exists("foo"))
+		assert.False(t, mock.Exists("baz"))
+	})
+}
+
+func TestRedis_Incr(t *testing.T) {
+	t.Run("increment counter and reset expiry", func(t *testing.T) {
+		r, mock, c := setup(t)
+		defer c()
+
+		got, err := r.Incr("foo", 10)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, got)
+
+		mock.FastForward(5 * time.Second)
+		got, err = r.Incr("foo", 10)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, got)
+		assert.Equal(t, 10*time.Second, mock.TTL("foo"))
+	})
+}
+
+func TestRedis_TTL(t *testing.T) {
+	t.Run("return ttl when key exists", func(t *testing.T) {
+		r, mock, c := setup(t)
+		defer c()
+
+		_ = mock.Set("foo", "bar")
+		mock.SetTTL("foo", 10*time.Second)
+		got, err := r.TTL("foo")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 10, got)
+	})
+
+	t.Run("return 0 when key does not exist", func(t *testing.T) {
+		r, _, c := setup(t)
+		defer c()
+
+		got, err := r.TTL("foo")
+		assert.Nil(t, err)
+		assert.Equal(t, 0, got)
+	})
+}
+
+func TestRedis_Stats(t *testing.T) {
+	r, _, c := setup(t)
+	defer c()
+
+	_ = r.Set("foo", "bar")
+	_ = r.Do(radix.Cmd(nil, "FOOBAR"))
+
+	s := r.Stats()
+	assert.Equal(t, 10, s.Size)
+	assert.NotZero(t, s.ConnsCreated)
+	assert.Equal(t, uint64(2), s.Commands)
+	assert.Equal(t, uint64(1), s.CommandErrors)
+}
+
+func TestRedis_PingContext(t *testing.T) {
+	t.Run("return nil when redis is reachable", func(t *testing.T) {
+		r, _, c := setup(t)
+		defer c()
+
+		assert.Nil(t, r.PingContext(context.Background()))
+	})
+
+	t.Run("return error when redis fails", func(t *testing.T) {
+		r, mock, c := setup(t)
+		defer c()
+
+		mock.SetError("LOADING")
+		assert.Error(t, r.PingContext(context.Background()))
+	})
+
+	t.Run("return error when ctx is done", func(t *testing.T) {
+		r, _, c := setup(t)
+		defer c()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.Equal(t, context.Canceled, r.PingContext(ctx))
+	})
+
+	t.Run("return once the deadline passes", func(t *testing.T) {
+		t.Parallel()
+
+		// a server that accepts connections, but never responds
+		l, _ := net.Listen("tcp", "127.0.0.1:0")
+		defer l.Close()
+		go func() {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+			}
+		}()
+		addr := l.Addr().(*net.TCPAddr)
+		r, err := New(&config.CacheConfig{Network: "tcp", Host: addr.IP.String(), Port: addr.Port, ConnSize: 1})
+		if err != nil {
+			t.Fatalf("cannot connect: %v", err)
+		}
+		defer r.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		assert.Equal(t, context.DeadlineExceeded, r.PingContext(ctx))
+		assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	})
+}
@@ -4,12 +4,18 @@
 
 package config
 
-import "adeia/pkg/util/constants"
+import (
+	"errors"
+
+	"adeia/pkg/util/constants"
+)
 
 // envOverrides holds all environment value keys for overriding the config.
 var envOverrides = map[string]string{
 	"server.jwt_secret": constants.EnvServerJWTSecretKey,
 
+	"storage.signing_secret": constants.EnvStorageSigningSecretKey,
+
 	"mailer.username": constants.EnvMailerUsernameKey,
 	"mailer.password": constants.EnvMailerPasswordKey,
 
@@ -25,11 +31,33 @@ var envOverrides = map[string]string{
 
 // Config represents the overall configuration.
 type Config struct {
-	CacheConfig  `mapstructure:"cache"`
-	DBConfig     `mapstructure:"database"`
-	LoggerConfig `mapstructure:"logger"`
-	MailerConfig `mapstructure:"mailer"`
-	ServerConfig `mapstructure:"server"`
+	AuthConfig       `mapstructure:"auth"`
+	BlobConfig       `mapstructure:"storage"`
+	CacheConfig      `mapstructure:"cache"`
+	DBConfig         `mapstructure:"database"`
+	I18nConfig       `mapstructure:"i18n"`
+	LoggerConfig     `mapstructure:"logger"`
+	MailerConfig     `mapstructure:"mailer"`
+	MiddlewareConfig `mapstructure:"middleware"`
+	ServerConfig     `mapstructure:"server"`
+}
+
+// AuthConfig represents the config for the brute-force protection of login.
+type AuthConfig struct {
+	MaxFailedLogins      int  `mapstructure:"max_failed_logins"`
+	MaxFailedLoginsPerIP int  `mapstructure:"max_failed_logins_per_ip"`
+	LoginDelayAfter      int  `mapstructure:"login_delay_after"`
+	FailedLoginWindow    int  `mapstructure:"failed_login_window"`
+	LockoutDuration      int  `mapstructure:"lockout_duration"`
+	LockoutAlert         bool `mapstructure:"lockout_alert"`
+}
+
+// BlobConfig represents the config for the blob storage.
+type BlobConfig struct {
+	Driver        string `mapstructure:"driver"`
+	Path          string `mapstructure:"path"`
+	SigningSecret string `mapstructure:"signing_secret"`
+	LinkExpiry    int    `mapstructure:"link_expiry"`
 }
 
 // CacheConfig represents the config for the cache.
@@ -54,25 +82,118 @@ type DBConfig struct {
 	SSLRootCert string `mapstructure:"sslrootcert,omitempty"`
 }
 
+// I18nConfig represents the config for the i18n message catalogues.
+type I18nConfig struct {
+	LocalesPath string `mapstructure:"locales_path"`
+}
+
 // LoggerConfig represents the config for the logger.
 type LoggerConfig struct {
-	Level string   `mapstructure:"level"`
-	Paths []string `mapstructure:"paths"`
+	Level           string            `mapstructure:"level"`
+	Encoding        string            `mapstructure:"encoding"`
+	Paths           []string          `mapstructure:"paths"`
+	Caller          bool              `mapstructure:"caller"`
+	StacktraceLevel string            `mapstructure:"stacktrace_level"`
+	Sampling        LogSamplingConfig `mapstructure:"sampling"`
+	Rotation        LogRotationConfig `mapstructure:"rotation"`
+}
+
+// LogSamplingConfig represents the config for sampling of repeated log lines. In
+// each second, the first Initial lines with the same level and message are logged,
+// and every Thereafter-th line after that. Sampling is disabled if Initial is 0.
+type LogSamplingConfig struct {
+	Initial    int `mapstructure:"initial"`
+	Thereafter int `mapstructure:"thereafter"`
+}
+
+// LogRotationConfig represents the config for rotation of log files.
+type LogRotationConfig struct {
+	MaxSize    int  `mapstructure:"max_size"`    // in megabytes
+	MaxAge     int  `mapstructure:"max_age"`     // in days
+	MaxBackups int  `mapstructure:"max_backups"` // no. of rotated files to keep
+	Compress   bool `mapstructure:"compress"`
 }
 
 // MailerConfig represents the config for the mailer.
 type MailerConfig struct {
-	Username string `mapstructure:"username"`
-	Password string `mapstructure:"password"`
-	SMTPHost string `mapstructure:"smtp_host"`
-	SMTPPort int    `mapstructure:"smtp_port"`
+	Username      string `mapstructure:"username"`
+	Password      string `mapstructure:"password"`
+	SMTPHost      string `mapstructure:"smtp_host"`
+	SMTPPort      int    `mapstructure:"smtp_port"`
+	TemplatesPath string `mapstructure:"templates_path"`
+	LinkBaseURL   string `mapstructure:"link_base_url"`
+}
+
+// MiddlewareConfig represents the config for the standard middlewares, that are
+// applied to every request. Each of them can be disabled.
+type MiddlewareConfig struct {
+	RequestID       bool                  `mapstructure:"request_id"`
+	Recover         bool                  `mapstructure:"recover"`
+	AccessLog       bool                  `mapstructure:"access_log"`
+	Metrics         bool                  `mapstructure:"metrics"`
+	Gzip            bool                  `mapstructure:"gzip"`
+	CORS            CORSConfig            `mapstructure:"cors"`
+	SecurityHeaders SecurityHeadersConfig `mapstructure:"security_headers"`
+}
+
+// CORSConfig represents the config for the CORS middleware.
+type CORSConfig struct {
+	Enabled          bool     `mapstructure:"enabled"`
+	AllowedOrigins   []string `mapstructure:"allowed_origins"`
+	AllowCredentials bool     `mapstructure:"allow_credentials"`
+	MaxAge           int      `mapstructure:"max_age"`
+}
+
+// validate checks that all origins are not allowed along with credentials, since
+// any site could then make authenticated requests on behalf of the users.
+func (c *CORSConfig) validate() error {
+	if !c.AllowCredentials {
+		return nil
+	}
+	for _, o := range c.AllowedOrigins {
+		if o == "*" {
+			return errors.New(`allowed_origins cannot contain "*" when allow_credentials is true`)
+		}
+	}
+	return nil
+}
+
+// SecurityHeadersConfig represents the config for the security headers middleware.
+type SecurityHeadersConfig struct {
+	Enabled    bool `mapstructure:"enabled"`
+	HSTSMaxAge int  `mapstructure:"hsts_max_age"`
 }
 
 // ServerConfig represents the config for the server.
 type ServerConfig struct {
-	Host            string `mapstructure:"host,omitempty"`
-	Port            int    `mapstructure:"port"`
-	RateLimitRate   int    `mapstructure:"ratelimit_rate"`
-	RateLimitWindow int    `mapstructure:"ratelimit_window"`
-	JWTSecret       string `mapstructure:"jwt_secret"`
+	Host               string              `mapstructure:"host,omitempty"`
+	Port               int                 `mapstructure:"port"`
+	ReadTimeout        int                 `mapstructure:"read_timeout"`
+	ReadHeaderTimeout  int                 `mapstructure:"read_header_timeout"`
+	WriteTimeout       int                 `mapstructure:"write_timeout"`
+	IdleTimeout        int                 `mapstructure:"idle_timeout"`
+	ShutdownTimeout    int                 `mapstructure:"shutdown_timeout"`
+	ShutdownDelay      int                 `mapstructure:"shutdown_delay"`
+	HealthCheckTimeout int                 `mapstructure:"health_check_timeout"`
+	TLSCertFile        string              `mapstructure:"tls_cert_file"`
+	TLSKeyFile         string              `mapstructure:"tls_key_file"`
+	MinTLSVersion      string              `mapstructure:"min_tls_version"`
+	RedirectPort       int                 `mapstructure:"redirect_port"`
+	AdminHost          string              `mapstructure:"admin_host"`
+	AdminPort          int                 `mapstructure:"admin_port"`
+	RateLimitDriver    string              `mapstructure:"ratelimit_driver"`
+	RateLimitRate      int                 `mapstructure:"ratelimit_rate"`
+	RateLimitWindow    int                 `mapstructure:"ratelimit_window"`
+	RateLimitOverrides []RateLimitOverride `mapstructure:"ratelimit_overrides"`
+	TrustedProxies     []string            `mapstructure:"trusted_proxies"`
+	JWTSecret          string              `mapstructure:"jwt_secret"`
+}
+
+// RateLimitOverride represents the config for the rate-limit of a route, that
+// overrides the server-wide rate-limit.
+type RateLimitOverride struct {
+	Method string `mapstructure:"method"`
+	Path   string `mapstructure:"path"`
+	Limit  int    `mapstructure:"limit"`
+	Window int    `mapstructure:"window"`
 }
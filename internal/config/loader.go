@@ -30,6 +30,10 @@ func Load(confFile io.Reader) (*Config, error) {
 		return nil, fmt.Errorf("cannot unmarshal to config struct: %v", err)
 	}
 
+	if err := c.CORS.validate(); err != nil {
+		return nil, fmt.Errorf("invalid cors config: %v", err)
+	}
+
 	return &c, nil
 }
 
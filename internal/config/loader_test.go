@@ -52,6 +52,20 @@ logger:
 		assert.Error(t, err)
 	})
 
+	t.Run("return error when all origins are allowed with credentials", func(t *testing.T) {
+		t.Parallel()
+		b := bytes.NewBufferString(`
+middleware:
+  cors:
+    allowed_origins: ["https://example.com", "*"]
+    allow_credentials: true
+`)
+		_, err := Load(b)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "allow_credentials")
+		}
+	})
+
 	t.Run("return error when unmarshalling fails", func(t *testing.T) {
 		t.Parallel()
 		b := bytes.NewBufferString(`
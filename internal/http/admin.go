@@ -0,0 +1,152 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"adeia"
+	"adeia/pkg/errs"
+	"adeia/pkg/log"
+	"adeia/pkg/util/httputil"
+
+	"github.com/go-chi/chi"
+)
+
+// AdminController represents the Admin controller, that serves the operational
+// endpoints of the server.
+type AdminController struct {
+	handler      chi.Router
+	log          log.Logger
+	pattern      string
+	leveler      log.Leveler
+	auditService adeia.AuditService
+}
+
+// Handler returns the AdminController's handler.
+func (ac *AdminController) Handler() http.Handler {
+	return ac.handler
+}
+
+// Pattern returns the AdminController's pattern.
+func (ac *AdminController) Pattern() string {
+	return ac.pattern
+}
+
+// NewAdminController creates a new AdminController. The log level is read and
+// changed using leveler.
+func NewAdminController(log log.Logger, leveler log.Leveler, as adeia.AuditService) *AdminController {
+	ac := &AdminController{
+		log:          log,
+		pattern:      "/admin",
+		leveler:      leveler,
+		auditService: as,
+	}
+	ac.BindRoutes()
+	return ac
+}
+
+// BindRoutes binds all admin-routes to the AdminController's handler.
+func (ac *AdminController) BindRoutes() {
+	r := chi.NewRouter()
+
+	r.Method(http.MethodGet, "/log-level", ac.GetLogLevel())
+	r.Method(http.MethodPut, "/log-level", ac.UpdateLogLevel())
+	r.Method(http.MethodGet, "/audit-events", ac.GetAllAuditEvents())
+
+	ac.handler = r
+}
+
+// logLevel represents the log level of the server.
+type logLevel struct {
+	Level string `json:"level" validate:"required,oneof=debug info warn error"`
+}
+
+// GetLogLevel returns the current log level.
+func (ac *AdminController) GetLogLevel() *ProtectedHandler {
+	return &ProtectedHandler{
+		PermissionName: "MANAGE_LOG_LEVEL",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			httputil.LogWriteErr(ac.log, httputil.RespondWithData(w, http.StatusOK, &logLevel{ac.leveler.Level()}))
+		},
+	}
+}
+
+// UpdateLogLevel changes the log level, without a restart. The change is not
+// persisted, so the configured level is used again after a restart.
+func (ac *AdminController) UpdateLogLevel() *ProtectedHandler {
+	checkRules(logLevel{})
+	return &ProtectedHandler{
+		PermissionName: "MANAGE_LOG_LEVEL",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			var body logLevel
+			if err := httputil.Decode(w, r, &body); err != nil {
+				ac.log.Debug(err)
+				return
+			}
+
+			before := &logLevel{ac.leveler.Level()}
+			if err := ac.leveler.SetLevel(body.Level); err != nil {
+				httputil.LogWriteErr(ac.log, httputil.RespondWithErr(w, r, adeia.ErrValidationFailed.AddValidationErr("level", err.Error())))
+				return
+			}
+
+			log.FromContext(r.Context(), ac.log).Infof("log level changed to %s", body.Level)
+			ac.auditService.Record(r.Context(), adeia.AuditLogLevelUpdate, adeia.AuditTargetLogLevel, "", before, &body)
+			httputil.LogWriteErr(ac.log, httputil.RespondWithData(w, http.StatusOK, &body))
+		},
+	}
+}
+
+// GetAllAuditEvents returns a page of the audit log, that can be sorted, filtered
+// and paginated using the query params. The since and until query params (in
+// RFC 3339 format) limit it to the AuditEvents created in [since, until).
+func (ac *AdminController) GetAllAuditEvents() *ProtectedHandler {
+	return &ProtectedHandler{
+		PermissionName: "VIEW_AUDIT_LOG",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			spec, err := httputil.DecodeQuery(w, r, adeia.AuditEventQueryOptions)
+			if err != nil {
+				ac.log.Debug(err)
+				return
+			}
+
+			since, err := timeParam(r, "since")
+			if err != nil {
+				httputil.LogWriteErr(ac.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+			until, err := timeParam(r, "until")
+			if err != nil {
+				httputil.LogWriteErr(ac.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			events, nextCursor, err := ac.auditService.GetAllEvents(r.Context(), spec, since, until)
+			if err != nil {
+				httputil.LogWriteErr(ac.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			httputil.LogWriteErr(ac.log, httputil.RespondWithPage(w, r, http.StatusOK, events, nextCursor))
+		},
+	}
+}
+
+// timeParam parses the query param of the request as an RFC 3339 time. nil is
+// returned if it is absent.
+func timeParam(r *http.Request, param string) (*time.Time, error) {
+	v := r.URL.Query().Get(param)
+	if v == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, adeia.ErrValidationFailed.AddValidationErr(param, "Time must be in RFC 3339 format")
+	}
+	return &t, nil
+}
@@ -0,0 +1,113 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"adeia"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeLeveler is a log.Leveler that stores the level in memory.
+type fakeLeveler struct {
+	level string
+}
+
+func (f *fakeLeveler) Level() string {
+	return f.level
+}
+
+func (f *fakeLeveler) SetLevel(level string) error {
+	f.level = level
+	return nil
+}
+
+// fakeAuditService is an adeia.AuditService that records the actions passed to
+// Record. Methods that are not overridden panic.
+type fakeAuditService struct {
+	adeia.AuditService
+	actions []string
+}
+
+func (f *fakeAuditService) Record(_ context.Context, action, _, _ string, _, _ interface{}) {
+	f.actions = append(f.actions, action)
+}
+
+func TestAdminController_GetAllAuditEvents(t *testing.T) {
+	serve := func(token string, permissions ...string) *httptest.ResponseRecorder {
+		h := Authenticate(testLogger, newFakeAuthService(permissions...))(NewAdminController(testLogger, &fakeLeveler{}, &fakeAuditService{}).Handler())
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/audit-events", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("reject anonymous callers", func(t *testing.T) {
+		rr := serve("")
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("reject callers without VIEW_AUDIT_LOG", func(t *testing.T) {
+		rr := serve("valid", "MANAGE_LOG_LEVEL")
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
+
+func TestAdminController_UpdateLogLevel(t *testing.T) {
+	serve := func(l *fakeLeveler, as *fakeAuditService, body, token string, permissions ...string) *httptest.ResponseRecorder {
+		h := Authenticate(testLogger, newFakeAuthService(permissions...))(NewAdminController(testLogger, l, as).Handler())
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/log-level", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("reject anonymous callers", func(t *testing.T) {
+		l, as := &fakeLeveler{"info"}, &fakeAuditService{}
+		rr := serve(l, as, `{"level":"debug"}`, "")
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, "info", l.level)
+	})
+
+	t.Run("reject callers without MANAGE_LOG_LEVEL", func(t *testing.T) {
+		l, as := &fakeLeveler{"info"}, &fakeAuditService{}
+		rr := serve(l, as, `{"level":"debug"}`, "valid", "VIEW_AUDIT_LOG")
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, "info", l.level)
+	})
+
+	t.Run("reject unknown levels", func(t *testing.T) {
+		l, as := &fakeLeveler{"info"}, &fakeAuditService{}
+		rr := serve(l, as, `{"level":"trace"}`, "valid", "MANAGE_LOG_LEVEL")
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Equal(t, adeia.ErrValidationFailed.ErrorCode, errorCode(t, rr))
+		assert.Equal(t, "info", l.level)
+	})
+
+	t.Run("change the level for callers with MANAGE_LOG_LEVEL", func(t *testing.T) {
+		l, as := &fakeLeveler{"info"}, &fakeAuditService{}
+		rr := serve(l, as, `{"level":"debug"}`, "valid", "MANAGE_LOG_LEVEL")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "debug", l.level)
+		assert.Equal(t, []string{adeia.AuditLogLevelUpdate}, as.actions)
+	})
+}
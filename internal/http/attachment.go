@@ -0,0 +1,149 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package http
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+
+	"adeia"
+	"adeia/pkg/errs"
+	"adeia/pkg/log"
+	"adeia/pkg/util/httputil"
+
+	"github.com/go-chi/chi"
+)
+
+// AttachmentController represents the Attachment controller.
+type AttachmentController struct {
+	handler           chi.Router
+	log               log.Logger
+	pattern           string
+	attachmentService adeia.AttachmentService
+}
+
+// Handler returns the AttachmentController's handler.
+func (ac *AttachmentController) Handler() http.Handler {
+	return ac.handler
+}
+
+// Pattern returns the AttachmentController's pattern.
+func (ac *AttachmentController) Pattern() string {
+	return ac.pattern
+}
+
+// NewAttachmentController creates a new AttachmentController.
+func NewAttachmentController(log log.Logger, as adeia.AttachmentService) *AttachmentController {
+	ac := &AttachmentController{
+		log:               log,
+		pattern:           "/attachments",
+		attachmentService: as,
+	}
+	ac.BindRoutes()
+	return ac
+}
+
+// BindRoutes binds all attachment-routes to the AttachmentController's handler.
+func (ac *AttachmentController) BindRoutes() {
+	r := chi.NewRouter()
+
+	r.Method(http.MethodPost, "/", ac.UploadAttachment())
+	r.Method(http.MethodGet, "/{id}", ac.GetAttachment())
+	r.Method(http.MethodGet, "/{id}/content", ac.DownloadAttachment())
+
+	ac.handler = r
+}
+
+// UploadAttachment stores a file (a PDF, JPEG or PNG), uploaded in the "file" field
+// of a multipart/form-data request, as an Attachment of the authenticated User.
+func (ac *AttachmentController) UploadAttachment() *AuthenticatedHandler {
+	return &AuthenticatedHandler{
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			file, err := httputil.DecodeFile(w, r, "file", adeia.AttachmentTypes...)
+			if err != nil {
+				ac.log.Debug(err)
+				return
+			}
+
+			u, _ := CurrentUser(r.Context())
+			a, err := ac.attachmentService.UploadAttachment(
+				r.Context(),
+				u,
+				file.Filename,
+				file.ContentType,
+				file.Checksum,
+				file.Content,
+			)
+			if err != nil {
+				httputil.LogWriteErr(ac.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			httputil.LogWriteErr(ac.log, httputil.RespondWithData(w, http.StatusCreated, a))
+		},
+	}
+}
+
+// GetAttachment returns the Attachment with the ID in the URL, along with a signed,
+// time-limited link to download it. Users can get their own Attachments, and Users
+// with the VIEW_ATTACHMENTS permission can get all of them.
+func (ac *AttachmentController) GetAttachment() *AuthenticatedHandler {
+	return &AuthenticatedHandler{
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			id, err := strconv.Atoi(chi.URLParam(r, "id"))
+			if err != nil {
+				httputil.LogWriteErr(ac.log, httputil.RespondWithErr(w, r, adeia.ErrResourceNotFound))
+				return
+			}
+
+			u, _ := CurrentUser(r.Context())
+			viewAll := hasPermission(r.Context(), "VIEW_ATTACHMENTS")
+			a, q, err := ac.attachmentService.GetAttachment(r.Context(), u, id, viewAll)
+			if err != nil {
+				httputil.LogWriteErr(ac.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			a.DownloadURL = path.Join(r.URL.Path, "content") + "?" + q.Encode()
+			httputil.LogWriteErr(ac.log, httputil.RespondWithData(w, http.StatusOK, a))
+		},
+	}
+}
+
+// DownloadAttachment sends the contents of the Attachment with the ID in the URL.
+// The request need not be authenticated, since the signature in the query params
+// of the link authorizes it.
+func (ac *AttachmentController) DownloadAttachment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			httputil.LogWriteErr(ac.log, httputil.RespondWithErr(w, r, adeia.ErrInvalidDownloadLink))
+			return
+		}
+
+		a, rc, err := ac.attachmentService.OpenAttachment(r.Context(), id, r.URL.Query())
+		if err != nil {
+			httputil.LogWriteErr(ac.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+			return
+		}
+		defer func() {
+			if err := rc.Close(); err != nil {
+				ac.log.Debugf("cannot close attachment %d: %v", id, err)
+			}
+		}()
+
+		w.Header().Set("Content-Type", a.ContentType)
+		w.Header().Set("Content-Length", strconv.FormatInt(a.Size, 10))
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename}))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "private, no-store")
+		if _, err := io.Copy(w, rc); err != nil {
+			ac.log.Debugf("cannot send attachment %d: %v", id, err)
+		}
+	}
+}
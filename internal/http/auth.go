@@ -0,0 +1,395 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"adeia"
+	"adeia/pkg/errs"
+	"adeia/pkg/http/middleware"
+	"adeia/pkg/log"
+	"adeia/pkg/util/httputil"
+
+	"github.com/go-chi/chi"
+)
+
+type authCtxKey struct{}
+
+// authInfo is the authenticated User and Session, carried by the request context,
+// along with the permissions of the User. When the request could not be
+// authenticated, only err is set.
+type authInfo struct {
+	user        *adeia.User
+	session     *adeia.Session
+	permissions []string
+	err         error
+}
+
+// CurrentUser returns the authenticated User and their Session from the context.
+// nil is returned for both, if the request is not authenticated.
+func CurrentUser(ctx context.Context) (*adeia.User, *adeia.Session) {
+	if a, ok := ctx.Value(authCtxKey{}).(*authInfo); ok {
+		return a.user, a.session
+	}
+	return nil, nil
+}
+
+// SavedLocale returns the locale that the authenticated User saved in their
+// preferences. It is empty if the request is not authenticated.
+func SavedLocale(r *http.Request) string {
+	if u, _ := CurrentUser(r.Context()); u != nil && u.Preferences != nil {
+		return u.Preferences.Locale
+	}
+	return ""
+}
+
+// authError returns the error that the handlers requiring authentication respond
+// with, when the request in the context is not authenticated. It is the error of
+// authenticating the token, if the request had one.
+func authError(ctx context.Context) errs.ResponseError {
+	if a, ok := ctx.Value(authCtxKey{}).(*authInfo); ok && a.err != nil {
+		return a.err.(errs.ResponseError)
+	}
+	return adeia.ErrUnauthenticated
+}
+
+// hasPermission returns whether the authenticated User in the context has the
+// permission. It is false if the request is not authenticated.
+func hasPermission(ctx context.Context, name string) bool {
+	a, ok := ctx.Value(authCtxKey{}).(*authInfo)
+	if !ok {
+		return false
+	}
+
+	for _, p := range a.permissions {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticate returns a middleware that authenticates the request using the
+// bearer token in the Authorization header, and adds the User and Session to the
+// request context, along with the permissions of the User. Requests without a
+// token, or with one that cannot be authenticated, are passed on without a User,
+// and it is upto the handlers to reject them. This way, public routes (like
+// /auth/login) still work for clients that send a stale token.
+func Authenticate(logger log.Logger, as adeia.AuthService) middleware.Func {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := r.Header.Get("Authorization")
+			if h == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// the error is reported by the handlers that require authentication
+			unauthenticated := func(err error) {
+				ctx := context.WithValue(r.Context(), authCtxKey{}, &authInfo{err: err})
+				next.ServeHTTP(w, r.WithContext(ctx))
+			}
+
+			token := strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+			if token == h {
+				unauthenticated(adeia.ErrInvalidToken)
+				return
+			}
+
+			u, s, err := as.Authenticate(r.Context(), token)
+			if err != nil {
+				unauthenticated(err)
+				return
+			}
+
+			permissions, err := as.Permissions(r.Context(), u)
+			if err != nil {
+				unauthenticated(err)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), authCtxKey{}, &authInfo{user: u, session: s, permissions: permissions})
+			ctx = log.NewContext(ctx, log.FromContext(ctx, logger).With("employee_id", u.EmployeeID))
+			ctx = adeia.NewAuditActorContext(ctx, &adeia.AuditActor{
+				EmployeeID: u.EmployeeID,
+				IP:         clientIP(r),
+				RequestID:  middleware.RequestIDFromContext(ctx),
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// AuthController represents the Auth controller.
+type AuthController struct {
+	handler     chi.Router
+	log         log.Logger
+	pattern     string
+	authService adeia.AuthService
+}
+
+// Handler returns the AuthController's handler.
+func (ac *AuthController) Handler() http.Handler {
+	return ac.handler
+}
+
+// Pattern returns the AuthController's pattern.
+func (ac *AuthController) Pattern() string {
+	return ac.pattern
+}
+
+// NewAuthController creates a new AuthController.
+func NewAuthController(log log.Logger, as adeia.AuthService) *AuthController {
+	ac := &AuthController{
+		log:         log,
+		pattern:     "/auth",
+		authService: as,
+	}
+	ac.BindRoutes()
+	return ac
+}
+
+// BindRoutes binds all auth-routes to the AuthController's handler.
+func (ac *AuthController) BindRoutes() {
+	r := chi.NewRouter()
+
+	r.Method(http.MethodPost, "/login", ac.Login())
+	r.Method(http.MethodPost, "/login/2fa", ac.VerifyLogin())
+	r.Method(http.MethodPost, "/2fa/enrol", ac.BeginTOTPEnrolment())
+	r.Method(http.MethodPost, "/2fa/enrol/confirm", ac.ConfirmTOTPEnrolment())
+	r.Method(http.MethodPost, "/refresh", ac.Refresh())
+	r.Method(http.MethodPost, "/logout", ac.Logout())
+	r.Method(http.MethodPost, "/password-reset", ac.RequestPasswordReset())
+	r.Method(http.MethodPost, "/password-reset/confirm", ac.ResetPassword())
+	r.Method(http.MethodPost, "/activate", ac.ActivateAccount())
+
+	ac.handler = r
+}
+
+// Login logs in a User using their email and password, and responds with the
+// access and refresh tokens of the new session. If a second step of login is
+// needed, an MFA token is sent instead.
+func (ac *AuthController) Login() http.HandlerFunc {
+	type request struct {
+		Email    string `json:"email" validate:"required,email"`
+		Password string `json:"password" validate:"required"`
+	}
+	checkRules(request{})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body request
+		if err := httputil.Decode(w, r, &body); err != nil {
+			ac.log.Debug(err)
+			return
+		}
+
+		tokens, err := ac.authService.Login(r.Context(), body.Email, body.Password, clientIP(r), r.UserAgent())
+		if err != nil {
+			httputil.LogWriteErr(ac.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+			return
+		}
+
+		httputil.LogWriteErr(ac.log, httputil.RespondWithData(w, http.StatusOK, tokens))
+	}
+}
+
+// VerifyLogin completes a login with two-factor authentication, using the MFA token
+// and a TOTP or recovery code.
+func (ac *AuthController) VerifyLogin() http.HandlerFunc {
+	type request struct {
+		MFAToken string `json:"mfa_token" validate:"required"`
+		Code     string `json:"code" validate:"required,max=32"`
+	}
+	checkRules(request{})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body request
+		if err := httputil.Decode(w, r, &body); err != nil {
+			ac.log.Debug(err)
+			return
+		}
+
+		tokens, err := ac.authService.VerifyLogin(r.Context(), body.MFAToken, body.Code, clientIP(r), r.UserAgent())
+		if err != nil {
+			httputil.LogWriteErr(ac.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+			return
+		}
+
+		httputil.LogWriteErr(ac.log, httputil.RespondWithData(w, http.StatusOK, tokens))
+	}
+}
+
+// BeginTOTPEnrolment begins the enrolment in two-factor authentication, for Users
+// whose role requires it before they can log in.
+func (ac *AuthController) BeginTOTPEnrolment() http.HandlerFunc {
+	type request struct {
+		MFAToken string `json:"mfa_token" validate:"required"`
+	}
+	checkRules(request{})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body request
+		if err := httputil.Decode(w, r, &body); err != nil {
+			ac.log.Debug(err)
+			return
+		}
+
+		enrolment, err := ac.authService.BeginLoginTOTPEnrolment(r.Context(), body.MFAToken)
+		if err != nil {
+			httputil.LogWriteErr(ac.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+			return
+		}
+
+		httputil.LogWriteErr(ac.log, httputil.RespondWithData(w, http.StatusOK, enrolment))
+	}
+}
+
+// ConfirmTOTPEnrolment confirms the enrolment in two-factor authentication, that
+// was begun during login, and responds with the recovery codes and the tokens of
+// the new session.
+func (ac *AuthController) ConfirmTOTPEnrolment() http.HandlerFunc {
+	type request struct {
+		MFAToken string `json:"mfa_token" validate:"required"`
+		Code     string `json:"code" validate:"required,max=32"`
+	}
+	checkRules(request{})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body request
+		if err := httputil.Decode(w, r, &body); err != nil {
+			ac.log.Debug(err)
+			return
+		}
+
+		c, err := ac.authService.ConfirmLoginTOTPEnrolment(r.Context(), body.MFAToken, body.Code, clientIP(r), r.UserAgent())
+		if err != nil {
+			httputil.LogWriteErr(ac.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+			return
+		}
+
+		httputil.LogWriteErr(ac.log, httputil.RespondWithData(w, http.StatusOK, c))
+	}
+}
+
+// Refresh issues new tokens in exchange for a refresh token.
+func (ac *AuthController) Refresh() http.HandlerFunc {
+	type request struct {
+		RefreshToken string `json:"refresh_token" validate:"required"`
+	}
+	checkRules(request{})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body request
+		if err := httputil.Decode(w, r, &body); err != nil {
+			ac.log.Debug(err)
+			return
+		}
+
+		tokens, err := ac.authService.Refresh(r.Context(), body.RefreshToken)
+		if err != nil {
+			httputil.LogWriteErr(ac.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+			return
+		}
+
+		httputil.LogWriteErr(ac.log, httputil.RespondWithData(w, http.StatusOK, tokens))
+	}
+}
+
+// Logout revokes the session of the authenticated User.
+func (ac *AuthController) Logout() *AuthenticatedHandler {
+	return &AuthenticatedHandler{
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			_, s := CurrentUser(r.Context())
+			if err := ac.authService.Logout(r.Context(), s); err != nil {
+				httputil.LogWriteErr(ac.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		},
+	}
+}
+
+// RequestPasswordReset emails a password reset link to the User with the email.
+// The response is the same whether or not the User exists.
+func (ac *AuthController) RequestPasswordReset() http.HandlerFunc {
+	type request struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+	checkRules(request{})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body request
+		if err := httputil.Decode(w, r, &body); err != nil {
+			ac.log.Debug(err)
+			return
+		}
+
+		if err := ac.authService.RequestPasswordReset(r.Context(), body.Email); err != nil {
+			httputil.LogWriteErr(ac.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// ResetPassword sets a new password using the token from a password reset link.
+func (ac *AuthController) ResetPassword() http.HandlerFunc {
+	type request struct {
+		Token       string `json:"token" validate:"required"`
+		NewPassword string `json:"new_password" validate:"required,max=128"`
+	}
+	checkRules(request{})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body request
+		if err := httputil.Decode(w, r, &body); err != nil {
+			ac.log.Debug(err)
+			return
+		}
+
+		if err := ac.authService.ResetPassword(r.Context(), body.Token, body.NewPassword); err != nil {
+			httputil.LogWriteErr(ac.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ActivateAccount sets the password of a new User, and activates their account,
+// using the token from an activation link.
+func (ac *AuthController) ActivateAccount() http.HandlerFunc {
+	type request struct {
+		Token       string `json:"token" validate:"required"`
+		NewPassword string `json:"new_password" validate:"required,max=128"`
+	}
+	checkRules(request{})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body request
+		if err := httputil.Decode(w, r, &body); err != nil {
+			ac.log.Debug(err)
+			return
+		}
+
+		if err := ac.authService.ActivateAccount(r.Context(), body.Token, body.NewPassword); err != nil {
+			httputil.LogWriteErr(ac.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// clientIP returns the IP address of the client that made the request, as
+// resolved by the ClientIP middleware.
+func clientIP(r *http.Request) string {
+	return middleware.ClientIPFromRequest(r)
+}
@@ -0,0 +1,172 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"adeia"
+	"adeia/internal/config"
+	logzap "adeia/pkg/log/zap"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+var testLogger = &logzap.Logger{SugaredLogger: zap.NewNop().Sugar()}
+
+// fakeAuthService is an adeia.AuthService that accepts the access token "valid",
+// for the User, with the permissions. Methods that are not overridden panic.
+type fakeAuthService struct {
+	adeia.AuthService
+	user        *adeia.User
+	session     *adeia.Session
+	permissions []string
+	revoked     []int
+	loginIPs    []string
+}
+
+func (f *fakeAuthService) Authenticate(_ context.Context, token string) (*adeia.User, *adeia.Session, error) {
+	if token != "valid" {
+		return nil, nil, adeia.ErrInvalidToken
+	}
+	return f.user, f.session, nil
+}
+
+func (f *fakeAuthService) Permissions(context.Context, *adeia.User) ([]string, error) {
+	return f.permissions, nil
+}
+
+func (f *fakeAuthService) GetSessions(_ context.Context, _ *adeia.User, current *adeia.Session) ([]*adeia.Session, error) {
+	return []*adeia.Session{{ID: current.ID, Current: true}}, nil
+}
+
+func (f *fakeAuthService) RevokeSession(_ context.Context, _ *adeia.User, id int) error {
+	if id != f.session.ID {
+		return adeia.ErrResourceNotFound
+	}
+	f.revoked = append(f.revoked, id)
+	return nil
+}
+
+func (f *fakeAuthService) Login(_ context.Context, _, _, ip, _ string) (*adeia.AuthTokens, error) {
+	f.loginIPs = append(f.loginIPs, ip)
+	return &adeia.AuthTokens{}, nil
+}
+
+func newFakeAuthService(permissions ...string) *fakeAuthService {
+	return &fakeAuthService{
+		user:        &adeia.User{ID: 1, EmployeeID: "FOO123", Name: "Foo", IsActivated: true},
+		session:     &adeia.Session{ID: 7, UserID: 1},
+		permissions: permissions,
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	as := newFakeAuthService("VIEW_USERS")
+	var gotCtx context.Context
+	h := Authenticate(testLogger, as)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotCtx = r.Context()
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	t.Run("pass on requests without a token", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		u, s := CurrentUser(gotCtx)
+		assert.Nil(t, u)
+		assert.Nil(t, s)
+	})
+
+	for _, header := range []string{"Basic valid", "Bearer invalid"} {
+		t.Run("pass on invalid tokens without a user: "+header, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", header)
+			h.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			u, _ := CurrentUser(gotCtx)
+			assert.Nil(t, u)
+			assert.Equal(t, adeia.ErrInvalidToken, authError(gotCtx))
+		})
+	}
+
+	t.Run("reject invalid tokens on routes that require authentication", func(t *testing.T) {
+		protected := Authenticate(testLogger, as)(&AuthenticatedHandler{Handler: okHandler})
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer invalid")
+		protected.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, adeia.ErrInvalidToken.ErrorCode, errorCode(t, rr))
+	})
+
+	t.Run("add the user, session and permissions to the context", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer valid")
+		h.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		u, s := CurrentUser(gotCtx)
+		assert.Equal(t, as.user, u)
+		assert.Equal(t, as.session, s)
+		assert.True(t, hasPermission(gotCtx, "VIEW_USERS"))
+		assert.False(t, hasPermission(gotCtx, "CREATE_USERS"))
+		assert.Equal(t, "FOO123", adeia.AuditActorFromContext(gotCtx).EmployeeID)
+	})
+}
+
+func TestSavedLocale(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Empty(t, SavedLocale(r))
+
+	u := &adeia.User{EmployeeID: "FOO123", Preferences: &adeia.UserPreferences{Locale: "ta"}}
+	assert.Equal(t, "ta", SavedLocale(withAuth(r, u)))
+}
+
+func TestAuthController_Login(t *testing.T) {
+	t.Run("resolve the client ip through trusted proxies only", func(t *testing.T) {
+		clientIP, err := ClientIP(&config.ServerConfig{TrustedProxies: []string{"10.0.0.0/8"}})
+		if err != nil {
+			t.Fatalf("cannot create client ip middleware: %v", err)
+		}
+
+		testcases := []struct {
+			remote string
+			want   string
+			msg    string
+		}{
+			{"10.0.0.1:1234", "198.51.100.1", "trusted peer"},
+			{"203.0.113.7:1234", "203.0.113.7", "untrusted peer"},
+		}
+		for _, tc := range testcases {
+			tc := tc
+			t.Run(tc.msg, func(t *testing.T) {
+				t.Parallel()
+				as := newFakeAuthService()
+				h := clientIP(NewAuthController(testLogger, as).Handler())
+
+				req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email":"foo@example.com","password":"secret"}`))
+				req.RemoteAddr = tc.remote
+				// the first hop is spoofed by the client
+				req.Header.Set("X-Forwarded-For", "6.6.6.6, 198.51.100.1")
+				rr := httptest.NewRecorder()
+				h.ServeHTTP(rr, req)
+
+				assert.Equal(t, http.StatusOK, rr.Code)
+				assert.Equal(t, []string{tc.want}, as.loginIPs)
+			})
+		}
+	})
+}
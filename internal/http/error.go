@@ -0,0 +1,59 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package http
+
+import (
+	"net/http"
+
+	"adeia/pkg/errs"
+	"adeia/pkg/log"
+	"adeia/pkg/util/httputil"
+
+	"github.com/go-chi/chi"
+)
+
+// ErrorController represents the Error controller, that serves the error catalogue.
+type ErrorController struct {
+	handler chi.Router
+	log     log.Logger
+	pattern string
+}
+
+// Handler returns the ErrorController's handler.
+func (ec *ErrorController) Handler() http.Handler {
+	return ec.handler
+}
+
+// Pattern returns the ErrorController's pattern.
+func (ec *ErrorController) Pattern() string {
+	return ec.pattern
+}
+
+// NewErrorController creates a new ErrorController.
+func NewErrorController(log log.Logger) *ErrorController {
+	ec := &ErrorController{
+		log:     log,
+		pattern: "/errors",
+	}
+	ec.BindRoutes()
+	return ec
+}
+
+// BindRoutes binds all error-routes to the ErrorController's handler.
+func (ec *ErrorController) BindRoutes() {
+	r := chi.NewRouter()
+
+	r.Method(http.MethodGet, "/", ec.GetAllErrors())
+
+	ec.handler = r
+}
+
+// GetAllErrors returns every registered error code, along with its HTTP status,
+// description and docs link.
+func (ec *ErrorController) GetAllErrors() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		httputil.LogWriteErr(ec.log, httputil.RespondWithData(w, http.StatusOK, errs.Catalogue()))
+	}
+}
@@ -4,10 +4,27 @@
 
 package http
 
-import "net/http"
+import (
+	"fmt"
+	"net/http"
+
+	"adeia"
+	"adeia/pkg/util/httputil"
+	"adeia/pkg/validation"
+)
+
+// checkRules panics if the validation rules of the request type v are invalid. The
+// handlers call it when they are created, as the routes are bound, so that invalid
+// rules fail on startup, instead of on the first request.
+func checkRules(v interface{}) {
+	if err := validation.Check(v); err != nil {
+		panic(fmt.Sprintf("invalid validation rules of %T: %v", v, err))
+	}
+}
 
 // ProtectedHandler checks if user is authorized before allowing the request to
-// pass to the underlying controller.
+// pass to the underlying controller. The user must be authenticated, and their
+// role must grant the PermissionName.
 type ProtectedHandler struct {
 	PermissionName string
 	Handler        http.HandlerFunc
@@ -15,11 +32,34 @@ type ProtectedHandler struct {
 
 // ServeHTTP serves a request using the ProtectedHandler.Handler.
 func (p *ProtectedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// perform role checking using context, like !contains(userRoles, p.PermissionName)
-	if false {
+	if u, _ := CurrentUser(r.Context()); u == nil {
+		_ = httputil.RespondWithErr(w, r, authError(r.Context()))
+		return
+	}
+
+	if !hasPermission(r.Context(), p.PermissionName) {
+		_ = httputil.RespondWithErr(w, r, adeia.ErrPermissionDenied)
 		return
 	}
 
 	// user has access, so continue
 	p.Handler.ServeHTTP(w, r)
 }
+
+// AuthenticatedHandler checks if the user is authenticated before allowing the
+// request to pass to the underlying controller. Unlike ProtectedHandler, no
+// permissions are checked, so it must only be used for routes that act on the
+// authenticated user's own resources.
+type AuthenticatedHandler struct {
+	Handler http.HandlerFunc
+}
+
+// ServeHTTP serves a request using the AuthenticatedHandler.Handler.
+func (a *AuthenticatedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if u, _ := CurrentUser(r.Context()); u == nil {
+		_ = httputil.RespondWithErr(w, r, authError(r.Context()))
+		return
+	}
+
+	a.Handler.ServeHTTP(w, r)
+}
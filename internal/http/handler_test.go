@@ -0,0 +1,104 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"adeia"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withAuth returns a copy of r, that is authenticated as the User with the
+// permissions.
+func withAuth(r *http.Request, u *adeia.User, permissions ...string) *http.Request {
+	ctx := context.WithValue(r.Context(), authCtxKey{}, &authInfo{user: u, session: &adeia.Session{ID: 1}, permissions: permissions})
+	return r.WithContext(ctx)
+}
+
+// errorCode returns the error code of the error response in rr.
+func errorCode(t *testing.T, rr *httptest.ResponseRecorder) string {
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	return body.Error.Code
+}
+
+var okHandler = func(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestCheckRules(t *testing.T) {
+	assert.NotPanics(t, func() {
+		checkRules(struct {
+			Email string `validate:"required,email"`
+		}{})
+	})
+	assert.Panics(t, func() {
+		checkRules(struct {
+			Email string `validate:"required,emial"`
+		}{})
+	})
+}
+
+func TestProtectedHandler(t *testing.T) {
+	h := &ProtectedHandler{PermissionName: "CREATE_USERS", Handler: okHandler}
+	u := &adeia.User{EmployeeID: "FOO123"}
+
+	t.Run("reject anonymous callers", func(t *testing.T) {
+		t.Parallel()
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, adeia.ErrUnauthenticated.ErrorCode, errorCode(t, rr))
+	})
+
+	t.Run("reject callers without the permission", func(t *testing.T) {
+		t.Parallel()
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, withAuth(httptest.NewRequest(http.MethodPost, "/", nil), u, "VIEW_USERS"))
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, adeia.ErrPermissionDenied.ErrorCode, errorCode(t, rr))
+	})
+
+	t.Run("allow callers with the permission", func(t *testing.T) {
+		t.Parallel()
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, withAuth(httptest.NewRequest(http.MethodPost, "/", nil), u, "VIEW_USERS", "CREATE_USERS"))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
+
+func TestAuthenticatedHandler(t *testing.T) {
+	h := &AuthenticatedHandler{Handler: okHandler}
+
+	t.Run("reject anonymous callers", func(t *testing.T) {
+		t.Parallel()
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, adeia.ErrUnauthenticated.ErrorCode, errorCode(t, rr))
+	})
+
+	t.Run("allow authenticated callers without permissions", func(t *testing.T) {
+		t.Parallel()
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, withAuth(httptest.NewRequest(http.MethodGet, "/", nil), &adeia.User{}))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
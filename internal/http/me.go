@@ -0,0 +1,260 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"adeia"
+	"adeia/pkg/errs"
+	"adeia/pkg/log"
+	"adeia/pkg/util/httputil"
+
+	"github.com/go-chi/chi"
+)
+
+// MeController represents the controller for the authenticated User's own
+// resources. The User is resolved from the access token, so that clients need not
+// know their employee ID.
+type MeController struct {
+	handler     chi.Router
+	log         log.Logger
+	pattern     string
+	authService adeia.AuthService
+	userService adeia.UserService
+}
+
+// Handler returns the MeController's handler.
+func (mc *MeController) Handler() http.Handler {
+	return mc.handler
+}
+
+// Pattern returns the MeController's pattern.
+func (mc *MeController) Pattern() string {
+	return mc.pattern
+}
+
+// NewMeController creates a new MeController.
+func NewMeController(log log.Logger, as adeia.AuthService, us adeia.UserService) *MeController {
+	mc := &MeController{
+		log:         log,
+		pattern:     "/me",
+		authService: as,
+		userService: us,
+	}
+	mc.BindRoutes()
+	return mc
+}
+
+// BindRoutes binds all me-routes to the MeController's handler.
+func (mc *MeController) BindRoutes() {
+	r := chi.NewRouter()
+
+	r.Method(http.MethodGet, "/", mc.GetMe())
+	r.Method(http.MethodPut, "/password", mc.ChangePassword())
+	r.Method(http.MethodGet, "/preferences", mc.GetPreferences())
+	r.Method(http.MethodPatch, "/preferences", mc.UpdatePreferences())
+	r.Method(http.MethodPost, "/2fa", mc.BeginTOTPEnrolment())
+	r.Method(http.MethodPost, "/2fa/confirm", mc.ConfirmTOTPEnrolment())
+	r.Method(http.MethodDelete, "/2fa", mc.DisableTOTP())
+	r.Method(http.MethodGet, "/sessions", mc.GetSessions())
+	r.Method(http.MethodDelete, "/sessions/{sessionID}", mc.RevokeSession())
+
+	mc.handler = r
+}
+
+// GetMe returns the authenticated User.
+func (mc *MeController) GetMe() *AuthenticatedHandler {
+	return &AuthenticatedHandler{
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			u, _ := CurrentUser(r.Context())
+			httputil.LogWriteErr(mc.log, httputil.RespondWithData(w, http.StatusOK, u))
+		},
+	}
+}
+
+// ChangePassword changes the password of the authenticated User. All other sessions
+// of the User are revoked.
+func (mc *MeController) ChangePassword() *AuthenticatedHandler {
+	type request struct {
+		CurrentPassword string `json:"current_password" validate:"required"`
+		NewPassword     string `json:"new_password" validate:"required,max=128"`
+	}
+	checkRules(request{})
+
+	return &AuthenticatedHandler{
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			var body request
+			if err := httputil.Decode(w, r, &body); err != nil {
+				mc.log.Debug(err)
+				return
+			}
+
+			u, s := CurrentUser(r.Context())
+			if err := mc.authService.ChangePassword(r.Context(), u, s, body.CurrentPassword, body.NewPassword); err != nil {
+				httputil.LogWriteErr(mc.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		},
+	}
+}
+
+// GetPreferences returns the preferences of the authenticated User.
+func (mc *MeController) GetPreferences() *AuthenticatedHandler {
+	return &AuthenticatedHandler{
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			u, _ := CurrentUser(r.Context())
+			prefs, err := mc.userService.GetUserPreferences(r.Context(), u.EmployeeID)
+			if err != nil {
+				httputil.LogWriteErr(mc.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			httputil.LogWriteErr(mc.log, httputil.RespondWithData(w, http.StatusOK, prefs))
+		},
+	}
+}
+
+// UpdatePreferences updates the preferences of the authenticated User. Fields that
+// are absent are left unchanged.
+func (mc *MeController) UpdatePreferences() *AuthenticatedHandler {
+	checkRules(preferencesRequest{})
+	return &AuthenticatedHandler{
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			var body preferencesRequest
+			if err := httputil.Decode(w, r, &body); err != nil {
+				mc.log.Debug(err)
+				return
+			}
+
+			u, _ := CurrentUser(r.Context())
+			prefs, err := mc.userService.UpdateUserPreferences(
+				r.Context(),
+				u.EmployeeID,
+				body.patch(),
+			)
+			if err != nil {
+				httputil.LogWriteErr(mc.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			httputil.LogWriteErr(mc.log, httputil.RespondWithData(w, http.StatusOK, prefs))
+		},
+	}
+}
+
+// BeginTOTPEnrolment begins the enrolment of the authenticated User in two-factor
+// authentication, and responds with the TOTP secret for their authenticator app.
+func (mc *MeController) BeginTOTPEnrolment() *AuthenticatedHandler {
+	return &AuthenticatedHandler{
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			u, _ := CurrentUser(r.Context())
+			enrolment, err := mc.authService.BeginTOTPEnrolment(r.Context(), u)
+			if err != nil {
+				httputil.LogWriteErr(mc.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			httputil.LogWriteErr(mc.log, httputil.RespondWithData(w, http.StatusOK, enrolment))
+		},
+	}
+}
+
+// ConfirmTOTPEnrolment enables two-factor authentication for the authenticated
+// User, using a code from their authenticator app, and responds with the recovery
+// codes.
+func (mc *MeController) ConfirmTOTPEnrolment() *AuthenticatedHandler {
+	type request struct {
+		Code string `json:"code" validate:"required,max=32"`
+	}
+	checkRules(request{})
+
+	return &AuthenticatedHandler{
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			var body request
+			if err := httputil.Decode(w, r, &body); err != nil {
+				mc.log.Debug(err)
+				return
+			}
+
+			u, _ := CurrentUser(r.Context())
+			c, err := mc.authService.ConfirmTOTPEnrolment(r.Context(), u, body.Code)
+			if err != nil {
+				httputil.LogWriteErr(mc.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			httputil.LogWriteErr(mc.log, httputil.RespondWithData(w, http.StatusOK, c))
+		},
+	}
+}
+
+// DisableTOTP disables two-factor authentication for the authenticated User, using
+// a TOTP or recovery code.
+func (mc *MeController) DisableTOTP() *AuthenticatedHandler {
+	type request struct {
+		Code string `json:"code" validate:"required,max=32"`
+	}
+	checkRules(request{})
+
+	return &AuthenticatedHandler{
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			var body request
+			if err := httputil.Decode(w, r, &body); err != nil {
+				mc.log.Debug(err)
+				return
+			}
+
+			u, _ := CurrentUser(r.Context())
+			if err := mc.authService.DisableTOTP(r.Context(), u, body.Code); err != nil {
+				httputil.LogWriteErr(mc.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		},
+	}
+}
+
+// GetSessions returns all the sessions of the authenticated User.
+func (mc *MeController) GetSessions() *AuthenticatedHandler {
+	return &AuthenticatedHandler{
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			u, s := CurrentUser(r.Context())
+			sessions, err := mc.authService.GetSessions(r.Context(), u, s)
+			if err != nil {
+				httputil.LogWriteErr(mc.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			httputil.LogWriteErr(mc.log, httputil.RespondWithData(w, http.StatusOK, sessions))
+		},
+	}
+}
+
+// RevokeSession revokes the session with the ID in the URL, of the authenticated
+// User, logging out the client that uses it.
+func (mc *MeController) RevokeSession() *AuthenticatedHandler {
+	return &AuthenticatedHandler{
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			id, err := strconv.Atoi(chi.URLParam(r, "sessionID"))
+			if err != nil {
+				httputil.LogWriteErr(mc.log, httputil.RespondWithErr(w, r, adeia.ErrResourceNotFound))
+				return
+			}
+
+			u, _ := CurrentUser(r.Context())
+			if err := mc.authService.RevokeSession(r.Context(), u, id); err != nil {
+				httputil.LogWriteErr(mc.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		},
+	}
+}
@@ -0,0 +1,72 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"adeia"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeController(t *testing.T) {
+	as := newFakeAuthService()
+	h := Authenticate(testLogger, as)(NewMeController(testLogger, as, nil).Handler())
+
+	serve := func(method, path, token string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("reject anonymous callers", func(t *testing.T) {
+		for _, path := range []string{"/", "/sessions", "/preferences"} {
+			rr := serve(http.MethodGet, path, "")
+			assert.Equal(t, http.StatusUnauthorized, rr.Code, path)
+			assert.Equal(t, adeia.ErrUnauthenticated.ErrorCode, errorCode(t, rr), path)
+		}
+	})
+
+	t.Run("return the authenticated user", func(t *testing.T) {
+		rr := serve(http.MethodGet, "/", "valid")
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var body struct {
+			Data adeia.User `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, "FOO123", body.Data.EmployeeID)
+	})
+
+	t.Run("return the sessions of the authenticated user", func(t *testing.T) {
+		rr := serve(http.MethodGet, "/sessions", "valid")
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var body struct {
+			Data []*adeia.Session `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Len(t, body.Data, 1)
+		assert.Equal(t, 7, body.Data[0].ID)
+		assert.True(t, body.Data[0].Current)
+	})
+
+	t.Run("revoke a session of the authenticated user", func(t *testing.T) {
+		rr := serve(http.MethodDelete, "/sessions/8", "valid")
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+
+		rr = serve(http.MethodDelete, "/sessions/7", "valid")
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		assert.Equal(t, []int{7}, as.revoked)
+	})
+}
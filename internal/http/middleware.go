@@ -0,0 +1,158 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"adeia"
+	"adeia/internal/config"
+	"adeia/internal/metrics"
+	"adeia/pkg/http/middleware"
+	"adeia/pkg/log"
+	"adeia/pkg/util/httputil"
+
+	"github.com/go-chi/chi"
+)
+
+// StandardMiddlewares returns the standard middlewares that are enabled in conf,
+// along with the request logger (see RequestLogger), which is always enabled.
+// They are ordered for middleware.FuncChain, so the first one is the innermost;
+// the request ID is assigned first, so that everything else can use it, and panics
+// are recovered inside the access log and metrics, so that they are logged (and
+// counted) as 500s.
+func StandardMiddlewares(logger log.Logger, conf *config.MiddlewareConfig) []middleware.Func {
+	var funcs []middleware.Func
+
+	if conf.Gzip {
+		funcs = append(funcs, middleware.Gzip())
+	}
+	if conf.CORS.Enabled {
+		funcs = append(funcs, middleware.CORS(middleware.CORSOptions{
+			AllowedOrigins: conf.CORS.AllowedOrigins,
+			AllowedMethods: []string{
+				http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+			},
+			AllowedHeaders: []string{"Accept", "Accept-Language", "Authorization", "Content-Type", middleware.RequestIDHeader},
+			ExposedHeaders: []string{
+				middleware.RequestIDHeader, "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset",
+			},
+			AllowCredentials: conf.CORS.AllowCredentials,
+			MaxAge:           conf.CORS.MaxAge,
+		}))
+	}
+	if conf.SecurityHeaders.Enabled {
+		funcs = append(funcs, middleware.SecurityHeaders(middleware.SecurityHeadersOptions{
+			HSTSMaxAge: conf.SecurityHeaders.HSTSMaxAge,
+		}))
+	}
+	if conf.Recover {
+		funcs = append(funcs, middleware.Recover(func(w http.ResponseWriter, r *http.Request, v interface{}, stack []byte) {
+			l := log.FromContext(r.Context(), logger)
+			l.Errorf("recovered from panic: %v\n%s", v, stack)
+			httputil.LogWriteErr(l, httputil.RespondWithErr(w, r, adeia.ErrInternalError))
+		}))
+	}
+	if conf.Metrics {
+		// the access log has all the details that the metrics need
+		funcs = append(funcs, middleware.AccessLog(func(r *http.Request, e *middleware.AccessLogEntry) {
+			metrics.ObserveRequest(e.Method, chi.RouteContext(r.Context()).RoutePattern(), e.Status, e.Duration)
+		}))
+	}
+	if conf.AccessLog {
+		funcs = append(funcs, middleware.AccessLog(func(r *http.Request, e *middleware.AccessLogEntry) {
+			log.FromContext(r.Context(), logger).With(
+				"status", e.Status,
+				"bytes", e.Bytes,
+				"duration", e.Duration,
+				"ip", e.RemoteIP,
+				"user_agent", e.UserAgent,
+			).Infof("%s %s %d", e.Method, e.Path, e.Status)
+		}))
+	}
+	funcs = append(funcs, RequestLogger(logger))
+	if conf.RequestID {
+		funcs = append(funcs, middleware.RequestID())
+	}
+
+	return funcs
+}
+
+// ClientIP returns a middleware that resolves the IP address of the client, and
+// adds it to the request context. The IPs of clients behind the trusted proxies in
+// conf are taken from the X-Forwarded-For header.
+func ClientIP(conf *config.ServerConfig) (middleware.Func, error) {
+	trusted, err := middleware.ParseCIDRs(conf.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %v", err)
+	}
+	return middleware.ClientIP(middleware.ForwardedIP(trusted)), nil
+}
+
+// RequestLogger returns a middleware that adds a request-scoped logger to the
+// request context (see log.FromContext), which adds the request ID, method and
+// route to every log line. It must run after the request ID is assigned.
+func RequestLogger(logger log.Logger) middleware.Func {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the router reuses this routing context, so that the route is known
+			// once the request is routed
+			rctx := chi.NewRouteContext()
+			ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+
+			fields := []interface{}{"method", r.Method}
+			if id := middleware.RequestIDFromContext(ctx); id != "" {
+				fields = append(fields, "request_id", id)
+			}
+			ctx = log.NewContext(ctx, &routeLogger{logger.With(fields...), rctx})
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// routeLogger is a log.Logger that adds the route pattern (like
+// "/v1/users/{empID}") of the routing context to every log line. The route is
+// looked up when the line is written, since it is not known until the request is
+// routed, and structured fields are encoded as soon as they are added.
+type routeLogger struct {
+	log.Logger
+	rctx *chi.Context
+}
+
+func (rl *routeLogger) withRoute() log.Logger {
+	if route := rl.rctx.RoutePattern(); route != "" {
+		return rl.Logger.With("route", route)
+	}
+	return rl.Logger
+}
+
+func (rl *routeLogger) Debug(args ...interface{}) { rl.withRoute().Debug(args...) }
+func (rl *routeLogger) Error(args ...interface{}) { rl.withRoute().Error(args...) }
+func (rl *routeLogger) Info(args ...interface{})  { rl.withRoute().Info(args...) }
+func (rl *routeLogger) Warn(args ...interface{})  { rl.withRoute().Warn(args...) }
+
+func (rl *routeLogger) Debugf(template string, args ...interface{}) {
+	rl.withRoute().Debugf(template, args...)
+}
+
+func (rl *routeLogger) Errorf(template string, args ...interface{}) {
+	rl.withRoute().Errorf(template, args...)
+}
+
+func (rl *routeLogger) Infof(template string, args ...interface{}) {
+	rl.withRoute().Infof(template, args...)
+}
+
+func (rl *routeLogger) Warnf(template string, args ...interface{}) {
+	rl.withRoute().Warnf(template, args...)
+}
+
+// With returns a child logger, that still adds the route.
+func (rl *routeLogger) With(fields ...interface{}) log.Logger {
+	return &routeLogger{rl.Logger.With(fields...), rl.rctx}
+}
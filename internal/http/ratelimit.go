@@ -0,0 +1,65 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"adeia"
+	"adeia/internal/config"
+	"adeia/pkg/http/middleware"
+	"adeia/pkg/log"
+	"adeia/pkg/util/httputil"
+
+	"github.com/mediocregopher/radix/v3"
+)
+
+const rateLimitKeyPrefix = "ratelimit:"
+
+// RateLimit returns a middleware that rate-limits requests by client IP, as per
+// conf. The limits are kept in-memory, or in the cache (using client), so that they
+// are shared by all replicas, depending on the driver. The client IP is resolved by
+// the ClientIP middleware, which must run before it.
+func RateLimit(log log.Logger, conf *config.ServerConfig, client radix.Client) (middleware.Func, error) {
+	newLimiter := func(limit int, window time.Duration) (middleware.Limiter, error) {
+		if limit <= 0 || window <= 0 {
+			return nil, fmt.Errorf("rate-limit and window must be positive")
+		}
+
+		switch conf.RateLimitDriver {
+		case "", "memory":
+			return middleware.NewTokenBucket(limit, window), nil
+		case "redis":
+			return middleware.NewSlidingWindow(client, limit, window, rateLimitKeyPrefix), nil
+		}
+		return nil, fmt.Errorf("unknown rate-limit driver: %q", conf.RateLimitDriver)
+	}
+
+	l, err := newLimiter(conf.RateLimitRate*conf.RateLimitWindow, time.Duration(conf.RateLimitWindow)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []middleware.RateLimitOpt{
+		middleware.WithKeyFunc(middleware.ClientIPFromRequest),
+		middleware.WithLimitHandler(func(w http.ResponseWriter, r *http.Request) {
+			httputil.LogWriteErr(log, httputil.RespondWithErr(w, r, adeia.ErrRateLimited))
+		}),
+		middleware.WithErrorHandler(func(r *http.Request, err error) {
+			log.Errorf("cannot rate-limit request: %v", err)
+		}),
+	}
+	for _, o := range conf.RateLimitOverrides {
+		rl, err := newLimiter(o.Limit, time.Duration(o.Window)*time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate-limit override for %s %s: %v", o.Method, o.Path, err)
+		}
+		opts = append(opts, middleware.WithRouteLimiter(o.Method, o.Path, rl))
+	}
+
+	return middleware.RateLimit(l, opts...), nil
+}
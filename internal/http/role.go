@@ -0,0 +1,94 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"adeia"
+	"adeia/pkg/errs"
+	"adeia/pkg/log"
+	"adeia/pkg/util/httputil"
+
+	"github.com/go-chi/chi"
+)
+
+// RoleController represents the Role controller.
+type RoleController struct {
+	handler     chi.Router
+	log         log.Logger
+	pattern     string
+	roleService adeia.RoleService
+}
+
+// Handler returns the RoleController's handler.
+func (rc *RoleController) Handler() http.Handler {
+	return rc.handler
+}
+
+// Pattern returns the RoleController's pattern.
+func (rc *RoleController) Pattern() string {
+	return rc.pattern
+}
+
+// NewRoleController creates a new RoleController.
+func NewRoleController(log log.Logger, rs adeia.RoleService) *RoleController {
+	rc := &RoleController{
+		log:         log,
+		pattern:     "/roles",
+		roleService: rs,
+	}
+	rc.BindRoutes()
+	return rc
+}
+
+// BindRoutes binds all role-routes to the RoleController's handler.
+func (rc *RoleController) BindRoutes() {
+	r := chi.NewRouter()
+
+	r.Method(http.MethodPatch, "/{id}", rc.UpdateRole())
+
+	rc.handler = r
+}
+
+// UpdateRole updates whether Users with the Role with the ID in the URL must use
+// two-factor authentication.
+func (rc *RoleController) UpdateRole() *ProtectedHandler {
+	type request struct {
+		Require2FA *bool `json:"require_2fa"`
+	}
+
+	return &ProtectedHandler{
+		PermissionName: "MANAGE_ROLES",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			id, err := strconv.Atoi(chi.URLParam(r, "id"))
+			if err != nil {
+				httputil.LogWriteErr(rc.log, httputil.RespondWithErr(w, r, adeia.ErrResourceNotFound))
+				return
+			}
+
+			var body request
+			if err := httputil.Decode(w, r, &body); err != nil {
+				rc.log.Debug(err)
+				return
+			}
+
+			// checked here, as the required rule rejects false
+			if body.Require2FA == nil {
+				httputil.LogWriteErr(rc.log, httputil.RespondWithErr(w, r, adeia.ErrValidationFailed.AddValidationErr("require_2fa", "This field is required")))
+				return
+			}
+
+			role, err := rc.roleService.UpdateRole(r.Context(), id, *body.Require2FA)
+			if err != nil {
+				httputil.LogWriteErr(rc.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			httputil.LogWriteErr(rc.log, httputil.RespondWithData(w, http.StatusOK, role))
+		},
+	}
+}
@@ -0,0 +1,71 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"adeia"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRoleService is an adeia.RoleService that records the updates it receives.
+type fakeRoleService struct {
+	updates []bool
+}
+
+func (f *fakeRoleService) UpdateRole(_ context.Context, id int, require2FA bool) (*adeia.Role, error) {
+	if id != 1 {
+		return nil, adeia.ErrResourceNotFound
+	}
+	f.updates = append(f.updates, require2FA)
+	return &adeia.Role{ID: id, Require2FA: require2FA}, nil
+}
+
+func TestRoleController_UpdateRole(t *testing.T) {
+	serve := func(rs *fakeRoleService, target, body string, permissions ...string) *httptest.ResponseRecorder {
+		h := Authenticate(testLogger, newFakeAuthService(permissions...))(NewRoleController(testLogger, rs).Handler())
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer valid")
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("reject callers without MANAGE_ROLES", func(t *testing.T) {
+		rs := &fakeRoleService{}
+		rr := serve(rs, "/1", `{"require_2fa": true}`, "VIEW_USERS")
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Empty(t, rs.updates)
+	})
+
+	t.Run("require the require_2fa field", func(t *testing.T) {
+		rs := &fakeRoleService{}
+		rr := serve(rs, "/1", `{}`, "MANAGE_ROLES")
+
+		assert.Equal(t, "VALIDATION_FAILED", errorCode(t, rr))
+		assert.Empty(t, rs.updates)
+	})
+
+	t.Run("update require_2fa, even to false", func(t *testing.T) {
+		rs := &fakeRoleService{}
+		rr := serve(rs, "/1", `{"require_2fa": false}`, "MANAGE_ROLES")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, []bool{false}, rs.updates)
+	})
+
+	t.Run("return not found for a missing role", func(t *testing.T) {
+		rr := serve(&fakeRoleService{}, "/abc", `{"require_2fa": true}`, "MANAGE_ROLES")
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -0,0 +1,145 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"adeia/pkg/log"
+	"adeia/pkg/util/httputil"
+)
+
+// defaultHealthCheckTimeout is the time that each readiness check gets, if it is
+// not configured.
+const defaultHealthCheckTimeout = 2 * time.Second
+
+// Statuses of the server and its components, in health responses.
+const (
+	statusOK           = "ok"
+	statusError        = "error"
+	statusShuttingDown = "shutting_down"
+)
+
+// CheckFunc checks if a dependency of the server, like the database, is ready. It
+// must return when ctx is done.
+type CheckFunc func(ctx context.Context) error
+
+// readinessCheck is a named CheckFunc.
+type readinessCheck struct {
+	name  string
+	check CheckFunc
+}
+
+// health tracks the readiness of the server, and serves the health endpoints.
+type health struct {
+	// ready is 1 while the server is accepting traffic; it is accessed atomically.
+	ready   int32
+	log     log.Logger
+	timeout time.Duration
+	checks  []readinessCheck
+}
+
+// healthResponse represents the response of the health endpoints.
+type healthResponse struct {
+	Status     string                      `json:"status"`
+	Components map[string]*componentStatus `json:"components,omitempty"`
+}
+
+// componentStatus represents the result of the readiness check of a component.
+// The error of a failed check is only logged, as the health endpoints are not
+// authenticated.
+type componentStatus struct {
+	Status   string `json:"status"`
+	Duration string `json:"duration"`
+}
+
+func (h *health) setReady(ready bool) {
+	var v int32
+	if ready {
+		v = 1
+	}
+	atomic.StoreInt32(&h.ready, v)
+}
+
+func (h *health) isReady() bool {
+	return atomic.LoadInt32(&h.ready) == 1
+}
+
+// liveness responds with 200 as long as the server can serve requests. It does not
+// check any dependencies, so that the server is not restarted when they are down.
+func (h *health) liveness(w http.ResponseWriter, _ *http.Request) {
+	_ = httputil.Respond(w, http.StatusOK, &healthResponse{Status: statusOK})
+}
+
+// readiness responds with 200 if the server is accepting traffic and all the
+// readiness checks pass, and with 503 otherwise, along with the status of each
+// component. It fails as soon as shutdown begins, without running the checks.
+func (h *health) readiness(w http.ResponseWriter, r *http.Request) {
+	if !h.isReady() {
+		_ = httputil.Respond(w, http.StatusServiceUnavailable, &healthResponse{Status: statusShuttingDown})
+		return
+	}
+
+	resp := &healthResponse{Status: statusOK, Components: h.runChecks(r.Context())}
+	code := http.StatusOK
+	for _, c := range resp.Components {
+		if c.Status != statusOK {
+			resp.Status = statusError
+			code = http.StatusServiceUnavailable
+		}
+	}
+	_ = httputil.Respond(w, code, resp)
+}
+
+// runChecks runs the readiness checks concurrently, each with the timeout.
+func (h *health) runChecks(ctx context.Context) map[string]*componentStatus {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	components := make(map[string]*componentStatus, len(h.checks))
+
+	for _, c := range h.checks {
+		wg.Add(1)
+		go func(c readinessCheck) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, h.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := runCheck(ctx, c.check)
+			s := &componentStatus{Status: statusOK, Duration: time.Since(start).String()}
+			if err != nil {
+				h.log.Warnf("readiness check %q failed: %v", c.name, err)
+				s.Status = statusError
+			}
+
+			mu.Lock()
+			components[c.name] = s
+			mu.Unlock()
+		}(c)
+	}
+
+	wg.Wait()
+	return components
+}
+
+// runCheck runs the check, returning early if ctx is done before the check does,
+// so that a check that ignores ctx cannot hold up the response.
+func runCheck(ctx context.Context, check CheckFunc) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- check(ctx)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,114 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	logzap "adeia/pkg/log/zap"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+var testLogger = &logzap.Logger{SugaredLogger: zap.NewNop().Sugar()}
+
+func serveHealth(t *testing.T, handler http.HandlerFunc) (int, *healthResponse) {
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	resp := &healthResponse{}
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), resp))
+	return rr.Code, resp
+}
+
+func TestHealth_Liveness(t *testing.T) {
+	// liveness does not depend on readiness or the checks
+	h := &health{log: testLogger, timeout: time.Second}
+	h.checks = []readinessCheck{{"db", func(context.Context) error { return errors.New("down") }}}
+
+	code, resp := serveHealth(t, h.liveness)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, statusOK, resp.Status)
+	assert.Nil(t, resp.Components)
+}
+
+func TestHealth_Readiness(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+
+	t.Run("return 503 when not ready", func(t *testing.T) {
+		h := &health{log: testLogger, timeout: time.Second, checks: []readinessCheck{{"db", ok}}}
+
+		code, resp := serveHealth(t, h.readiness)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, statusShuttingDown, resp.Status)
+		assert.Nil(t, resp.Components)
+	})
+
+	t.Run("return 200 when all checks pass", func(t *testing.T) {
+		h := &health{log: testLogger, timeout: time.Second, checks: []readinessCheck{{"db", ok}, {"cache", ok}}}
+		h.setReady(true)
+
+		code, resp := serveHealth(t, h.readiness)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, statusOK, resp.Status)
+		assert.Len(t, resp.Components, 2)
+		assert.Equal(t, statusOK, resp.Components["db"].Status)
+		assert.Equal(t, statusOK, resp.Components["cache"].Status)
+	})
+
+	t.Run("return 503 when a check fails", func(t *testing.T) {
+		fail := func(context.Context) error { return errors.New("dial tcp 10.0.0.5:6379: connection refused") }
+		h := &health{log: testLogger, timeout: time.Second, checks: []readinessCheck{{"db", ok}, {"cache", fail}}}
+		h.setReady(true)
+
+		code, resp := serveHealth(t, h.readiness)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, statusError, resp.Status)
+		assert.Equal(t, statusOK, resp.Components["db"].Status)
+		assert.Equal(t, statusError, resp.Components["cache"].Status)
+	})
+
+	t.Run("do not expose the errors of the checks", func(t *testing.T) {
+		fail := func(context.Context) error { return errors.New("dial tcp 10.0.0.5:6379: connection refused") }
+		h := &health{log: testLogger, timeout: time.Second, checks: []readinessCheck{{"cache", fail}}}
+		h.setReady(true)
+
+		rr := httptest.NewRecorder()
+		h.readiness(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.NotContains(t, rr.Body.String(), "10.0.0.5")
+	})
+
+	t.Run("return 503 when a check times out", func(t *testing.T) {
+		block := make(chan struct{})
+		defer close(block)
+		// the check ignores ctx, so it must not hold up the response
+		hang := func(context.Context) error { <-block; return nil }
+		h := &health{log: testLogger, timeout: 10 * time.Millisecond, checks: []readinessCheck{{"db", hang}}}
+		h.setReady(true)
+
+		code, resp := serveHealth(t, h.readiness)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, statusError, resp.Status)
+		assert.Equal(t, statusError, resp.Components["db"].Status)
+	})
+}
+
+func TestServer_Handler(t *testing.T) {
+	s := &Server{health: &health{log: testLogger, timeout: time.Second}}
+	s.health.setReady(true)
+
+	for _, path := range []string{"/healthz", "/readyz"} {
+		rr := httptest.NewRecorder()
+		s.handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, rr.Code, path)
+	}
+}
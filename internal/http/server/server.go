@@ -14,12 +14,17 @@ import (
 	"time"
 
 	"adeia/internal/config"
+	"adeia/pkg/http/middleware"
 	"adeia/pkg/log"
 	"adeia/pkg/util/constants"
 
 	"github.com/go-chi/chi"
 )
 
+// defaultShutdownTimeout is the grace period for pending requests to complete on
+// shutdown, if it is not configured.
+const defaultShutdownTimeout = 5 * time.Second
+
 // Controller is the interface for all methods of the controllers.
 type Controller interface {
 	Pattern() string
@@ -28,19 +33,32 @@ type Controller interface {
 
 // Server represents the server.
 type Server struct {
+	admin       *http.ServeMux
 	config      *config.ServerConfig
 	controllers []Controller
+	health      *health
 	log         log.Logger
+	middlewares middleware.FuncChain
 	srv         chi.Router
 }
 
-// New creates a new *Server.
-func New(conf *config.ServerConfig, log log.Logger, controllers ...Controller) *Server {
+// New creates a new *Server. The middleware chain is applied around the router,
+// to every request. It is composed using middleware.FuncChain.Compose, so the last
+// func in it is the outermost one, and runs first.
+func New(conf *config.ServerConfig, log log.Logger, chain middleware.FuncChain, controllers ...Controller) *Server {
 	log.Debug("initializing new API server...")
+	timeout := seconds(conf.HealthCheckTimeout)
+	if timeout == 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+
 	return &Server{
+		admin:       http.NewServeMux(),
 		config:      conf,
 		controllers: controllers,
+		health:      &health{log: log, timeout: timeout},
 		log:         log,
+		middlewares: chain,
 		srv:         chi.NewRouter(),
 	}
 }
@@ -56,48 +74,181 @@ func (s *Server) BindControllers() {
 	})
 }
 
-// Serve starts serving the API. All server-related errors are handled here.
-func (s *Server) Serve() {
+// AddReadinessCheck adds a check to the readiness endpoint, /readyz, for the named
+// component (like "postgres"). The server is ready only when all the checks pass.
+// Each check runs with a timeout, and must return when its ctx is done.
+func (s *Server) AddReadinessCheck(name string, check CheckFunc) {
+	s.health.checks = append(s.health.checks, readinessCheck{name, check})
+}
+
+// HandleAdmin registers the handler for the pattern (like "/metrics") on the admin
+// listener, that is separate from the API, so that it can be kept private. The
+// middleware chain is not applied to it.
+func (s *Server) HandleAdmin(pattern string, handler http.Handler) {
+	s.admin.Handle(pattern, handler)
+}
+
+// handler returns the handler of the API listener. The health endpoints, /healthz
+// and /readyz, are served outside the middleware chain, so that probes are not
+// rate-limited or logged.
+func (s *Server) handler() http.Handler {
+	api := s.middlewares.Compose(s.srv)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthz":
+			s.health.liveness(w, r)
+		case "/readyz":
+			s.health.readiness(w, r)
+		default:
+			api.ServeHTTP(w, r)
+		}
+	})
+}
+
+// Serve starts serving the API, over TLS if a certificate is configured. All
+// server-related errors are handled here, and an error is returned only if the
+// server cannot be started, or stops unexpectedly.
+//
+// On SIGHUP, the TLS certificate is reloaded from its files. On ctrl+c or SIGTERM,
+// the server is gracefully shutdown; readiness fails as soon as shutdown begins,
+// and the listeners are closed after the shutdown delay, so that load balancers
+// stop sending traffic before that.
+func (s *Server) Serve() error {
 	addr := s.config.Host + ":" + strconv.Itoa(s.config.Port)
-	// TODO: add timeouts
-	// TODO: add TLS support
-	// TODO: add rate-limiter
 	srv := &http.Server{
-		Addr:    addr,
-		Handler: s.srv,
+		Addr:              addr,
+		Handler:           s.handler(),
+		ReadTimeout:       seconds(s.config.ReadTimeout),
+		ReadHeaderTimeout: seconds(s.config.ReadHeaderTimeout),
+		WriteTimeout:      seconds(s.config.WriteTimeout),
+		IdleTimeout:       seconds(s.config.IdleTimeout),
+	}
+
+	var certs *certReloader
+	if s.config.TLSCertFile != "" {
+		var err error
+		if certs, err = newCertReloader(s.config.TLSCertFile, s.config.TLSKeyFile); err != nil {
+			return err
+		}
+		if srv.TLSConfig, err = newTLSConfig(certs, s.config.MinTLSVersion); err != nil {
+			return err
+		}
 	}
 
 	// catch server errors in a channel
-	serverErrs := make(chan error, 1)
+	serverErrs := make(chan error, 3)
+	servers := []*http.Server{srv}
 
 	go func() {
-		s.log.Infof("starting server on %q", addr)
-		serverErrs <- srv.ListenAndServe()
+		if certs == nil {
+			s.log.Infof("starting server on %q", addr)
+			serverErrs <- srv.ListenAndServe()
+			return
+		}
+		s.log.Infof("starting server on %q, with tls", addr)
+		// the certificate is already in TLSConfig
+		serverErrs <- srv.ListenAndServeTLS("", "")
 	}()
 
+	if certs != nil && s.config.RedirectPort != 0 {
+		redirectAddr := s.config.Host + ":" + strconv.Itoa(s.config.RedirectPort)
+		redirect := &http.Server{
+			Addr:              redirectAddr,
+			Handler:           redirectHandler(s.config.Port),
+			ReadHeaderTimeout: seconds(s.config.ReadHeaderTimeout),
+			IdleTimeout:       seconds(s.config.IdleTimeout),
+		}
+		servers = append(servers, redirect)
+
+		go func() {
+			s.log.Infof("redirecting http requests on %q to https", redirectAddr)
+			serverErrs <- redirect.ListenAndServe()
+		}()
+	}
+
+	if s.config.AdminPort != 0 {
+		adminAddr := s.config.AdminHost + ":" + strconv.Itoa(s.config.AdminPort)
+		admin := &http.Server{
+			Addr:              adminAddr,
+			Handler:           s.admin,
+			ReadHeaderTimeout: seconds(s.config.ReadHeaderTimeout),
+			WriteTimeout:      seconds(s.config.WriteTimeout),
+			IdleTimeout:       seconds(s.config.IdleTimeout),
+		}
+		servers = append(servers, admin)
+
+		go func() {
+			s.log.Infof("starting admin server on %q", adminAddr)
+			serverErrs <- admin.ListenAndServe()
+		}()
+	}
+
+	s.health.setReady(true)
+
 	// chan to listen for ctrl+c, SIGTERM
 	interruptChan := make(chan os.Signal, 1)
 	signal.Notify(interruptChan, os.Interrupt, syscall.SIGTERM)
 
-	// gracefully shutdown server
-	select {
-	case err := <-serverErrs:
-		if err != http.ErrServerClosed {
-			s.log.Errorf("error while serving: %v", err)
+	// chan to listen for SIGHUP, to reload the certificate
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+	defer signal.Stop(reloadChan)
+
+	for {
+		select {
+		case err := <-serverErrs:
+			s.health.setReady(false)
+			if err != http.ErrServerClosed {
+				s.log.Errorf("error while serving: %v", err)
+				s.shutdown(servers...)
+				return err
+			}
+			return nil
+
+		case <-reloadChan:
+			if certs == nil {
+				s.log.Info("received SIGHUP, but tls is not enabled; ignoring")
+				continue
+			}
+			if err := certs.reload(); err != nil {
+				s.log.Errorf("failed to reload tls certificate; the current one is still in use: %v", err)
+			} else {
+				s.log.Info("reloaded tls certificate")
+			}
+
+		case sig := <-interruptChan:
+			s.log.Infof("received: %v; starting shutdown...", sig)
+			s.health.setReady(false)
+			if delay := seconds(s.config.ShutdownDelay); delay > 0 {
+				s.log.Infof("waiting %v for load balancers to stop sending traffic...", delay)
+				time.Sleep(delay)
+			}
+			s.shutdown(servers...)
+			return nil
 		}
+	}
+}
 
-	case sig := <-interruptChan:
-		s.log.Infof("received: %v; starting shutdown...", sig)
-
-		// wait for 5 seconds for pending requests to complete
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+// shutdown gracefully shuts down the servers, waiting for pending requests to
+// complete. Requests that exceed the grace period are cancelled.
+func (s *Server) shutdown(servers ...*http.Server) {
+	grace := seconds(s.config.ShutdownTimeout)
+	if grace == 0 {
+		grace = defaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
 
-		// cancel requests that exceed the context deadline (5 seconds)
+	for _, srv := range servers {
 		if err := srv.Shutdown(ctx); err != nil {
-			s.log.Errorf("failed to gracefully shutdown server: %v", err)
+			s.log.Errorf("failed to gracefully shutdown server on %q: %v", srv.Addr, err)
 		} else {
-			s.log.Info("server gracefully stopped")
+			s.log.Infof("server on %q gracefully stopped", srv.Addr)
 		}
 	}
 }
+
+// seconds converts the no. of seconds in the config to a time.Duration.
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}
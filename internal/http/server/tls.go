@@ -0,0 +1,95 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// tlsVersions maps the supported values of the min_tls_version config to their
+// tls package constants.
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// certReloader holds the TLS certificate of the server, so that it can be reloaded
+// (like after a renewal) without a restart.
+type certReloader struct {
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	certFile string
+	keyFile  string
+}
+
+// newCertReloader creates a new *certReloader, and loads the certificate.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	cr := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := cr.reload(); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// reload loads the certificate from its files. The current certificate is kept if
+// they cannot be loaded.
+func (cr *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return fmt.Errorf("cannot load tls certificate: %v", err)
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.cert = &cert
+	return nil
+}
+
+// getCertificate is used as tls.Config.GetCertificate, so that every handshake
+// uses the latest certificate.
+func (cr *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	return cr.cert, nil
+}
+
+// newTLSConfig creates the tls.Config of the server, with the minimum version.
+func newTLSConfig(cr *certReloader, minVersion string) (*tls.Config, error) {
+	if minVersion == "" {
+		minVersion = "1.2"
+	}
+	v, ok := tlsVersions[minVersion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported minimum tls version: %q", minVersion)
+	}
+
+	return &tls.Config{
+		MinVersion:     v,
+		GetCertificate: cr.getCertificate,
+	}, nil
+}
+
+// redirectHandler redirects all requests to the same URL on HTTPS, at the port.
+func redirectHandler(port int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if port != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(port))
+		}
+
+		u := *r.URL
+		u.Scheme = "https"
+		u.Host = host
+		http.Redirect(w, r, u.String(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,139 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeCert writes a self-signed certificate for the common name, and its key, to dir.
+func writeCert(t *testing.T, dir, commonName string) (certFile, keyFile string) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.Nil(t, err)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	assert.Nil(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.Nil(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
+
+func commonName(t *testing.T, cr *certReloader) string {
+	cert, _ := cr.getCertificate(nil)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	assert.Nil(t, err)
+	return leaf.Subject.CommonName
+}
+
+func TestCertReloader(t *testing.T) {
+	t.Run("reload certificate", func(t *testing.T) {
+		t.Parallel()
+		dir, _ := ioutil.TempDir("", "certs")
+		defer os.RemoveAll(dir)
+
+		certFile, keyFile := writeCert(t, dir, "old")
+		cr, err := newCertReloader(certFile, keyFile)
+		assert.Nil(t, err)
+		assert.Equal(t, "old", commonName(t, cr))
+
+		writeCert(t, dir, "new")
+		assert.Nil(t, cr.reload())
+		assert.Equal(t, "new", commonName(t, cr))
+	})
+
+	t.Run("keep current certificate when reload fails", func(t *testing.T) {
+		t.Parallel()
+		dir, _ := ioutil.TempDir("", "certs")
+		defer os.RemoveAll(dir)
+
+		certFile, keyFile := writeCert(t, dir, "old")
+		cr, _ := newCertReloader(certFile, keyFile)
+
+		_ = os.Remove(keyFile)
+		assert.Error(t, cr.reload())
+		assert.Equal(t, "old", commonName(t, cr))
+	})
+
+	t.Run("return error when files do not exist", func(t *testing.T) {
+		t.Parallel()
+		_, err := newCertReloader("unknown.pem", "unknown.key")
+		assert.Error(t, err)
+	})
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	cr := &certReloader{}
+	tests := []struct {
+		name       string
+		minVersion string
+		want       uint16
+		wantErr    bool
+	}{
+		{"default to tls 1.2", "", tls.VersionTLS12, false},
+		{"tls 1.3", "1.3", tls.VersionTLS13, false},
+		{"unsupported version", "1.0", 0, true},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := newTLSConfig(cr, tc.minVersion)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got.MinVersion)
+		})
+	}
+}
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		port   int
+		target string
+		want   string
+	}{
+		{"default https port", 443, "http://example.com:8080/v1/users?limit=1", "https://example.com/v1/users?limit=1"},
+		{"custom https port", 8443, "http://example.com/v1/users", "https://example.com:8443/v1/users"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rr := httptest.NewRecorder()
+			redirectHandler(tc.port).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.target, nil))
+
+			assert.Equal(t, http.StatusPermanentRedirect, rr.Code)
+			assert.Equal(t, tc.want, rr.Header().Get("Location"))
+		})
+	}
+}
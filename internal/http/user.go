@@ -5,8 +5,10 @@
 package http
 
 import (
+	"bytes"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"adeia"
 	"adeia/pkg/errs"
@@ -50,7 +52,17 @@ func NewUserController(log log.Logger, us adeia.UserService) *UserController {
 func (uc *UserController) BindRoutes() {
 	r := chi.NewRouter()
 
+	r.Method(http.MethodGet, "/", uc.GetAllUsers())
 	r.Method(http.MethodPost, "/", uc.CreateUser())
+	r.Method(http.MethodPost, "/import", uc.ImportUsers())
+	r.Method(http.MethodGet, "/{empID}", uc.GetUser())
+	r.Method(http.MethodPatch, "/{empID}", uc.UpdateUser())
+	r.Method(http.MethodDelete, "/{empID}", uc.DeleteUser())
+	r.Method(http.MethodGet, "/{empID}/preferences", uc.GetUserPreferences())
+	r.Method(http.MethodPatch, "/{empID}/preferences", uc.UpdateUserPreferences())
+	r.Method(http.MethodPost, "/{empID}/deactivate", uc.DeactivateUser())
+	r.Method(http.MethodPost, "/{empID}/restore", uc.RestoreUser())
+	r.Method(http.MethodPost, "/{empID}/unlock", uc.UnlockUser())
 	//r.Method(http.MethodGet, "/", uc.CheckContext())
 
 	uc.handler = r
@@ -75,11 +87,13 @@ func (uc *UserController) BindRoutes() {
 
 // CreateUser creates a new User if it doesn't exist already.
 func (uc *UserController) CreateUser() *ProtectedHandler {
+	// validated by the service, as the same rules apply to imported users
 	type request struct {
 		Name        string `json:"name"`
 		EmployeeID  string `json:"employee_id,omitempty"`
 		Email       string `json:"email"`
 		Designation string `json:"designation"`
+		Department  string `json:"department"`
 	}
 
 	return &ProtectedHandler{
@@ -91,17 +105,16 @@ func (uc *UserController) CreateUser() *ProtectedHandler {
 				return
 			}
 
-			// validate request
-
 			user, err := uc.userService.CreateUser(
 				r.Context(),
 				body.Name,
 				body.Email,
 				body.EmployeeID,
 				body.Designation,
+				body.Department,
 			)
 			if err != nil {
-				httputil.LogWriteErr(uc.log, httputil.RespondWithErr(w, err.(errs.ResponseError)))
+				httputil.LogWriteErr(uc.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
 				return
 			}
 
@@ -115,3 +128,234 @@ func (uc *UserController) CreateUser() *ProtectedHandler {
 		},
 	}
 }
+
+// ImportUsers creates Users in bulk from a CSV file, uploaded in the "file" field
+// of a multipart/form-data request. Nothing is created when the dry_run query param
+// is true. A per-row report of the import is returned.
+func (uc *UserController) ImportUsers() *ProtectedHandler {
+	return &ProtectedHandler{
+		PermissionName: "CREATE_USERS",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			// CSV files are sniffed as plain text
+			file, err := httputil.DecodeFile(w, r, "file", "text/plain")
+			if err != nil {
+				uc.log.Debug(err)
+				return
+			}
+			dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+			report, err := uc.userService.ImportUsers(r.Context(), bytes.NewReader(file.Content), dryRun)
+			if err != nil {
+				httputil.LogWriteErr(uc.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			httputil.LogWriteErr(uc.log, httputil.RespondWithData(w, http.StatusOK, report))
+		},
+	}
+}
+
+// GetAllUsers returns a page of Users, that can be sorted, filtered and paginated
+// using the query params. Deleted Users are also returned when the include_deleted
+// query param is true, which requires the VIEW_DELETED_USERS permission.
+func (uc *UserController) GetAllUsers() *ProtectedHandler {
+	return &ProtectedHandler{
+		PermissionName: "VIEW_USERS",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			spec, err := httputil.DecodeQuery(w, r, adeia.UserQueryOptions)
+			if err != nil {
+				uc.log.Debug(err)
+				return
+			}
+			inclDeleted, _ := strconv.ParseBool(r.URL.Query().Get("include_deleted"))
+			if inclDeleted && !hasPermission(r.Context(), "VIEW_DELETED_USERS") {
+				httputil.LogWriteErr(uc.log, httputil.RespondWithErr(w, r, adeia.ErrPermissionDenied))
+				return
+			}
+
+			users, nextCursor, err := uc.userService.GetAllUsers(r.Context(), spec, inclDeleted)
+			if err != nil {
+				httputil.LogWriteErr(uc.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			httputil.LogWriteErr(uc.log, httputil.RespondWithPage(w, r, http.StatusOK, users, nextCursor))
+		},
+	}
+}
+
+// GetUser returns the User with the employee ID in the URL.
+func (uc *UserController) GetUser() *ProtectedHandler {
+	return &ProtectedHandler{
+		PermissionName: "VIEW_USERS",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			user, err := uc.userService.GetUserByEmpID(r.Context(), chi.URLParam(r, "empID"))
+			if err != nil {
+				httputil.LogWriteErr(uc.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			httputil.LogWriteErr(uc.log, httputil.RespondWithData(w, http.StatusOK, user))
+		},
+	}
+}
+
+// UpdateUser updates the profile fields of the User with the employee ID in the URL.
+func (uc *UserController) UpdateUser() *ProtectedHandler {
+	type request struct {
+		Name        string `json:"name,omitempty" validate:"max=255"`
+		Designation string `json:"designation,omitempty" validate:"max=255"`
+		Department  string `json:"department,omitempty" validate:"max=255"`
+	}
+	checkRules(request{})
+
+	return &ProtectedHandler{
+		PermissionName: "UPDATE_USERS",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			var body request
+			if err := httputil.Decode(w, r, &body); err != nil {
+				uc.log.Debug(err)
+				return
+			}
+
+			user, err := uc.userService.UpdateUser(
+				r.Context(),
+				chi.URLParam(r, "empID"),
+				body.Name,
+				body.Designation,
+				body.Department,
+			)
+			if err != nil {
+				httputil.LogWriteErr(uc.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			httputil.LogWriteErr(uc.log, httputil.RespondWithData(w, http.StatusOK, user))
+		},
+	}
+}
+
+// GetUserPreferences returns the preferences of the User with the employee ID in the URL.
+func (uc *UserController) GetUserPreferences() *ProtectedHandler {
+	return &ProtectedHandler{
+		PermissionName: "VIEW_USERS",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			prefs, err := uc.userService.GetUserPreferences(r.Context(), chi.URLParam(r, "empID"))
+			if err != nil {
+				httputil.LogWriteErr(uc.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			httputil.LogWriteErr(uc.log, httputil.RespondWithData(w, http.StatusOK, prefs))
+		},
+	}
+}
+
+// preferencesRequest is the request body to update the preferences of a User.
+type preferencesRequest struct {
+	NotificationChannels *[]string `json:"notification_channels" validate:"oneof=email"`
+	EmailDigest          *bool     `json:"email_digest"`
+	Locale               *string   `json:"locale" validate:"locale,max=35"`
+	TimeZone             *string   `json:"time_zone" validate:"timezone,max=64"`
+}
+
+// patch converts the preferencesRequest to an adeia.UserPreferencesPatch.
+func (p *preferencesRequest) patch() *adeia.UserPreferencesPatch {
+	return &adeia.UserPreferencesPatch{
+		Channels:    p.NotificationChannels,
+		EmailDigest: p.EmailDigest,
+		Locale:      p.Locale,
+		TimeZone:    p.TimeZone,
+	}
+}
+
+// UpdateUserPreferences updates the preferences of the User with the employee ID in
+// the URL. Fields that are absent are left unchanged.
+func (uc *UserController) UpdateUserPreferences() *ProtectedHandler {
+	checkRules(preferencesRequest{})
+	return &ProtectedHandler{
+		PermissionName: "UPDATE_USERS",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			var body preferencesRequest
+			if err := httputil.Decode(w, r, &body); err != nil {
+				uc.log.Debug(err)
+				return
+			}
+
+			prefs, err := uc.userService.UpdateUserPreferences(
+				r.Context(),
+				chi.URLParam(r, "empID"),
+				body.patch(),
+			)
+			if err != nil {
+				httputil.LogWriteErr(uc.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			httputil.LogWriteErr(uc.log, httputil.RespondWithData(w, http.StatusOK, prefs))
+		},
+	}
+}
+
+// DeactivateUser deactivates the User with the employee ID in the URL.
+func (uc *UserController) DeactivateUser() *ProtectedHandler {
+	return &ProtectedHandler{
+		PermissionName: "DEACTIVATE_USERS",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			user, err := uc.userService.DeactivateUser(r.Context(), chi.URLParam(r, "empID"))
+			if err != nil {
+				httputil.LogWriteErr(uc.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			httputil.LogWriteErr(uc.log, httputil.RespondWithData(w, http.StatusOK, user))
+		},
+	}
+}
+
+// DeleteUser soft-deletes the User with the employee ID in the URL.
+func (uc *UserController) DeleteUser() *ProtectedHandler {
+	return &ProtectedHandler{
+		PermissionName: "DELETE_USERS",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			if err := uc.userService.DeleteUser(r.Context(), chi.URLParam(r, "empID")); err != nil {
+				httputil.LogWriteErr(uc.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		},
+	}
+}
+
+// UnlockUser removes the login lockout of the User with the employee ID in the URL,
+// that is caused by too many failed logins.
+func (uc *UserController) UnlockUser() *ProtectedHandler {
+	return &ProtectedHandler{
+		PermissionName: "UNLOCK_USERS",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			if err := uc.userService.UnlockUser(r.Context(), chi.URLParam(r, "empID")); err != nil {
+				httputil.LogWriteErr(uc.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		},
+	}
+}
+
+// RestoreUser restores the soft-deleted User with the employee ID in the URL.
+func (uc *UserController) RestoreUser() *ProtectedHandler {
+	return &ProtectedHandler{
+		PermissionName: "RESTORE_USERS",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			user, err := uc.userService.RestoreUser(r.Context(), chi.URLParam(r, "empID"))
+			if err != nil {
+				httputil.LogWriteErr(uc.log, httputil.RespondWithErr(w, r, err.(errs.ResponseError)))
+				return
+			}
+
+			httputil.LogWriteErr(uc.log, httputil.RespondWithData(w, http.StatusOK, user))
+		},
+	}
+}
@@ -0,0 +1,143 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"adeia"
+	"adeia/pkg/query"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeUserService is an adeia.UserService that records the Users that it unlocks,
+// and whether deleted Users were listed. Methods that are not overridden panic.
+type fakeUserService struct {
+	adeia.UserService
+	unlocked    []string
+	inclDeleted []bool
+	restoreErr  error
+}
+
+func (f *fakeUserService) GetAllUsers(_ context.Context, _ *query.Spec, inclDeleted bool) ([]*adeia.User, string, error) {
+	f.inclDeleted = append(f.inclDeleted, inclDeleted)
+	return []*adeia.User{}, "", nil
+}
+
+func (f *fakeUserService) RestoreUser(_ context.Context, empID string) (*adeia.User, error) {
+	if f.restoreErr != nil {
+		return nil, f.restoreErr
+	}
+	return &adeia.User{EmployeeID: empID}, nil
+}
+
+func (f *fakeUserService) UnlockUser(_ context.Context, empID string) error {
+	f.unlocked = append(f.unlocked, empID)
+	return nil
+}
+
+func TestUserController_UnlockUser(t *testing.T) {
+	serve := func(us *fakeUserService, token string, permissions ...string) *httptest.ResponseRecorder {
+		h := Authenticate(testLogger, newFakeAuthService(permissions...))(NewUserController(testLogger, us).Handler())
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/BAR456/unlock", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("reject anonymous callers", func(t *testing.T) {
+		us := &fakeUserService{}
+		rr := serve(us, "")
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Empty(t, us.unlocked)
+	})
+
+	t.Run("reject callers without UNLOCK_USERS", func(t *testing.T) {
+		us := &fakeUserService{}
+		rr := serve(us, "valid", "VIEW_USERS", "UPDATE_USERS")
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Empty(t, us.unlocked)
+	})
+
+	t.Run("unlock for callers with UNLOCK_USERS", func(t *testing.T) {
+		us := &fakeUserService{}
+		rr := serve(us, "valid", "UNLOCK_USERS")
+
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		assert.Equal(t, []string{"BAR456"}, us.unlocked)
+	})
+}
+
+func TestUserController_GetAllUsers(t *testing.T) {
+	serve := func(us *fakeUserService, target string, permissions ...string) *httptest.ResponseRecorder {
+		h := Authenticate(testLogger, newFakeAuthService(permissions...))(NewUserController(testLogger, us).Handler())
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Authorization", "Bearer valid")
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("list active users with VIEW_USERS", func(t *testing.T) {
+		us := &fakeUserService{}
+		rr := serve(us, "/", "VIEW_USERS")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, []bool{false}, us.inclDeleted)
+	})
+
+	t.Run("reject include_deleted without VIEW_DELETED_USERS", func(t *testing.T) {
+		us := &fakeUserService{}
+		rr := serve(us, "/?include_deleted=true", "VIEW_USERS")
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, "PERMISSION_DENIED", errorCode(t, rr))
+		assert.Empty(t, us.inclDeleted)
+	})
+
+	t.Run("list deleted users with VIEW_DELETED_USERS", func(t *testing.T) {
+		us := &fakeUserService{}
+		rr := serve(us, "/?include_deleted=true", "VIEW_USERS", "VIEW_DELETED_USERS")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, []bool{true}, us.inclDeleted)
+	})
+}
+
+func TestUserController_RestoreUser(t *testing.T) {
+	serve := func(us *fakeUserService, permissions ...string) *httptest.ResponseRecorder {
+		h := Authenticate(testLogger, newFakeAuthService(permissions...))(NewUserController(testLogger, us).Handler())
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/BAR456/restore", nil)
+		req.Header.Set("Authorization", "Bearer valid")
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("reject callers without RESTORE_USERS", func(t *testing.T) {
+		rr := serve(&fakeUserService{}, "VIEW_USERS")
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("restore for callers with RESTORE_USERS", func(t *testing.T) {
+		rr := serve(&fakeUserService{}, "RESTORE_USERS")
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("respond with conflict when the user cannot be restored", func(t *testing.T) {
+		rr := serve(&fakeUserService{restoreErr: adeia.ErrResourceConflict}, "RESTORE_USERS")
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, "RESOURCE_CONFLICT", errorCode(t, rr))
+	})
+}
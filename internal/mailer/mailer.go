@@ -0,0 +1,173 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"adeia/pkg/i18n"
+)
+
+const templateExt = ".tmpl"
+
+// Message represents an email that is rendered from a template.
+type Message struct {
+	// To is the email address of the recipient.
+	To string
+
+	// Template is the name of the page template (without the extension), like
+	// "email_verify".
+	Template string
+
+	// Locale is the locale of the recipient, that the template is rendered in.
+	// Messages that are not translated to the locale are rendered in English.
+	Locale string
+
+	// Data is passed to the template when rendering it.
+	Data interface{}
+}
+
+// Mailer is an interface for all mailer-related functions, that implementations
+// must implement.
+type Mailer interface {
+	Send(msgs ...*Message) error
+}
+
+// Templates represents the parsed email templates. Each page template is parsed
+// along with all the layouts and partials.
+type Templates struct {
+	pages     map[string]*template.Template
+	catalogue *i18n.Catalogue
+}
+
+// LoadTemplates parses all the email templates in dir. Links in the templates are
+// built relative to linkBaseURL, using the `link` template func, like
+// `{{link "/activate" "token" .Token}}`.
+//
+// Messages in the templates are translated using the catalogue, with the `t`
+// template func, like `{{t "email_verify.title" "Verify your email"}}`, where the
+// second argument is the English message. The `locale` template func returns the
+// locale that the template is rendered in.
+//
+// A template of another page, like its "title" or "body", is rendered with the
+// `page` template func, like `{{page "account_locked" "body" .Data}}`, which is
+// how digests show the content of the notifications in them.
+func LoadTemplates(dir, linkBaseURL string, catalogue *i18n.Catalogue) (*Templates, error) {
+	var l *i18n.Localizer
+	t := &Templates{make(map[string]*template.Template), catalogue}
+	funcs := template.FuncMap{
+		"link":   linkFunc(linkBaseURL),
+		"t":      l.Translate,
+		"locale": l.Locale,
+		"page":   t.pageFunc(""),
+	}
+
+	var shared []string
+	for _, d := range []string{"layouts", "partials"} {
+		files, err := filepath.Glob(filepath.Join(dir, d, "*"+templateExt))
+		if err != nil {
+			return nil, err
+		}
+		shared = append(shared, files...)
+	}
+
+	pages, err := filepath.Glob(filepath.Join(dir, "pages", "*"+templateExt))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, page := range pages {
+		name := strings.TrimSuffix(filepath.Base(page), templateExt)
+		tmpl, err := template.New(name).Funcs(funcs).ParseFiles(append([]string{page}, shared...)...)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse template %q: %v", name, err)
+		}
+		t.pages[name] = tmpl
+	}
+	return t, nil
+}
+
+// Render renders the page template name in the locale. The subject is rendered
+// from the "title" template, and the body from the page itself.
+func (t *Templates) Render(name, locale string, data interface{}) (subject, body string, err error) {
+	tmpl, err := t.localized(name, locale)
+	if err != nil {
+		return "", "", err
+	}
+
+	var s, b bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&s, "title", data); err != nil {
+		return "", "", err
+	}
+	if err := tmpl.ExecuteTemplate(&b, name+templateExt, data); err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(s.String()), b.String(), nil
+}
+
+// localized returns a copy of the page template name, with the template funcs
+// bound to the locale. A copy is used, as the templates are shared.
+func (t *Templates) localized(name, locale string) (*template.Template, error) {
+	tmpl, ok := t.pages[name]
+	if !ok {
+		return nil, fmt.Errorf("template %q does not exist", name)
+	}
+
+	tmpl, err := tmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+	l := i18n.NewLocalizer(t.catalogue, locale)
+	tmpl.Funcs(template.FuncMap{
+		"t":      l.Translate,
+		"locale": l.Locale,
+		"page":   t.pageFunc(locale),
+	})
+	return tmpl, nil
+}
+
+// pageFunc returns a template func that renders the template (like "body") of the
+// page name in the locale.
+func (t *Templates) pageFunc(locale string) func(name, tmplName string, data interface{}) (template.HTML, error) {
+	return func(name, tmplName string, data interface{}) (template.HTML, error) {
+		tmpl, err := t.localized(name, locale)
+		if err != nil {
+			return "", err
+		}
+
+		var b bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&b, tmplName, data); err != nil {
+			return "", err
+		}
+		// the output of html/template is already escaped
+		return template.HTML(strings.TrimSpace(b.String())), nil
+	}
+}
+
+// linkFunc returns a template func that builds an absolute link from a path and
+// key, value pairs of query params.
+func linkFunc(baseURL string) func(path string, kv ...string) (string, error) {
+	return func(path string, kv ...string) (string, error) {
+		if len(kv)%2 != 0 {
+			return "", fmt.Errorf("link: odd number of query params for %q", path)
+		}
+
+		q := url.Values{}
+		for i := 0; i < len(kv); i += 2 {
+			q.Add(kv[i], kv[i+1])
+		}
+
+		link := strings.TrimSuffix(baseURL, "/") + path
+		if len(q) > 0 {
+			link += "?" + q.Encode()
+		}
+		return link, nil
+	}
+}
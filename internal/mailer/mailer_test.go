@@ -0,0 +1,132 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package mailer
+
+import (
+	"testing"
+
+	"adeia/pkg/i18n"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const templatesPath = "../../web/email_templates"
+
+func TestLoadTemplates(t *testing.T) {
+	t.Run("invalid templates dir", func(t *testing.T) {
+		t.Parallel()
+		got, err := LoadTemplates("non-existent", "", nil)
+		assert.Nil(t, err)
+		assert.Empty(t, got.pages)
+	})
+
+	t.Run("load templates", func(t *testing.T) {
+		t.Parallel()
+		got, err := LoadTemplates(templatesPath, "", nil)
+		assert.Nil(t, err)
+		assert.Contains(t, got.pages, "email_verify")
+		assert.Contains(t, got.pages, "account_activation")
+		assert.Contains(t, got.pages, "notification_digest")
+		assert.Contains(t, got.pages, "password_reset")
+	})
+}
+
+func TestTemplates_Render(t *testing.T) {
+	catalogue, err := i18n.Load("../../web/locales")
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates, err := LoadTemplates(templatesPath, "https://example.com/", catalogue)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("template does not exist", func(t *testing.T) {
+		t.Parallel()
+		_, _, err := templates.Render("foobar", "en", nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("render template", func(t *testing.T) {
+		t.Parallel()
+		data := struct{ Name, Token string }{"foo", "a b"}
+		subject, body, err := templates.Render("account_activation", "en", data)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "Activate your account", subject)
+		assert.Contains(t, body, "Hi foo")
+		assert.Contains(t, body, `href="https://example.com/activate?token=a&#43;b"`)
+		assert.Contains(t, body, `<html lang="en">`)
+	})
+
+	t.Run("render template in locale", func(t *testing.T) {
+		t.Parallel()
+		data := struct{ Name, Token string }{"foo", "a b"}
+		subject, body, err := templates.Render("account_activation", "ta-IN", data)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "உங்கள் கணக்கைச் செயல்படுத்துங்கள்", subject)
+		assert.Contains(t, body, "வணக்கம் foo")
+		assert.Contains(t, body, `<html lang="ta-in">`)
+	})
+
+	t.Run("render the notifications of a digest", func(t *testing.T) {
+		t.Parallel()
+		type item struct {
+			Template string
+			Data     interface{}
+		}
+		data := map[string]interface{}{
+			"Name":  "foo",
+			"Count": 1,
+			"Notifications": []item{{"account_locked", map[string]interface{}{
+				"Name": "foo", "Duration": int64(10), "IP": "10.0.0.1", "Until": "2020-01-01 10:00 UTC",
+			}}},
+		}
+		_, body, err := templates.Render("notification_digest", "ta", data)
+
+		assert.Nil(t, err)
+		assert.Contains(t, body, "<h3>உங்கள் கணக்கு பூட்டப்பட்டுள்ளது</h3>")
+		assert.Contains(t, body, "10 நிமிடங்களுக்குப்")
+		assert.Contains(t, body, "10.0.0.1")
+	})
+
+	t.Run("fallback to english for unsupported locale", func(t *testing.T) {
+		t.Parallel()
+		subject, _, err := templates.Render("email_verify", "fr", nil)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "Verify your email", subject)
+	})
+}
+
+func TestLinkFunc(t *testing.T) {
+	testcases := []struct {
+		base string
+		path string
+		kv   []string
+		want string
+		msg  string
+	}{
+		{"https://example.com", "/foo", nil, "https://example.com/foo", "without query params"},
+		{"https://example.com/", "/foo", nil, "https://example.com/foo", "trailing slash in base"},
+		{"https://example.com", "/foo", []string{"a", "1", "b", "x y"}, "https://example.com/foo?a=1&b=x+y", "with query params"},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.msg, func(t *testing.T) {
+			t.Parallel()
+			got, err := linkFunc(tc.base)(tc.path, tc.kv...)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	t.Run("odd number of query params", func(t *testing.T) {
+		t.Parallel()
+		_, err := linkFunc("")("/foo", "a")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,119 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package smtp
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strconv"
+
+	"adeia/internal/config"
+	"adeia/internal/mailer"
+	"adeia/pkg/i18n"
+)
+
+// SMTP represents a mailer that sends emails through an SMTP server.
+type SMTP struct {
+	addr      string
+	host      string
+	from      string
+	auth      smtp.Auth
+	templates *mailer.Templates
+}
+
+// New creates a new *SMTP, parsing all the email templates. The templates are
+// translated using the catalogue.
+func New(conf *config.MailerConfig, catalogue *i18n.Catalogue) (*SMTP, error) {
+	t, err := mailer.LoadTemplates(conf.TemplatesPath, conf.LinkBaseURL, catalogue)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SMTP{
+		addr:      conf.SMTPHost + ":" + strconv.Itoa(conf.SMTPPort),
+		host:      conf.SMTPHost,
+		from:      conf.Username,
+		auth:      smtp.PlainAuth("", conf.Username, conf.Password, conf.SMTPHost),
+		templates: t,
+	}, nil
+}
+
+// Send renders and sends the messages. All messages are sent over a single
+// connection, so that sending in bulk is cheap.
+func (s *SMTP) Send(msgs ...*mailer.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	// render everything upfront, so that we don't send only some of the messages
+	// because of a broken template
+	rendered := make([][]byte, len(msgs))
+	for i, m := range msgs {
+		subject, body, err := s.templates.Render(m.Template, m.Locale, m.Data)
+		if err != nil {
+			return fmt.Errorf("cannot render template: %v", err)
+		}
+		rendered[i] = buildMessage(s.from, m.To, subject, body)
+	}
+
+	c, err := smtp.Dial(s.addr)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return err
+		}
+	}
+	if ok, _ := c.Extension("AUTH"); ok {
+		if err := c.Auth(s.auth); err != nil {
+			return err
+		}
+	}
+
+	for i, m := range msgs {
+		if err := send(c, s.from, m.To, rendered[i]); err != nil {
+			return fmt.Errorf("cannot send email to %q: %v", m.To, err)
+		}
+	}
+	return c.Quit()
+}
+
+func send(c *smtp.Client, from, to string, msg []byte) error {
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// buildMessage builds a HTML email with the required headers.
+func buildMessage(from, to, subject, body string) []byte {
+	var b bytes.Buffer
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + to + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(body)
+	return b.Bytes()
+}
@@ -0,0 +1,50 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package smtp
+
+import (
+	"testing"
+
+	"adeia/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("load templates", func(t *testing.T) {
+		t.Parallel()
+		s, err := New(&config.MailerConfig{
+			Username:      "foo@example.com",
+			SMTPHost:      "localhost",
+			SMTPPort:      587,
+			TemplatesPath: "../../../web/email_templates",
+		}, nil)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "localhost:587", s.addr)
+		assert.Equal(t, "foo@example.com", s.from)
+	})
+}
+
+func TestSMTP_Send(t *testing.T) {
+	t.Run("no messages", func(t *testing.T) {
+		t.Parallel()
+		s := &SMTP{addr: "256.256.256.256:25"}
+		assert.Nil(t, s.Send())
+	})
+}
+
+func TestBuildMessage(t *testing.T) {
+	t.Parallel()
+	want := "From: foo@example.com\r\n" +
+		"To: bar@example.com\r\n" +
+		"Subject: =?utf-8?q?V=C3=A9rify?=\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/html; charset=UTF-8\r\n" +
+		"\r\n" +
+		"<p>body</p>"
+	got := buildMessage("foo@example.com", "bar@example.com", "Vérify", "<p>body</p>")
+	assert.Equal(t, want, string(got))
+}
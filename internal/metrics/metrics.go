@@ -0,0 +1,127 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+// Package metrics holds the Prometheus metrics of adeia. The metrics are
+// package-level, so that they can be updated from anywhere, and are exposed
+// through a Registry created by NewRegistry.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "adeia"
+
+// unmatchedRoute is the route label of requests that did not match any route, so
+// that arbitrary paths do not create new series.
+const unmatchedRoute = "unmatched"
+
+// otherMethod is the method label of requests with a method that is not a
+// standard HTTP method, so that arbitrary methods do not create new series.
+const otherMethod = "other"
+
+// methods are the standard HTTP methods, that are used as method labels as-is.
+var methods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodConnect: true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// Results of a login, for the Logins metric.
+const (
+	LoginSuccess = "success"
+	LoginFailure = "failure"
+)
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "No. of HTTP requests, by method, route pattern and status.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of HTTP requests, by method, route pattern and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// UsersCreated counts the Users that are created, including imported ones.
+	UsersCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "users_created_total",
+		Help:      "No. of users created.",
+	})
+
+	// Logins counts the logins, by result (LoginSuccess or LoginFailure).
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "No. of logins, by result.",
+	}, []string{"result"})
+
+	// LoginLockouts counts the accounts and IPs that are locked, due to too many
+	// failed logins.
+	LoginLockouts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_lockouts_total",
+		Help:      "No. of accounts and IPs locked due to too many failed logins.",
+	})
+)
+
+// NewRegistry creates a new *prometheus.Registry with the metrics of adeia, the Go
+// runtime and the process, along with the collectors.
+func NewRegistry(collectors ...prometheus.Collector) (*prometheus.Registry, error) {
+	reg := prometheus.NewRegistry()
+	collectors = append([]prometheus.Collector{
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		httpRequests,
+		httpRequestDuration,
+		UsersCreated,
+		Logins,
+		LoginLockouts,
+	}, collectors...)
+
+	for _, c := range collectors {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return reg, nil
+}
+
+// Handler returns a http.Handler that serves the metrics in the Registry, in the
+// Prometheus exposition format.
+func Handler(reg *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a HTTP request, that matched the route pattern (like
+// "/v1/users/{empID}"). An empty route means that no route was matched.
+func ObserveRequest(method, route string, status int, d time.Duration) {
+	if !methods[method] {
+		method = otherMethod
+	}
+	if route == "" {
+		route = unmatchedRoute
+	}
+	labels := prometheus.Labels{"method": method, "route": route, "status": strconv.Itoa(status)}
+	httpRequests.With(labels).Inc()
+	httpRequestDuration.With(labels).Observe(d.Seconds())
+}
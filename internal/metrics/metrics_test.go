@@ -0,0 +1,69 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package metrics
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"adeia/internal/cache/redis"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDB struct{}
+
+func (fakeDB) Stats() sql.DBStats {
+	return sql.DBStats{MaxOpenConnections: 10, OpenConnections: 3, InUse: 1, Idle: 2, WaitCount: 5}
+}
+
+type fakeCache struct{}
+
+func (fakeCache) Stats() redis.PoolStats {
+	return redis.PoolStats{Size: 10, Available: 9, ConnsCreated: 10, Commands: 42, CommandErrors: 1}
+}
+
+func TestObserveRequest(t *testing.T) {
+	ObserveRequest(http.MethodGet, "/v1/users/{empID}", http.StatusOK, 10*time.Millisecond)
+	ObserveRequest(http.MethodGet, "/v1/users/{empID}", http.StatusOK, 20*time.Millisecond)
+	ObserveRequest(http.MethodGet, "", http.StatusNotFound, time.Millisecond)
+	ObserveRequest("FOOBAR", "", http.StatusMethodNotAllowed, time.Millisecond)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, "/v1/users/{empID}", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, unmatchedRoute, "404")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues(otherMethod, unmatchedRoute, "405")))
+}
+
+func TestHandler(t *testing.T) {
+	reg, err := NewRegistry(NewDBCollector(fakeDB{}), NewCacheCollector(fakeCache{}))
+	assert.Nil(t, err)
+	UsersCreated.Inc()
+
+	rr := httptest.NewRecorder()
+	Handler(reg).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	body, _ := ioutil.ReadAll(rr.Body)
+	for _, want := range []string{
+		"adeia_db_open_connections 3",
+		"adeia_db_wait_count_total 5",
+		`adeia_db_closed_connections_total{reason="max_idle"} 0`,
+		"adeia_cache_available_connections 9",
+		"adeia_cache_commands_total 42",
+		"adeia_users_created_total",
+		"go_goroutines",
+	} {
+		assert.Contains(t, string(body), want)
+	}
+
+	// collectors cannot be registered twice
+	_, err = NewRegistry(NewDBCollector(fakeDB{}), NewDBCollector(fakeDB{}))
+	assert.Error(t, err)
+}
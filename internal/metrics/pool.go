@@ -0,0 +1,123 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package metrics
+
+import (
+	"database/sql"
+
+	"adeia/internal/cache/redis"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DBStatser is implemented by database connection pools, like pg.PostgresDB.
+type DBStatser interface {
+	Stats() sql.DBStats
+}
+
+// CacheStatser is implemented by cache connection pools, like redis.Redis.
+type CacheStatser interface {
+	Stats() redis.PoolStats
+}
+
+// dbCollector is a prometheus.Collector for the stats of a database connection
+// pool. The stats are read on every scrape.
+type dbCollector struct {
+	db DBStatser
+
+	maxOpen      *prometheus.Desc
+	open         *prometheus.Desc
+	inUse        *prometheus.Desc
+	idle         *prometheus.Desc
+	waitCount    *prometheus.Desc
+	waitDuration *prometheus.Desc
+	closed       *prometheus.Desc
+}
+
+// NewDBCollector creates a new prometheus.Collector for the connection pool stats
+// of the database.
+func NewDBCollector(db DBStatser) prometheus.Collector {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db", name), help, labels, nil)
+	}
+	return &dbCollector{
+		db:           db,
+		maxOpen:      desc("max_open_connections", "Maximum no. of open connections to the database."),
+		open:         desc("open_connections", "No. of established connections, both in use and idle."),
+		inUse:        desc("in_use_connections", "No. of connections currently in use."),
+		idle:         desc("idle_connections", "No. of idle connections."),
+		waitCount:    desc("wait_count_total", "No. of connections waited for."),
+		waitDuration: desc("wait_duration_seconds_total", "Total time blocked waiting for a new connection."),
+		closed:       desc("closed_connections_total", "No. of connections closed, by reason.", "reason"),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *dbCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{c.maxOpen, c.open, c.inUse, c.idle, c.waitCount, c.waitDuration, c.closed} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *dbCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.db.Stats()
+	ch <- prometheus.MustNewConstMetric(c.maxOpen, prometheus.GaugeValue, float64(s.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(s.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(s.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.Idle))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(s.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, s.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.closed, prometheus.CounterValue, float64(s.MaxIdleClosed), "max_idle")
+	ch <- prometheus.MustNewConstMetric(c.closed, prometheus.CounterValue, float64(s.MaxLifetimeClosed), "max_lifetime")
+}
+
+// cacheCollector is a prometheus.Collector for the stats of a cache connection
+// pool. The stats are read on every scrape.
+type cacheCollector struct {
+	cache CacheStatser
+
+	size          *prometheus.Desc
+	available     *prometheus.Desc
+	connsCreated  *prometheus.Desc
+	connsClosed   *prometheus.Desc
+	commands      *prometheus.Desc
+	commandErrors *prometheus.Desc
+}
+
+// NewCacheCollector creates a new prometheus.Collector for the connection pool
+// stats of the cache.
+func NewCacheCollector(cache CacheStatser) prometheus.Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", name), help, nil, nil)
+	}
+	return &cacheCollector{
+		cache:         cache,
+		size:          desc("pool_size", "Configured no. of connections in the pool."),
+		available:     desc("available_connections", "No. of connections available in the pool."),
+		connsCreated:  desc("connections_created_total", "No. of connections created."),
+		connsClosed:   desc("connections_closed_total", "No. of connections closed."),
+		commands:      desc("commands_total", "No. of commands (or pipelines) run."),
+		commandErrors: desc("command_errors_total", "No. of commands (or pipelines) that failed."),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{c.size, c.available, c.connsCreated, c.connsClosed, c.commands, c.commandErrors} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.cache.Stats()
+	ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(s.Size))
+	ch <- prometheus.MustNewConstMetric(c.available, prometheus.GaugeValue, float64(s.Available))
+	ch <- prometheus.MustNewConstMetric(c.connsCreated, prometheus.CounterValue, float64(s.ConnsCreated))
+	ch <- prometheus.MustNewConstMetric(c.connsClosed, prometheus.CounterValue, float64(s.ConnsClosed))
+	ch <- prometheus.MustNewConstMetric(c.commands, prometheus.CounterValue, float64(s.Commands))
+	ch <- prometheus.MustNewConstMetric(c.commandErrors, prometheus.CounterValue, float64(s.CommandErrors))
+}
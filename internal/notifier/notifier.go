@@ -0,0 +1,234 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+// Package notifier sends notifications to Users, honouring their notification
+// preferences.
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"adeia"
+	"adeia/internal/mailer"
+	"adeia/pkg/log"
+)
+
+const digestTemplate = "notification_digest"
+
+// Notification represents a notification to a User, that is rendered from a
+// template.
+type Notification struct {
+	// User is the recipient of the Notification. The Preferences of the User are
+	// honoured, if present, and the defaults are used otherwise.
+	User *adeia.User
+
+	// Template is the name of the email template, like "account_activation".
+	Template string
+
+	// Data is passed to the template when rendering it.
+	Data interface{}
+}
+
+// Notifier sends Notifications to Users. Notifications for Users that prefer an
+// email digest are stored as PendingNotifications, so that they survive restarts,
+// and are sent together in a digest email when FlushDigests is called.
+type Notifier struct {
+	log     log.Logger
+	mailer  mailer.Mailer
+	pending adeia.PendingNotificationRepo
+}
+
+// digest represents the pending Notifications of a User.
+type digest struct {
+	email, name, locale string
+	notifications       []*DigestItem
+}
+
+// DigestItem is a Notification in a digest email. The digest template renders the
+// content of each of them, using their own template.
+type DigestItem struct {
+	Template string
+	Data     interface{}
+}
+
+// New creates a new *Notifier.
+func New(log log.Logger, mailer mailer.Mailer, pending adeia.PendingNotificationRepo) *Notifier {
+	return &Notifier{
+		log:     log,
+		mailer:  mailer,
+		pending: pending,
+	}
+}
+
+// Send sends transactional Notifications, like account activation and password
+// reset emails, by email. They are sent immediately, regardless of the
+// notification preferences of the Users, since the Users cannot use their account
+// without them, but are translated to the locale of the User.
+func (n *Notifier) Send(notifications ...*Notification) error {
+	msgs := make([]*mailer.Message, len(notifications))
+	for i, nt := range notifications {
+		msgs[i] = message(nt.User, nt.Template, nt.Data)
+	}
+	return n.mailer.Send(msgs...)
+}
+
+// Notify sends Notifications through the channels that the Users prefer. Users that
+// have turned off email notifications are skipped, and the Notifications of Users
+// that prefer an email digest are added to their digest.
+func (n *Notifier) Notify(ctx context.Context, notifications ...*Notification) error {
+	var msgs []*mailer.Message
+	for _, nt := range notifications {
+		p := preferences(nt.User)
+		switch {
+		case !p.Channels.Has(adeia.NotificationChannelEmail):
+			continue
+		case p.EmailDigest:
+			if err := n.addToDigest(ctx, nt); err != nil {
+				return err
+			}
+		default:
+			msgs = append(msgs, message(nt.User, nt.Template, nt.Data))
+		}
+	}
+
+	if len(msgs) == 0 {
+		return nil
+	}
+	return n.mailer.Send(msgs...)
+}
+
+// FlushDigests sends a digest email, with the content of all the pending
+// Notifications, to each User that has them. The pending Notifications are
+// cleared, even if sending them fails.
+func (n *Notifier) FlushDigests(ctx context.Context) error {
+	pending, err := n.pending.DeleteAll(ctx)
+	if err != nil || len(pending) == 0 {
+		return err
+	}
+
+	var digests []*digest
+	byEmail := make(map[string]*digest)
+	for _, p := range pending {
+		d, ok := byEmail[p.Email]
+		if !ok {
+			d = &digest{email: p.Email, name: p.Name, locale: p.Locale}
+			byEmail[p.Email] = d
+			digests = append(digests, d)
+		}
+
+		data, err := decodeData(p.Data)
+		if err != nil {
+			n.log.Errorf("cannot decode pending notification %d: %v", p.ID, err)
+			continue
+		}
+		d.notifications = append(d.notifications, &DigestItem{Template: p.Template, Data: data})
+	}
+
+	msgs := make([]*mailer.Message, 0, len(digests))
+	for _, d := range digests {
+		msgs = append(msgs, &mailer.Message{
+			To:       d.email,
+			Template: digestTemplate,
+			Locale:   d.locale,
+			Data: map[string]interface{}{
+				"Name":          d.name,
+				"Count":         len(d.notifications),
+				"Notifications": d.notifications,
+			},
+		})
+	}
+	return n.mailer.Send(msgs...)
+}
+
+// Run flushes the digests every interval, until stop is closed.
+func (n *Notifier) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := n.FlushDigests(context.Background()); err != nil {
+				n.log.Errorf("cannot send digest emails: %v", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// addToDigest stores the Notification, until the next digest of the User is sent.
+func (n *Notifier) addToDigest(ctx context.Context, nt *Notification) error {
+	data, err := json.Marshal(nt.Data)
+	if err != nil {
+		return err
+	}
+
+	_, err = n.pending.Insert(ctx, &adeia.PendingNotification{
+		UserID:    nt.User.ID,
+		Email:     nt.User.Email,
+		Name:      nt.User.Name,
+		Locale:    preferences(nt.User).Locale,
+		Template:  nt.Template,
+		Data:      data,
+		CreatedAt: time.Now().UTC(),
+	})
+	return err
+}
+
+// decodeData decodes the JSON-encoded data of a template. Whole numbers are
+// decoded as int64, instead of float64, so that the templates can still format
+// them with %d.
+func decodeData(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return convertNumbers(v), nil
+}
+
+func convertNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = convertNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = convertNumbers(e)
+		}
+	}
+	return v
+}
+
+// message builds an email to the User, in the locale of the User.
+func message(u *adeia.User, template string, data interface{}) *mailer.Message {
+	return &mailer.Message{
+		To:       u.Email,
+		Template: template,
+		Locale:   preferences(u).Locale,
+		Data:     data,
+	}
+}
+
+// preferences returns the Preferences of the User, or the defaults if they are
+// not present.
+func preferences(u *adeia.User) *adeia.UserPreferences {
+	if u.Preferences == nil {
+		return adeia.NewUserPreferences()
+	}
+	return u.Preferences
+}
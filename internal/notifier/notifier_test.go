@@ -0,0 +1,156 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package notifier
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"adeia"
+	"adeia/internal/mailer"
+	logzap "adeia/pkg/log/zap"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// fakeMailer records the messages that are sent.
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []*mailer.Message
+}
+
+func (f *fakeMailer) Send(msgs ...*mailer.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, msgs...)
+	return nil
+}
+
+// memPendingRepo is an in-memory adeia.PendingNotificationRepo.
+type memPendingRepo struct {
+	pending []*adeia.PendingNotification
+}
+
+func (m *memPendingRepo) DeleteAll(context.Context) ([]*adeia.PendingNotification, error) {
+	pending := m.pending
+	m.pending = nil
+	return pending, nil
+}
+
+func (m *memPendingRepo) Insert(_ context.Context, n *adeia.PendingNotification) (int, error) {
+	m.pending = append(m.pending, n)
+	n.ID = len(m.pending)
+	return n.ID, nil
+}
+
+var testLogger = &logzap.Logger{SugaredLogger: zap.NewNop().Sugar()}
+
+func setup(t *testing.T) (*Notifier, *fakeMailer) {
+	t.Parallel()
+	m := &fakeMailer{}
+	return New(testLogger, m, &memPendingRepo{}), m
+}
+
+func newUser(email string, p *adeia.UserPreferences) *adeia.User {
+	return adeia.NewUser(
+		adeia.WithName("foo"),
+		adeia.WithEmail(email),
+		adeia.WithEmpID(email),
+		adeia.WithPreferences(p),
+	)
+}
+
+func TestNotifier_Send(t *testing.T) {
+	t.Run("send regardless of preferences", func(t *testing.T) {
+		n, m := setup(t)
+		u := newUser("foo@example.com", &adeia.UserPreferences{Channels: adeia.Channels{}, Locale: "ta"})
+
+		err := n.Send(&Notification{User: u, Template: "account_activation"})
+		assert.Nil(t, err)
+		assert.Equal(t, []*mailer.Message{
+			{To: "foo@example.com", Template: "account_activation", Locale: "ta"},
+		}, m.sent)
+	})
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("send by email", func(t *testing.T) {
+		n, m := setup(t)
+		u := newUser("foo@example.com", adeia.NewUserPreferences())
+
+		err := n.Notify(ctx, &Notification{User: u, Template: "foo"})
+		assert.Nil(t, err)
+		assert.Len(t, m.sent, 1)
+		assert.Equal(t, "en", m.sent[0].Locale)
+	})
+
+	t.Run("use defaults when preferences are missing", func(t *testing.T) {
+		n, m := setup(t)
+		u := newUser("foo@example.com", nil)
+
+		err := n.Notify(ctx, &Notification{User: u, Template: "foo"})
+		assert.Nil(t, err)
+		assert.Len(t, m.sent, 1)
+	})
+
+	t.Run("skip users without email channel", func(t *testing.T) {
+		n, m := setup(t)
+		u := newUser("foo@example.com", &adeia.UserPreferences{Channels: adeia.Channels{}})
+
+		err := n.Notify(ctx, &Notification{User: u, Template: "foo"})
+		assert.Nil(t, err)
+		assert.Empty(t, m.sent)
+	})
+
+	t.Run("add to digest", func(t *testing.T) {
+		n, m := setup(t)
+		p := adeia.NewUserPreferences()
+		p.EmailDigest = true
+		u := newUser("foo@example.com", p)
+
+		err := n.Notify(ctx,
+			&Notification{User: u, Template: "foo", Data: map[string]interface{}{"Duration": 10, "IP": "10.0.0.1"}},
+			&Notification{User: u, Template: "bar"},
+		)
+		assert.Nil(t, err)
+		assert.Empty(t, m.sent)
+
+		assert.Nil(t, n.FlushDigests(ctx))
+		assert.Equal(t, []*mailer.Message{{
+			To:       "foo@example.com",
+			Template: "notification_digest",
+			Locale:   "en",
+			Data: map[string]interface{}{
+				"Name":  "foo",
+				"Count": 2,
+				"Notifications": []*DigestItem{
+					{Template: "foo", Data: map[string]interface{}{"Duration": int64(10), "IP": "10.0.0.1"}},
+					{Template: "bar"},
+				},
+			},
+		}}, m.sent)
+
+		// digests are cleared once sent
+		assert.Nil(t, n.FlushDigests(ctx))
+		assert.Len(t, m.sent, 1)
+	})
+
+	t.Run("keep digests across restarts", func(t *testing.T) {
+		t.Parallel()
+		m, pending := &fakeMailer{}, &memPendingRepo{}
+		p := adeia.NewUserPreferences()
+		p.EmailDigest = true
+
+		err := New(testLogger, m, pending).Notify(ctx, &Notification{User: newUser("foo@example.com", p), Template: "foo"})
+		assert.Nil(t, err)
+
+		assert.Nil(t, New(testLogger, m, pending).FlushDigests(ctx))
+		assert.Len(t, m.sent, 1)
+	})
+}
@@ -0,0 +1,44 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package repo
+
+import (
+	"context"
+
+	"adeia"
+	"adeia/internal/store"
+)
+
+const (
+	queryAttachmentByID   = "SELECT * FROM attachments WHERE id=$1"
+	queryInsertAttachment = "INSERT INTO attachments (user_id, key, filename, content_type, size, checksum, created_at) " +
+		"VALUES (:user_id, :key, :filename, :content_type, :size, :checksum, :created_at) RETURNING id"
+)
+
+// AttachmentRepo represents the Attachment repository.
+type AttachmentRepo struct {
+	db store.DB
+}
+
+// NewAttachmentRepo creates a new *AttachmentRepo.
+func NewAttachmentRepo(d store.DB) *AttachmentRepo {
+	return &AttachmentRepo{d}
+}
+
+// GetByID returns the Attachment with the ID. nil is returned if it does not exist.
+func (ar *AttachmentRepo) GetByID(ctx context.Context, id int) (*adeia.Attachment, error) {
+	a := adeia.Attachment{}
+	if ok, err := ar.db.GetOne(ctx, &a, queryAttachmentByID, id); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+	return &a, nil
+}
+
+// Insert inserts a new Attachment and returns the lastInsertID.
+func (ar *AttachmentRepo) Insert(ctx context.Context, a *adeia.Attachment) (lastInsertID int, err error) {
+	return ar.db.InsertNamed(ctx, queryInsertAttachment, a)
+}
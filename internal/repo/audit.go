@@ -0,0 +1,90 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package repo
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"adeia"
+	"adeia/internal/store"
+	"adeia/pkg/query"
+)
+
+const (
+	queryAuditAll    = "SELECT * FROM audit_events"
+	queryAuditAfter  = "SELECT * FROM audit_events WHERE id > $1 ORDER BY id LIMIT $2"
+	queryAuditLast   = "SELECT * FROM audit_events ORDER BY id DESC LIMIT 1"
+	queryAuditInsert = "INSERT INTO audit_events " +
+		"(prev_hash, hash, actor, action, target_type, target_id, before, after, ip, request_id, created_at) " +
+		"VALUES (:prev_hash, :hash, :actor, :action, :target_type, :target_id, :before, :after, :ip, :request_id, :created_at) " +
+		"RETURNING id"
+)
+
+// AuditRepo represents the AuditEvent repository.
+type AuditRepo struct {
+	db store.DB
+}
+
+// NewAuditRepo creates a new *AuditRepo.
+func NewAuditRepo(d store.DB) *AuditRepo {
+	return &AuditRepo{d}
+}
+
+// GetAll returns a page of AuditEvents, as specified by spec. Only AuditEvents
+// created in [since, until) are returned, if they are not nil.
+func (ar *AuditRepo) GetAll(ctx context.Context, spec *query.Spec, since, until *time.Time) (events []*adeia.AuditEvent, nextCursor string, err error) {
+	var conds []string
+	var args []interface{}
+	if since != nil {
+		args = append(args, since.UTC())
+		conds = append(conds, "created_at >= $"+strconv.Itoa(len(args)))
+	}
+	if until != nil {
+		args = append(args, until.UTC())
+		conds = append(conds, "created_at < $"+strconv.Itoa(len(args)))
+	}
+
+	q, qArgs := spec.Build(queryAuditAll, conds, args...)
+	if events, err = ar.getMany(ctx, q, qArgs...); err != nil {
+		return nil, "", err
+	}
+
+	if nextCursor, err = spec.NextCursor(&events); err != nil {
+		return nil, "", err
+	}
+	return events, nextCursor, nil
+}
+
+// GetAfter returns upto limit AuditEvents with IDs greater than id, in the order
+// of the chain.
+func (ar *AuditRepo) GetAfter(ctx context.Context, id, limit int) ([]*adeia.AuditEvent, error) {
+	return ar.getMany(ctx, queryAuditAfter, id, limit)
+}
+
+// GetLast returns the last AuditEvent of the chain. nil is returned if there are none.
+func (ar *AuditRepo) GetLast(ctx context.Context) (*adeia.AuditEvent, error) {
+	e := adeia.AuditEvent{}
+	if ok, err := ar.db.GetOne(ctx, &e, queryAuditLast); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+	return &e, nil
+}
+
+// Insert inserts a new AuditEvent and returns the lastInsertID.
+func (ar *AuditRepo) Insert(ctx context.Context, e *adeia.AuditEvent) (lastInsertID int, err error) {
+	return ar.db.InsertNamed(ctx, queryAuditInsert, e)
+}
+
+func (ar *AuditRepo) getMany(ctx context.Context, query string, args ...interface{}) ([]*adeia.AuditEvent, error) {
+	var e []*adeia.AuditEvent
+	if err := ar.db.GetMany(ctx, &e, query, args...); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
@@ -0,0 +1,74 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package repo
+
+import (
+	"context"
+	"reflect"
+)
+
+// fakeDB is a store.DB that records the queries run on it. GetOne and GetMany copy
+// result into dest; a nil result means that no rows were found.
+type fakeDB struct {
+	result       interface{}
+	rowsAffected int64
+	err          error
+
+	queries []string
+	args    [][]interface{}
+}
+
+func (f *fakeDB) record(query string, args ...interface{}) {
+	f.queries = append(f.queries, query)
+	f.args = append(f.args, args)
+}
+
+func (f *fakeDB) Close() error { return nil }
+
+func (f *fakeDB) Delete(_ context.Context, query string, args ...interface{}) (int64, error) {
+	f.record(query, args...)
+	return f.rowsAffected, f.err
+}
+
+func (f *fakeDB) GetMany(_ context.Context, dest interface{}, query string, args ...interface{}) error {
+	f.record(query, args...)
+	if f.result != nil {
+		reflect.ValueOf(dest).Elem().Set(reflect.ValueOf(f.result))
+	}
+	return f.err
+}
+
+func (f *fakeDB) GetOne(_ context.Context, dest interface{}, query string, args ...interface{}) (bool, error) {
+	f.record(query, args...)
+	if f.err != nil || f.result == nil {
+		return false, f.err
+	}
+	reflect.ValueOf(dest).Elem().Set(reflect.ValueOf(f.result).Elem())
+	return true, nil
+}
+
+func (f *fakeDB) Insert(_ context.Context, query string, args ...interface{}) (int, error) {
+	f.record(query, args...)
+	return 1, f.err
+}
+
+func (f *fakeDB) InsertNamed(_ context.Context, query string, arg interface{}) (int, error) {
+	f.record(query, arg)
+	return 1, f.err
+}
+
+func (f *fakeDB) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (f *fakeDB) Update(_ context.Context, query string, args ...interface{}) (int64, error) {
+	f.record(query, args...)
+	return f.rowsAffected, f.err
+}
+
+func (f *fakeDB) UpdateNamed(_ context.Context, query string, arg interface{}) (int64, error) {
+	f.record(query, arg)
+	return f.rowsAffected, f.err
+}
@@ -0,0 +1,48 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package repo
+
+import (
+	"context"
+	"sort"
+
+	"adeia"
+	"adeia/internal/store"
+)
+
+const (
+	// the rows are deleted and returned in one statement, so that each of them is
+	// only sent by one of the replicas
+	queryDeletePendingNotifications = "DELETE FROM pending_notifications RETURNING *"
+	queryInsertPendingNotification  = "INSERT INTO pending_notifications " +
+		"(user_id, email, name, locale, template, data, created_at) " +
+		"VALUES (:user_id, :email, :name, :locale, :template, :data, :created_at) RETURNING id"
+)
+
+// PendingNotificationRepo represents the PendingNotification repository.
+type PendingNotificationRepo struct {
+	db store.DB
+}
+
+// NewPendingNotificationRepo creates a new *PendingNotificationRepo.
+func NewPendingNotificationRepo(d store.DB) *PendingNotificationRepo {
+	return &PendingNotificationRepo{d}
+}
+
+// DeleteAll deletes all the PendingNotifications, and returns them in the order
+// that they were created.
+func (pr *PendingNotificationRepo) DeleteAll(ctx context.Context) ([]*adeia.PendingNotification, error) {
+	var pending []*adeia.PendingNotification
+	if err := pr.db.GetMany(ctx, &pending, queryDeletePendingNotifications); err != nil {
+		return nil, err
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].ID < pending[j].ID })
+	return pending, nil
+}
+
+// Insert inserts a new PendingNotification and returns the lastInsertID.
+func (pr *PendingNotificationRepo) Insert(ctx context.Context, n *adeia.PendingNotification) (lastInsertID int, err error) {
+	return pr.db.InsertNamed(ctx, queryInsertPendingNotification, n)
+}
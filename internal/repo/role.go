@@ -0,0 +1,59 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package repo
+
+import (
+	"context"
+
+	"adeia"
+	"adeia/internal/store"
+)
+
+const (
+	queryRoleByID         = "SELECT * FROM roles WHERE id=$1"
+	queryRolePermissions  = "SELECT permission FROM role_permissions WHERE role_id=$1"
+	queryUpdateRequire2FA = "UPDATE roles SET require_2fa=:require_2fa WHERE id=:id"
+)
+
+// RoleRepo represents the Role repository.
+type RoleRepo struct {
+	db store.DB
+}
+
+// NewRoleRepo creates a new *RoleRepo.
+func NewRoleRepo(d store.DB) *RoleRepo {
+	return &RoleRepo{d}
+}
+
+// GetByID returns the Role with the ID. nil is returned if it does not exist.
+func (rr *RoleRepo) GetByID(ctx context.Context, id int) (*adeia.Role, error) {
+	r := adeia.Role{}
+	if ok, err := rr.db.GetOne(ctx, &r, queryRoleByID, id); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+	return &r, nil
+}
+
+// GetPermissions returns the names of the permissions granted to the Role with the
+// ID, like "CREATE_USERS".
+func (rr *RoleRepo) GetPermissions(ctx context.Context, id int) ([]string, error) {
+	var permissions []string
+	if err := rr.db.GetMany(ctx, &permissions, queryRolePermissions, id); err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// UpdateRequire2FA updates whether Users with the Role must use two-factor
+// authentication.
+func (rr *RoleRepo) UpdateRequire2FA(ctx context.Context, r *adeia.Role, require2FA bool) error {
+	r.Require2FA = require2FA
+	if _, err := rr.db.UpdateNamed(ctx, queryUpdateRequire2FA, r); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"adeia"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleRepo_UpdateRequire2FA(t *testing.T) {
+	t.Run("update require_2fa", func(t *testing.T) {
+		db := &fakeDB{rowsAffected: 1}
+		r := &adeia.Role{ID: 1, Name: "admin"}
+		err := NewRoleRepo(db).UpdateRequire2FA(context.Background(), r, true)
+
+		assert.Nil(t, err)
+		assert.True(t, r.Require2FA)
+		assert.Equal(t, queryUpdateRequire2FA, db.queries[0])
+	})
+
+	t.Run("return the error of the database", func(t *testing.T) {
+		err := NewRoleRepo(&fakeDB{err: errors.New("db error")}).UpdateRequire2FA(context.Background(), &adeia.Role{}, true)
+		assert.EqualError(t, err, "db error")
+	})
+}
+
+func TestSessionRepo_DeleteAllWithoutMFAByRoleID(t *testing.T) {
+	db := &fakeDB{rowsAffected: 2}
+	n, err := NewSessionRepo(db).DeleteAllWithoutMFAByRoleID(context.Background(), 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.Equal(t, queryDeleteSessionsWithoutMFAByRole, db.queries[0])
+	assert.Equal(t, []interface{}{1}, db.args[0])
+}
@@ -0,0 +1,106 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package repo
+
+import (
+	"context"
+	"time"
+
+	"adeia"
+	"adeia/internal/store"
+)
+
+const (
+	querySessionByID           = "SELECT * FROM sessions WHERE id=$1 AND refresh_token_expires > $2"
+	querySessionByRefreshToken = "SELECT * FROM sessions WHERE refresh_token=$1 AND refresh_token_expires > $2"
+	querySessionsByUserID      = "SELECT * FROM sessions WHERE user_id=$1 AND refresh_token_expires > $2 ORDER BY id"
+	queryDeleteSession         = "DELETE FROM sessions WHERE id=$1 AND user_id=$2"
+	queryDeleteSessionsByUser  = "DELETE FROM sessions WHERE user_id=$1 AND id<>$2"
+	// users that have not confirmed their enrolment have no confirmed_at, just like
+	// those that have not begun it
+	queryDeleteSessionsWithoutMFAByRole = "DELETE FROM sessions WHERE user_id IN (" +
+		"SELECT u.id FROM users u LEFT JOIN user_totp t ON t.user_id=u.id " +
+		"WHERE u.role_id=$1 AND t.confirmed_at IS NULL)"
+	queryInsertSession = "INSERT INTO sessions (user_id, refresh_token, refresh_token_expires, created_at, ip, user_agent) " +
+		"VALUES (:user_id, :refresh_token, :refresh_token_expires, :created_at, :ip, :user_agent) RETURNING id"
+	// the refresh token is only replaced if it has not been rotated since it was
+	// read, so that concurrent requests cannot use the same token twice
+	queryUpdateRefreshToken = "UPDATE sessions SET refresh_token=$1, refresh_token_expires=$2 " +
+		"WHERE id=$3 AND refresh_token=$4"
+)
+
+// SessionRepo represents the Session repository.
+type SessionRepo struct {
+	db store.DB
+}
+
+// NewSessionRepo creates a new *SessionRepo.
+func NewSessionRepo(d store.DB) *SessionRepo {
+	return &SessionRepo{d}
+}
+
+// Insert inserts a new Session and returns the lastInsertID.
+func (sr *SessionRepo) Insert(ctx context.Context, s *adeia.Session) (lastInsertID int, err error) {
+	return sr.db.InsertNamed(ctx, queryInsertSession, s)
+}
+
+// DeleteAllByUserID deletes all the Sessions of the User, except the one with the
+// ID exceptID. Pass 0 to delete all of them.
+func (sr *SessionRepo) DeleteAllByUserID(ctx context.Context, userID int, exceptID int) (rowsAffected int64, err error) {
+	return sr.db.Delete(ctx, queryDeleteSessionsByUser, userID, exceptID)
+}
+
+// DeleteAllWithoutMFAByRoleID deletes all the Sessions of the Users with the Role,
+// that have not enabled two-factor authentication.
+func (sr *SessionRepo) DeleteAllWithoutMFAByRoleID(ctx context.Context, roleID int) (rowsAffected int64, err error) {
+	return sr.db.Delete(ctx, queryDeleteSessionsWithoutMFAByRole, roleID)
+}
+
+// DeleteByIDAndUserID deletes the Session with the ID, only if it belongs to the User.
+func (sr *SessionRepo) DeleteByIDAndUserID(ctx context.Context, id, userID int) (rowsAffected int64, err error) {
+	return sr.db.Delete(ctx, queryDeleteSession, id, userID)
+}
+
+// GetAllByUserID returns all the Sessions of the User, that have not expired.
+func (sr *SessionRepo) GetAllByUserID(ctx context.Context, userID int) ([]*adeia.Session, error) {
+	var sessions []*adeia.Session
+	if err := sr.db.GetMany(ctx, &sessions, querySessionsByUserID, userID, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// GetByID returns the Session with the ID, if it has not expired.
+func (sr *SessionRepo) GetByID(ctx context.Context, id int) (*adeia.Session, error) {
+	return sr.get(ctx, querySessionByID, id, time.Now().UTC())
+}
+
+// GetByRefreshToken returns the Session using the hash of its refresh token, if it
+// has not expired.
+func (sr *SessionRepo) GetByRefreshToken(ctx context.Context, refreshToken []byte) (*adeia.Session, error) {
+	return sr.get(ctx, querySessionByRefreshToken, refreshToken, time.Now().UTC())
+}
+
+// UpdateRefreshToken replaces the refresh token of the Session, along with its expiry,
+// only if the refresh token has not been replaced since the Session was read.
+// rowsAffected is 0 if it has.
+func (sr *SessionRepo) UpdateRefreshToken(ctx context.Context, s *adeia.Session, refreshToken []byte, expires time.Time) (rowsAffected int64, err error) {
+	rowsAffected, err = sr.db.Update(ctx, queryUpdateRefreshToken, refreshToken, expires, s.ID, s.RefreshToken)
+	if err == nil && rowsAffected > 0 {
+		s.RefreshToken = refreshToken
+		s.RefreshTokenExpires = expires
+	}
+	return rowsAffected, err
+}
+
+func (sr *SessionRepo) get(ctx context.Context, query string, args ...interface{}) (*adeia.Session, error) {
+	s := adeia.Session{}
+	if ok, err := sr.db.GetOne(ctx, &s, query, args...); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+	return &s, nil
+}
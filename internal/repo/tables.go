@@ -0,0 +1,31 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package repo
+
+// Schema maps the tables that the repositories need to their columns, which are
+// created by the SQL files in resources. It must be updated when a table or a
+// column is added there, so that the readiness check catches migrations that have
+// not been run.
+var Schema = map[string][]string{
+	"attachments": {"id", "user_id", "key", "filename", "content_type", "size", "checksum", "created_at"},
+	"audit_events": {
+		"id", "prev_hash", "hash", "actor", "action", "target_type", "target_id",
+		"before", "after", "ip", "request_id", "created_at",
+	},
+	"holidays": {"id", "name", "type", "date"},
+	"pending_notifications": {
+		"id", "user_id", "email", "name", "locale", "template", "data", "created_at",
+	},
+	"recovery_codes":   {"id", "user_id", "code_hash", "used_at"},
+	"role_permissions": {"role_id", "permission"},
+	"roles":            {"id", "name", "require_2fa"},
+	"sessions":         {"id", "user_id", "refresh_token", "refresh_token_expires", "created_at", "ip", "user_agent"},
+	"user_preferences": {"user_id", "notification_channels", "email_digest", "locale", "time_zone"},
+	"user_totp":        {"user_id", "secret", "confirmed_at", "last_used_step"},
+	"users": {
+		"id", "employee_id", "name", "email", "password", "designation", "department",
+		"is_activated", "role_id", "deactivated_at", "deleted_at",
+	},
+}
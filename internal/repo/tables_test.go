@@ -0,0 +1,58 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package repo
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var createTable = regexp.MustCompile(`(?s)CREATE TABLE (\w+)\s*\((.*?)\n\);`)
+
+// schemaFromResources returns the tables, and their columns, created by the SQL
+// files in resources.
+func schemaFromResources(t *testing.T) map[string][]string {
+	files, err := filepath.Glob("../../resources/*.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("cannot find the SQL files: %v", err)
+	}
+
+	schema := make(map[string][]string)
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			t.Fatalf("cannot read %s: %v", f, err)
+		}
+
+		for _, m := range createTable.FindAllStringSubmatch(string(b), -1) {
+			var columns []string
+			for _, line := range strings.Split(m[2], "\n") {
+				fields := strings.Fields(line)
+				if len(fields) == 0 || fields[0] == "(" || fields[0] == "PRIMARY" {
+					continue
+				}
+				columns = append(columns, fields[0])
+			}
+			schema[m[1]] = columns
+		}
+	}
+	return schema
+}
+
+func TestSchema(t *testing.T) {
+	t.Run("match the tables and columns in resources", func(t *testing.T) {
+		want := schemaFromResources(t)
+
+		assert.Equal(t, len(want), len(Schema))
+		for table, columns := range want {
+			assert.ElementsMatch(t, columns, Schema[table], "columns of %s", table)
+		}
+	})
+}
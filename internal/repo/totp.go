@@ -0,0 +1,112 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package repo
+
+import (
+	"context"
+	"time"
+
+	"adeia"
+	"adeia/internal/store"
+)
+
+const (
+	queryTOTPByUserID = "SELECT * FROM user_totp WHERE user_id=$1"
+	queryUpsertTOTP   = "INSERT INTO user_totp (user_id, secret, confirmed_at, last_used_step) " +
+		"VALUES (:user_id, :secret, :confirmed_at, :last_used_step) " +
+		"ON CONFLICT (user_id) DO UPDATE SET secret=EXCLUDED.secret, " +
+		"confirmed_at=EXCLUDED.confirmed_at, last_used_step=EXCLUDED.last_used_step"
+	queryConfirmTOTP = "UPDATE user_totp SET confirmed_at=:confirmed_at, last_used_step=:last_used_step " +
+		"WHERE user_id=:user_id"
+	// the step is only updated if it is newer, so that concurrent requests cannot
+	// use the same code twice
+	queryUpdateLastUsedStep  = "UPDATE user_totp SET last_used_step=$1 WHERE user_id=$2 AND last_used_step < $1"
+	queryDeleteTOTP          = "DELETE FROM user_totp WHERE user_id=$1"
+	queryDeleteRecoveryCodes = "DELETE FROM recovery_codes WHERE user_id=$1"
+	queryInsertRecoveryCode  = "INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2) RETURNING id"
+	queryUseRecoveryCode     = "UPDATE recovery_codes SET used_at=$1 WHERE user_id=$2 AND code_hash=$3 AND used_at IS NULL"
+)
+
+// TOTPRepo represents the UserTOTP repository, along with the recovery codes.
+type TOTPRepo struct {
+	db store.DB
+}
+
+// NewTOTPRepo creates a new *TOTPRepo.
+func NewTOTPRepo(d store.DB) *TOTPRepo {
+	return &TOTPRepo{d}
+}
+
+// GetByUserID returns the UserTOTP of the User. nil is returned if the User has not
+// enrolled.
+func (tr *TOTPRepo) GetByUserID(ctx context.Context, userID int) (*adeia.UserTOTP, error) {
+	t := adeia.UserTOTP{}
+	if ok, err := tr.db.GetOne(ctx, &t, queryTOTPByUserID, userID); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+	return &t, nil
+}
+
+// Upsert inserts the UserTOTP, or replaces it if the User has already enrolled.
+func (tr *TOTPRepo) Upsert(ctx context.Context, t *adeia.UserTOTP) error {
+	if _, err := tr.db.UpdateNamed(ctx, queryUpsertTOTP, t); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Confirm marks the UserTOTP as confirmed, with step as the last used step.
+func (tr *TOTPRepo) Confirm(ctx context.Context, t *adeia.UserTOTP, step int64) error {
+	now := time.Now().UTC()
+	t.ConfirmedAt = &now
+	t.LastUsedStep = step
+	if _, err := tr.db.UpdateNamed(ctx, queryConfirmTOTP, t); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateLastUsedStep sets the last used step of the UserTOTP, only if step is newer
+// than it. rowsAffected is 0 if it is not.
+func (tr *TOTPRepo) UpdateLastUsedStep(ctx context.Context, t *adeia.UserTOTP, step int64) (rowsAffected int64, err error) {
+	rowsAffected, err = tr.db.Update(ctx, queryUpdateLastUsedStep, step, t.UserID)
+	if err == nil && rowsAffected > 0 {
+		t.LastUsedStep = step
+	}
+	return rowsAffected, err
+}
+
+// DeleteByUserID deletes the UserTOTP and the recovery codes of the User.
+func (tr *TOTPRepo) DeleteByUserID(ctx context.Context, userID int) error {
+	if _, err := tr.db.Delete(ctx, queryDeleteRecoveryCodes, userID); err != nil {
+		return err
+	}
+	if _, err := tr.db.Delete(ctx, queryDeleteTOTP, userID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes deletes all the recovery codes of the User, and inserts the
+// new ones. Only the hashes of the codes are stored.
+func (tr *TOTPRepo) ReplaceRecoveryCodes(ctx context.Context, userID int, hashes [][]byte) error {
+	if _, err := tr.db.Delete(ctx, queryDeleteRecoveryCodes, userID); err != nil {
+		return err
+	}
+	for _, h := range hashes {
+		if _, err := tr.db.Insert(ctx, queryInsertRecoveryCode, userID, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode marks the unused recovery code with the hash as used.
+// rowsAffected is 0 if no such code exists.
+func (tr *TOTPRepo) UseRecoveryCode(ctx context.Context, userID int, hash []byte) (rowsAffected int64, err error) {
+	return tr.db.Update(ctx, queryUseRecoveryCode, time.Now().UTC(), userID, hash)
+}
@@ -6,19 +6,28 @@ package repo
 
 import (
 	"context"
+	"time"
 
 	"adeia"
 	"adeia/internal/store"
+	"adeia/pkg/query"
 )
 
 const (
-	queryByEmail = "SELECT * FROM users WHERE email=$1"
-	//queryByEmpID            = "SELECT * FROM users WHERE employee_id=$1"
-	//queryByID               = "SELECT * FROM users WHERE id=$1"
-	queryInsert = "INSERT INTO users (employee_id, name, email, password, designation, is_activated) VALUES " +
-		"(:employee_id, :name, :email, :password, :designation, :is_activated) RETURNING id"
-	//queryUpdatePwdAndIsActivated = "UPDATE users SET password=:password, is_activated=:is_activated " +
-	//	"WHERE id=:id"
+	queryAll            = "SELECT * FROM users"
+	condNotDeleted      = "deleted_at IS NULL"
+	queryByEmail        = "SELECT * FROM users WHERE email=$1 AND deleted_at IS NULL"
+	queryByEmpID        = "SELECT * FROM users WHERE employee_id=$1 AND deleted_at IS NULL"
+	queryDeletedByEmpID = "SELECT * FROM users WHERE employee_id=$1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC LIMIT 1"
+	queryByID           = "SELECT * FROM users WHERE id=$1 AND deleted_at IS NULL"
+	queryDeleteByEmpID  = "UPDATE users SET deleted_at=$1 WHERE employee_id=$2 AND deleted_at IS NULL"
+	queryDeactivate     = "UPDATE users SET is_activated=false, deactivated_at=:deactivated_at WHERE id=:id"
+	queryInsert         = "INSERT INTO users (employee_id, name, email, password, designation, department, is_activated) " +
+		"VALUES (:employee_id, :name, :email, :password, :designation, :department, :is_activated) RETURNING id"
+	queryUpdatePwdAndIsActivated = "UPDATE users SET password=:password, is_activated=:is_activated " +
+		"WHERE id=:id"
+	queryUpdateProfile = "UPDATE users SET name=:name, designation=:designation, department=:department WHERE id=:id"
+	queryRestore       = "UPDATE users SET deleted_at=NULL WHERE id=:id AND deleted_at IS NOT NULL"
 )
 
 // UserRepo represents the User repository.
@@ -31,45 +40,89 @@ func NewUserRepo(d store.DB) *UserRepo {
 	return &UserRepo{d}
 }
 
-//
-//func (ur *UserRepo) DeleteByEmpID(empID string) (rowsAffected int64, err error) {
-//	return ur.db.Delete(queryDeleteByEmpID, time.Now().UTC(), empID)
-//}
-//
-//func (ur *UserRepo) GetByEmail(email string) (*adeia.User, error) {
-//	return ur.get(queryByEmail, email)
-//}
-//
-//func (ur *UserRepo) GetByEmailInclDeleted(email string) (*adeia.User, error) {
-//	return ur.get(queryByEmailInclDeleted, email)
-//}
-//
-//func (ur *UserRepo) GetByEmpID(empID string) (*adeia.User, error) {
-//	return ur.get(queryByEmpID, empID)
-//}
-//
-//func (ur *UserRepo) GetByID(id int) (*adeia.User, error) {
-//	return ur.get(queryByID, id)
-//}
+// GetByID returns a User using the provided ID.
+func (ur *UserRepo) GetByID(ctx context.Context, id int) (*adeia.User, error) {
+	return ur.get(ctx, queryByID, id)
+}
 
 // Insert inserts a new User and returns the lastInsertID.
 func (ur *UserRepo) Insert(ctx context.Context, u *adeia.User) (lastInsertID int, err error) {
 	return ur.db.InsertNamed(ctx, queryInsert, u)
 }
 
+// Deactivate deactivates the User and records the time of deactivation.
+func (ur *UserRepo) Deactivate(ctx context.Context, u *adeia.User) error {
+	now := time.Now().UTC()
+	u.IsActivated = false
+	u.DeactivatedAt = &now
+	if _, err := ur.db.UpdateNamed(ctx, queryDeactivate, u); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteByEmpID soft-deletes a User using the provided employee ID. The User is
+// only marked as deleted, so that the history of the User is preserved.
+func (ur *UserRepo) DeleteByEmpID(ctx context.Context, empID string) (rowsAffected int64, err error) {
+	return ur.db.Delete(ctx, queryDeleteByEmpID, time.Now().UTC(), empID)
+}
+
+// GetAll returns a page of Users, except the deleted ones, as specified by spec.
+func (ur *UserRepo) GetAll(ctx context.Context, spec *query.Spec) (users []*adeia.User, nextCursor string, err error) {
+	return ur.getPage(ctx, spec, []string{condNotDeleted})
+}
+
+// GetAllInclDeleted returns a page of Users, including the deleted ones, as specified
+// by spec.
+func (ur *UserRepo) GetAllInclDeleted(ctx context.Context, spec *query.Spec) (users []*adeia.User, nextCursor string, err error) {
+	return ur.getPage(ctx, spec, nil)
+}
+
 // GetByEmail returns a User using the provided email address.
 func (ur *UserRepo) GetByEmail(ctx context.Context, email string) (*adeia.User, error) {
 	return ur.get(ctx, queryByEmail, email)
 }
 
-//func (ur *UserRepo) UpdatePasswordAndIsActivated(u *adeia.User, password string, isActivated bool) error {
-//	u.Password = password
-//	u.IsActivated = isActivated
-//	if _, err := ur.db.UpdateNamed(queryUpdatePwdAndIsActivated, u); err != nil {
-//		return err
-//	}
-//	return nil
-//}
+// GetByEmpID returns a User using the provided employee ID.
+func (ur *UserRepo) GetByEmpID(ctx context.Context, empID string) (*adeia.User, error) {
+	return ur.get(ctx, queryByEmpID, empID)
+}
+
+// GetDeletedByEmpID returns the deleted User with the provided employee ID. When the
+// employee ID was deleted more than once, the most-recently deleted User is returned.
+func (ur *UserRepo) GetDeletedByEmpID(ctx context.Context, empID string) (*adeia.User, error) {
+	return ur.get(ctx, queryDeletedByEmpID, empID)
+}
+
+// Restore restores a soft-deleted User.
+func (ur *UserRepo) Restore(ctx context.Context, u *adeia.User) error {
+	if _, err := ur.db.UpdateNamed(ctx, queryRestore, u); err != nil {
+		return err
+	}
+	u.DeletedAt = nil
+	return nil
+}
+
+// UpdatePasswordAndIsActivated updates the password and activation status of the User.
+func (ur *UserRepo) UpdatePasswordAndIsActivated(ctx context.Context, u *adeia.User, password string, isActivated bool) error {
+	u.Password = password
+	u.IsActivated = isActivated
+	if _, err := ur.db.UpdateNamed(ctx, queryUpdatePwdAndIsActivated, u); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateProfile updates the profile fields (name, designation and department) of the User.
+func (ur *UserRepo) UpdateProfile(ctx context.Context, u *adeia.User, name, designation, department string) error {
+	u.Name = name
+	u.Designation = designation
+	u.Department = department
+	if _, err := ur.db.UpdateNamed(ctx, queryUpdateProfile, u); err != nil {
+		return err
+	}
+	return nil
+}
 
 func (ur *UserRepo) get(ctx context.Context, query string, args ...interface{}) (*adeia.User, error) {
 	u := adeia.User{}
@@ -90,3 +143,15 @@ func (ur *UserRepo) getMany(ctx context.Context, query string, args ...interface
 	}
 	return u, nil
 }
+
+func (ur *UserRepo) getPage(ctx context.Context, spec *query.Spec, conds []string) (users []*adeia.User, nextCursor string, err error) {
+	q, qArgs := spec.Build(queryAll, conds)
+	if users, err = ur.getMany(ctx, q, qArgs...); err != nil {
+		return nil, "", err
+	}
+
+	if nextCursor, err = spec.NextCursor(&users); err != nil {
+		return nil, "", err
+	}
+	return users, nextCursor, nil
+}
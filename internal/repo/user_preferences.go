@@ -0,0 +1,50 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package repo
+
+import (
+	"context"
+
+	"adeia"
+	"adeia/internal/store"
+)
+
+const (
+	queryPreferencesByUserID = "SELECT * FROM user_preferences WHERE user_id=$1"
+	queryUpsertPreferences   = "INSERT INTO user_preferences (user_id, notification_channels, email_digest, locale, time_zone) " +
+		"VALUES (:user_id, :notification_channels, :email_digest, :locale, :time_zone) " +
+		"ON CONFLICT (user_id) DO UPDATE SET notification_channels=EXCLUDED.notification_channels, " +
+		"email_digest=EXCLUDED.email_digest, locale=EXCLUDED.locale, time_zone=EXCLUDED.time_zone"
+)
+
+// UserPreferencesRepo represents the UserPreferences repository.
+type UserPreferencesRepo struct {
+	db store.DB
+}
+
+// NewUserPreferencesRepo creates a new *UserPreferencesRepo.
+func NewUserPreferencesRepo(d store.DB) *UserPreferencesRepo {
+	return &UserPreferencesRepo{d}
+}
+
+// GetByUserID returns the UserPreferences of the User with the provided ID. nil is
+// returned if the User has not saved any preferences.
+func (pr *UserPreferencesRepo) GetByUserID(ctx context.Context, userID int) (*adeia.UserPreferences, error) {
+	p := adeia.UserPreferences{}
+	if ok, err := pr.db.GetOne(ctx, &p, queryPreferencesByUserID, userID); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+// Upsert inserts the UserPreferences, or updates them if they already exist.
+func (pr *UserPreferencesRepo) Upsert(ctx context.Context, p *adeia.UserPreferences) error {
+	if _, err := pr.db.UpdateNamed(ctx, queryUpsertPreferences, p); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"adeia"
+	"adeia/pkg/query"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserRepo_GetAll(t *testing.T) {
+	spec := &query.Spec{Limit: 2, Sort: query.Sort{Field: "id", Column: "id"}}
+
+	t.Run("return a page of active users with the next cursor", func(t *testing.T) {
+		db := &fakeDB{result: []*adeia.User{{ID: 1}, {ID: 2}, {ID: 3}}}
+		users, next, err := NewUserRepo(db).GetAll(context.Background(), spec)
+
+		assert.Nil(t, err)
+		assert.Len(t, users, 2)
+		assert.NotEmpty(t, next)
+		assert.Contains(t, db.queries[0], "deleted_at IS NULL")
+		assert.Contains(t, db.queries[0], "LIMIT $1")
+		assert.Equal(t, []interface{}{3}, db.args[0])
+	})
+
+	t.Run("return nil users when there are no rows", func(t *testing.T) {
+		db := &fakeDB{}
+		users, next, err := NewUserRepo(db).GetAll(context.Background(), spec)
+
+		assert.Nil(t, err)
+		assert.Nil(t, users)
+		assert.Empty(t, next)
+	})
+
+	t.Run("return the error of the database", func(t *testing.T) {
+		db := &fakeDB{err: errors.New("db error")}
+		_, _, err := NewUserRepo(db).GetAll(context.Background(), spec)
+
+		assert.EqualError(t, err, "db error")
+	})
+}
+
+func TestUserRepo_GetByEmpID(t *testing.T) {
+	t.Run("return the active user", func(t *testing.T) {
+		db := &fakeDB{result: &adeia.User{ID: 1, EmployeeID: "FOO123"}}
+		u, err := NewUserRepo(db).GetByEmpID(context.Background(), "FOO123")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "FOO123", u.EmployeeID)
+		assert.Equal(t, queryByEmpID, db.queries[0])
+		assert.Equal(t, []interface{}{"FOO123"}, db.args[0])
+	})
+
+	t.Run("return nil when the user does not exist", func(t *testing.T) {
+		u, err := NewUserRepo(&fakeDB{}).GetByEmpID(context.Background(), "FOO123")
+
+		assert.Nil(t, err)
+		assert.Nil(t, u)
+	})
+
+	t.Run("return the error of the database", func(t *testing.T) {
+		u, err := NewUserRepo(&fakeDB{err: errors.New("db error")}).GetByEmpID(context.Background(), "FOO123")
+
+		assert.EqualError(t, err, "db error")
+		assert.Nil(t, u)
+	})
+}
+
+func TestUserRepo_Deactivate(t *testing.T) {
+	t.Run("deactivate the user", func(t *testing.T) {
+		db := &fakeDB{rowsAffected: 1}
+		u := &adeia.User{ID: 1, IsActivated: true}
+		err := NewUserRepo(db).Deactivate(context.Background(), u)
+
+		assert.Nil(t, err)
+		assert.Equal(t, queryDeactivate, db.queries[0])
+		assert.False(t, u.IsActivated)
+		assert.NotNil(t, u.DeactivatedAt)
+	})
+
+	t.Run("return the error of the database", func(t *testing.T) {
+		err := NewUserRepo(&fakeDB{err: errors.New("db error")}).Deactivate(context.Background(), &adeia.User{})
+		assert.EqualError(t, err, "db error")
+	})
+}
+
+func TestUserRepo_UpdateProfile(t *testing.T) {
+	t.Run("update the profile fields", func(t *testing.T) {
+		db := &fakeDB{rowsAffected: 1}
+		u := &adeia.User{ID: 1, Name: "Foo", Designation: "Engineer", Department: "R&D"}
+		err := NewUserRepo(db).UpdateProfile(context.Background(), u, "Bar", "Manager", "Sales")
+
+		assert.Nil(t, err)
+		assert.Equal(t, queryUpdateProfile, db.queries[0])
+		assert.Equal(t, &adeia.User{ID: 1, Name: "Bar", Designation: "Manager", Department: "Sales"}, u)
+	})
+
+	t.Run("return the error of the database", func(t *testing.T) {
+		err := NewUserRepo(&fakeDB{err: errors.New("db error")}).UpdateProfile(context.Background(), &adeia.User{}, "Bar", "", "")
+		assert.EqualError(t, err, "db error")
+	})
+}
+
+func TestUserRepo_UpdatePasswordAndIsActivated(t *testing.T) {
+	t.Run("update the password and activation status", func(t *testing.T) {
+		db := &fakeDB{rowsAffected: 1}
+		u := &adeia.User{ID: 1, Password: "old"}
+		err := NewUserRepo(db).UpdatePasswordAndIsActivated(context.Background(), u, "new", true)
+
+		assert.Nil(t, err)
+		assert.Equal(t, queryUpdatePwdAndIsActivated, db.queries[0])
+		assert.Equal(t, &adeia.User{ID: 1, Password: "new", IsActivated: true}, u)
+	})
+
+	t.Run("return the error of the database", func(t *testing.T) {
+		err := NewUserRepo(&fakeDB{err: errors.New("db error")}).UpdatePasswordAndIsActivated(context.Background(), &adeia.User{}, "new", true)
+		assert.EqualError(t, err, "db error")
+	})
+}
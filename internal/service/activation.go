@@ -0,0 +1,34 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package service
+
+import (
+	"context"
+
+	"adeia"
+)
+
+// ActivateAccount sets the password of the User that the activation token was
+// issued to, and activates their account. The token can only be used once, and
+// only for Users that are not activated yet and were never deactivated.
+func (as *AuthService) ActivateAccount(ctx context.Context, token, password string) error {
+	u, err := as.redeemToken(ctx, activationKeyPrefix, token, func(u *adeia.User) error {
+		if u.IsActivated {
+			as.logger(ctx).Debug("user of the activation token is already activated: " + u.EmployeeID)
+			return adeia.ErrInvalidToken
+		}
+		if u.DeactivatedAt != nil {
+			as.logger(ctx).Debug("user of the activation token is deactivated: " + u.EmployeeID)
+			return adeia.ErrInvalidToken
+		}
+		return checkPassword(u, password)
+	})
+	if err != nil {
+		return err
+	}
+
+	u.IsActivated = true
+	return as.setPassword(ctx, u, password)
+}
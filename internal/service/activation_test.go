@@ -0,0 +1,79 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"adeia"
+	"adeia/pkg/util/crypto"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthService_ActivateAccount(t *testing.T) {
+	const password = "correct horse battery staple"
+	ctx := context.Background()
+
+	setup := func(t *testing.T, activated bool) (*AuthService, *memUserRepo, string) {
+		as, mock, c := setupThrottle(t)
+		t.Cleanup(c)
+
+		repo := &memUserRepo{users: []*adeia.User{
+			{ID: 1, EmployeeID: "FOO123", Name: "Foo", Email: "foo@example.com", IsActivated: activated},
+		}}
+		as.userRepo = repo
+
+		b, _ := crypto.GenerateRandomBytes(32)
+		_ = mock.Set(activationKeyPrefix+crypto.EncodeHex(crypto.Hash(b)), "FOO123")
+		return as, repo, crypto.EncodeBase64(b)
+	}
+
+	t.Run("activate the account and set the password", func(t *testing.T) {
+		as, repo, token := setup(t, false)
+
+		assert.Nil(t, as.ActivateAccount(ctx, token, password))
+		assert.True(t, repo.users[0].IsActivated)
+		match, _ := crypto.ComparePwdHash(password, repo.users[0].Password)
+		assert.True(t, match)
+	})
+
+	t.Run("reject a token that was already used", func(t *testing.T) {
+		as, _, token := setup(t, false)
+
+		assert.Nil(t, as.ActivateAccount(ctx, token, password))
+		assert.Equal(t, adeia.ErrInvalidToken, as.ActivateAccount(ctx, token, password))
+	})
+
+	t.Run("keep the token when the password is weak", func(t *testing.T) {
+		as, repo, token := setup(t, false)
+
+		assert.Error(t, as.ActivateAccount(ctx, token, "foo"))
+		assert.False(t, repo.users[0].IsActivated)
+		assert.Nil(t, as.ActivateAccount(ctx, token, password))
+	})
+
+	t.Run("reject users that are already activated", func(t *testing.T) {
+		as, _, token := setup(t, true)
+
+		assert.Equal(t, adeia.ErrInvalidToken, as.ActivateAccount(ctx, token, password))
+	})
+
+	t.Run("reject users that were deactivated", func(t *testing.T) {
+		as, repo, token := setup(t, true)
+		assert.Nil(t, repo.Deactivate(ctx, repo.users[0]))
+
+		assert.Equal(t, adeia.ErrInvalidToken, as.ActivateAccount(ctx, token, password))
+		assert.False(t, repo.users[0].IsActivated)
+	})
+
+	t.Run("reject unknown tokens", func(t *testing.T) {
+		as, _, _ := setup(t, false)
+
+		assert.Equal(t, adeia.ErrInvalidToken, as.ActivateAccount(ctx, "Zm9v", password))
+		assert.Equal(t, adeia.ErrInvalidToken, as.ActivateAccount(ctx, "!!", password))
+	})
+}
@@ -0,0 +1,115 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"adeia"
+	"adeia/internal/blob"
+	"adeia/pkg/log"
+	"adeia/pkg/util/crypto"
+)
+
+// attachmentKeyLength is the length (in bytes) of the random part of the blob key
+// of an Attachment.
+const attachmentKeyLength = 16
+
+// AttachmentService represents the Attachment service.
+type AttachmentService struct {
+	log    log.Logger
+	repo   adeia.AttachmentRepo
+	blobs  blob.BlobStore
+	signer *blob.Signer
+}
+
+// NewAttachmentService creates a new *AttachmentService.
+func NewAttachmentService(log log.Logger, repo adeia.AttachmentRepo, blobs blob.BlobStore, signer *blob.Signer) *AttachmentService {
+	return &AttachmentService{log, repo, blobs, signer}
+}
+
+// logger returns the logger of the request in ctx, if any.
+func (as *AttachmentService) logger(ctx context.Context) log.Logger {
+	return log.FromContext(ctx, as.log)
+}
+
+// UploadAttachment stores the file uploaded by the User in the blob store, as a new
+// Attachment. The blob key is random, so that it cannot be guessed.
+func (as *AttachmentService) UploadAttachment(ctx context.Context, u *adeia.User, filename, contentType, checksum string, content []byte) (*adeia.Attachment, error) {
+	b, err := crypto.GenerateRandomBytes(attachmentKeyLength)
+	if err != nil {
+		as.logger(ctx).Errorf("cannot generate attachment key: %v", err)
+		return nil, adeia.ErrInternalError
+	}
+
+	a := &adeia.Attachment{
+		UserID:      u.ID,
+		Key:         fmt.Sprintf("attachments/%d/%s", u.ID, crypto.EncodeHex(b)),
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        int64(len(content)),
+		Checksum:    checksum,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := as.blobs.Put(ctx, a.Key, bytes.NewReader(content)); err != nil {
+		as.logger(ctx).Errorf("cannot store attachment: %v", err)
+		return nil, adeia.ErrInternalError
+	}
+
+	if a.ID, err = as.repo.Insert(ctx, a); err != nil {
+		as.logger(ctx).Warnf("cannot create attachment: %v", err)
+		if err := as.blobs.Delete(ctx, a.Key); err != nil {
+			as.logger(ctx).Errorf("cannot delete orphaned attachment %s: %v", a.Key, err)
+		}
+		return nil, adeia.ErrDatabaseError
+	}
+	return a, nil
+}
+
+// GetAttachment returns the Attachment with the ID, along with the query params of a
+// signed link to download it. Users can only get their own Attachments, unless
+// viewAll is true.
+func (as *AttachmentService) GetAttachment(ctx context.Context, u *adeia.User, id int, viewAll bool) (*adeia.Attachment, url.Values, error) {
+	a, err := as.repo.GetByID(ctx, id)
+	if err != nil {
+		as.logger(ctx).Errorf("cannot fetch attachment by id: %v", err)
+		return nil, nil, adeia.ErrDatabaseError
+	} else if a == nil || (a.UserID != u.ID && !viewAll) {
+		// attachments of others are reported as missing, so that they cannot be
+		// enumerated
+		as.logger(ctx).Debugf("attachment %d does not exist, or belongs to another user", id)
+		return nil, nil, adeia.ErrResourceNotFound
+	}
+	return a, as.signer.Sign(a.Key), nil
+}
+
+// OpenAttachment returns the Attachment with the ID and its contents, if q has the
+// query params of a valid download link for it. The caller must close the contents.
+func (as *AttachmentService) OpenAttachment(ctx context.Context, id int, q url.Values) (*adeia.Attachment, io.ReadCloser, error) {
+	a, err := as.repo.GetByID(ctx, id)
+	if err != nil {
+		as.logger(ctx).Errorf("cannot fetch attachment by id: %v", err)
+		return nil, nil, adeia.ErrDatabaseError
+	} else if a == nil {
+		return nil, nil, adeia.ErrInvalidDownloadLink
+	}
+
+	if err := as.signer.Verify(a.Key, q); err != nil {
+		as.logger(ctx).Debugf("cannot download attachment %d: %v", id, err)
+		return nil, nil, adeia.ErrInvalidDownloadLink
+	}
+
+	rc, err := as.blobs.Get(ctx, a.Key)
+	if err != nil {
+		as.logger(ctx).Errorf("cannot read attachment %s: %v", a.Key, err)
+		return nil, nil, adeia.ErrInternalError
+	}
+	return a, rc, nil
+}
@@ -0,0 +1,179 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"adeia"
+	"adeia/internal/blob"
+	"adeia/internal/blob/fs"
+	"adeia/internal/config"
+	logzap "adeia/pkg/log/zap"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// memAttachmentRepo is an in-memory adeia.AttachmentRepo.
+type memAttachmentRepo struct {
+	attachments []*adeia.Attachment
+	insertErr   error
+}
+
+func (m *memAttachmentRepo) GetByID(_ context.Context, id int) (*adeia.Attachment, error) {
+	for _, a := range m.attachments {
+		if a.ID == id {
+			c := *a
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memAttachmentRepo) Insert(_ context.Context, a *adeia.Attachment) (int, error) {
+	if m.insertErr != nil {
+		return 0, m.insertErr
+	}
+	c := *a
+	c.ID = len(m.attachments) + 1
+	m.attachments = append(m.attachments, &c)
+	return c.ID, nil
+}
+
+// setupAttachmentService returns an AttachmentService backed by a blob store in a
+// temporary directory, which is also returned.
+func setupAttachmentService(t *testing.T, linkExpiry int) (*AttachmentService, *memAttachmentRepo, *fs.FS, string) {
+	t.Parallel()
+	conf := &config.BlobConfig{Driver: "fs", Path: t.TempDir(), SigningSecret: "secret", LinkExpiry: linkExpiry}
+	store, err := fs.New(conf)
+	if err != nil {
+		t.Fatalf("cannot create blob store: %v", err)
+	}
+	repo := &memAttachmentRepo{}
+	logger := &logzap.Logger{SugaredLogger: zap.NewNop().Sugar()}
+	return NewAttachmentService(logger, repo, store, blob.NewSigner(conf)), repo, store, conf.Path
+}
+
+func TestAttachmentService_UploadAttachment(t *testing.T) {
+	t.Run("should store the contents and the attachment", func(t *testing.T) {
+		as, repo, store, _ := setupAttachmentService(t, 60)
+		u := &adeia.User{ID: 1}
+
+		a, err := as.UploadAttachment(context.Background(), u, "scan.pdf", "application/pdf", "abc", []byte("%PDF-1.4"))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, a.ID)
+		assert.Equal(t, int64(8), a.Size)
+		assert.Contains(t, a.Key, "attachments/1/")
+		assert.Len(t, repo.attachments, 1)
+
+		rc, err := store.Get(context.Background(), a.Key)
+		assert.NoError(t, err)
+		defer rc.Close()
+		b, _ := ioutil.ReadAll(rc)
+		assert.Equal(t, "%PDF-1.4", string(b))
+	})
+
+	t.Run("should delete the contents if the attachment cannot be created", func(t *testing.T) {
+		as, repo, _, dir := setupAttachmentService(t, 60)
+		repo.insertErr = errors.New("insert failed")
+
+		a, err := as.UploadAttachment(context.Background(), &adeia.User{ID: 1}, "scan.pdf", "application/pdf", "abc", []byte("%PDF-1.4"))
+		assert.Equal(t, adeia.ErrDatabaseError, err)
+		assert.Nil(t, a)
+
+		files, _ := ioutil.ReadDir(filepath.Join(dir, "attachments", "1"))
+		assert.Empty(t, files)
+	})
+}
+
+func TestAttachmentService_GetAttachment(t *testing.T) {
+	as, repo, _, _ := setupAttachmentService(t, 60)
+	_, _ = repo.Insert(context.Background(), &adeia.Attachment{UserID: 1, Key: "attachments/1/a"})
+
+	t.Run("should return own attachment with a signed link", func(t *testing.T) {
+		a, q, err := as.GetAttachment(context.Background(), &adeia.User{ID: 1}, 1, false)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, a.ID)
+		assert.NotEmpty(t, q.Get("signature"))
+		assert.NotEmpty(t, q.Get("expires"))
+	})
+
+	t.Run("should not return the attachment of another user", func(t *testing.T) {
+		a, _, err := as.GetAttachment(context.Background(), &adeia.User{ID: 2}, 1, false)
+		assert.Equal(t, adeia.ErrResourceNotFound, err)
+		assert.Nil(t, a)
+	})
+
+	t.Run("should return the attachment of another user with viewAll", func(t *testing.T) {
+		a, _, err := as.GetAttachment(context.Background(), &adeia.User{ID: 2}, 1, true)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, a.ID)
+	})
+
+	t.Run("should return not found for missing attachment", func(t *testing.T) {
+		_, _, err := as.GetAttachment(context.Background(), &adeia.User{ID: 1}, 42, true)
+		assert.Equal(t, adeia.ErrResourceNotFound, err)
+	})
+}
+
+func TestAttachmentService_OpenAttachment(t *testing.T) {
+	t.Run("should open the attachment with a valid link", func(t *testing.T) {
+		as, _, _, _ := setupAttachmentService(t, 60)
+		u := &adeia.User{ID: 1}
+		up, _ := as.UploadAttachment(context.Background(), u, "scan.png", "image/png", "abc", []byte("png"))
+		_, q, _ := as.GetAttachment(context.Background(), u, up.ID, false)
+
+		a, rc, err := as.OpenAttachment(context.Background(), up.ID, q)
+		assert.NoError(t, err)
+		defer rc.Close()
+		b, _ := ioutil.ReadAll(rc)
+		assert.Equal(t, "scan.png", a.Filename)
+		assert.Equal(t, "png", string(b))
+	})
+
+	t.Run("should reject a link signed for another attachment", func(t *testing.T) {
+		as, _, _, _ := setupAttachmentService(t, 60)
+		u := &adeia.User{ID: 1}
+		first, _ := as.UploadAttachment(context.Background(), u, "a.png", "image/png", "abc", []byte("a"))
+		second, _ := as.UploadAttachment(context.Background(), u, "b.png", "image/png", "abc", []byte("b"))
+		_, q, _ := as.GetAttachment(context.Background(), u, first.ID, false)
+
+		_, rc, err := as.OpenAttachment(context.Background(), second.ID, q)
+		assert.Equal(t, adeia.ErrInvalidDownloadLink, err)
+		assert.Nil(t, rc)
+	})
+
+	t.Run("should reject a tampered link", func(t *testing.T) {
+		as, _, _, _ := setupAttachmentService(t, 60)
+		u := &adeia.User{ID: 1}
+		up, _ := as.UploadAttachment(context.Background(), u, "a.png", "image/png", "abc", []byte("a"))
+		_, q, _ := as.GetAttachment(context.Background(), u, up.ID, false)
+		q.Set("expires", "9999999999")
+
+		_, _, err := as.OpenAttachment(context.Background(), up.ID, q)
+		assert.Equal(t, adeia.ErrInvalidDownloadLink, err)
+	})
+
+	t.Run("should reject an expired link", func(t *testing.T) {
+		as, _, _, _ := setupAttachmentService(t, -1)
+		u := &adeia.User{ID: 1}
+		up, _ := as.UploadAttachment(context.Background(), u, "a.png", "image/png", "abc", []byte("a"))
+		_, q, _ := as.GetAttachment(context.Background(), u, up.ID, false)
+
+		_, _, err := as.OpenAttachment(context.Background(), up.ID, q)
+		assert.Equal(t, adeia.ErrInvalidDownloadLink, err)
+	})
+
+	t.Run("should reject a link for a missing attachment", func(t *testing.T) {
+		as, _, _, _ := setupAttachmentService(t, 60)
+		_, _, err := as.OpenAttachment(context.Background(), 42, nil)
+		assert.Equal(t, adeia.ErrInvalidDownloadLink, err)
+	})
+}
@@ -0,0 +1,274 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"adeia"
+	"adeia/pkg/log"
+	"adeia/pkg/query"
+	"adeia/pkg/util/crypto"
+)
+
+// maxAuditAppendAttempts is the no. of times an AuditEvent is appended to the
+// chain. Since prev_hash is unique, an append fails if another replica appends at
+// the same time, and is retried on the new last AuditEvent.
+const maxAuditAppendAttempts = 3
+
+// auditVerifyBatchSize is the no. of AuditEvents that are fetched at a time, when
+// verifying the chain.
+const auditVerifyBatchSize = 500
+
+// AuditService represents the Audit service.
+type AuditService struct {
+	log  log.Logger
+	repo adeia.AuditRepo
+
+	// mu serializes appends to the chain by this replica.
+	mu sync.Mutex
+}
+
+// NewAuditService creates a new *AuditService.
+func NewAuditService(log log.Logger, repo adeia.AuditRepo) *AuditService {
+	return &AuditService{log: log, repo: repo}
+}
+
+// logger returns the logger of the request in ctx, if any.
+func (aud *AuditService) logger(ctx context.Context) log.Logger {
+	return log.FromContext(ctx, aud.log)
+}
+
+// Record appends an AuditEvent for the action on the target to the audit log, as
+// performed by the AuditActor in ctx. before and after are the target (as structs
+// or maps) before and after the action; only the fields that changed are recorded.
+// The action has already been performed by the time it is recorded, so failures
+// are logged rather than returned. Actions without an AuditActor (like those of a
+// request that was not authenticated) are not recorded, since they cannot be
+// attributed to anyone.
+func (aud *AuditService) Record(ctx context.Context, action, targetType, targetID string, before, after interface{}) {
+	actor := adeia.AuditActorFromContext(ctx)
+	if actor == nil || actor.EmployeeID == "" {
+		aud.logger(ctx).Errorf("refusing to record audit event %s on %s %q without an actor", action, targetType, targetID)
+		return
+	}
+
+	b, a, err := auditDiff(before, after)
+	if err != nil {
+		aud.logger(ctx).Errorf("cannot diff audit event %s: %v", action, err)
+		return
+	}
+
+	e := &adeia.AuditEvent{
+		Actor:      actor.EmployeeID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Before:     b,
+		After:      a,
+		IP:         actor.IP,
+		RequestID:  actor.RequestID,
+		// Postgres stores timestamps in microseconds, and the hash must match
+		// the stored value
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+
+	aud.mu.Lock()
+	defer aud.mu.Unlock()
+	for attempt := 1; ; attempt++ {
+		err := aud.append(ctx, e)
+		if err == nil {
+			return
+		}
+		if attempt == maxAuditAppendAttempts {
+			aud.logger(ctx).Errorf("cannot record audit event %s: %v", action, err)
+			return
+		}
+	}
+}
+
+// append chains the AuditEvent to the last one, and inserts it.
+func (aud *AuditService) append(ctx context.Context, e *adeia.AuditEvent) error {
+	last, err := aud.repo.GetLast(ctx)
+	if err != nil {
+		return err
+	}
+
+	e.PrevHash = []byte{}
+	if last != nil {
+		e.PrevHash = last.Hash
+	}
+	if e.Hash, err = hashAuditEvent(e); err != nil {
+		return err
+	}
+
+	id, err := aud.repo.Insert(ctx, e)
+	if err != nil {
+		return err
+	}
+	e.ID = id
+	return nil
+}
+
+// GetAllEvents returns a page of AuditEvents, as specified by spec, along with the
+// cursor to the next page. Only AuditEvents created in [since, until) are returned,
+// if they are not nil.
+func (aud *AuditService) GetAllEvents(ctx context.Context, spec *query.Spec, since, until *time.Time) ([]*adeia.AuditEvent, string, error) {
+	events, nextCursor, err := aud.repo.GetAll(ctx, spec, since, until)
+	if err != nil {
+		aud.logger(ctx).Errorf("cannot fetch audit events: %v", err)
+		return nil, "", adeia.ErrDatabaseError
+	} else if events == nil {
+		events = []*adeia.AuditEvent{}
+	}
+	return events, nextCursor, nil
+}
+
+// Verify checks the hash chain of the audit log, from the first AuditEvent to the
+// last. Verification stops at the first AuditEvent that does not match the chain.
+// If head (the Head of an earlier verification) is not empty, the chain must also
+// contain it; otherwise, the AuditEvents from head onwards were deleted.
+func (aud *AuditService) Verify(ctx context.Context, head string) (*adeia.AuditVerification, error) {
+	v := &adeia.AuditVerification{OK: true}
+	prevHash := []byte{}
+	lastID := 0
+	head = strings.ToLower(head)
+	headFound := head == ""
+
+	for {
+		events, err := aud.repo.GetAfter(ctx, lastID, auditVerifyBatchSize)
+		if err != nil {
+			aud.logger(ctx).Errorf("cannot fetch audit events: %v", err)
+			return nil, adeia.ErrDatabaseError
+		} else if len(events) == 0 {
+			break
+		}
+
+		for _, e := range events {
+			v.Checked++
+			if !bytes.Equal(e.PrevHash, prevHash) {
+				v.OK, v.BrokenAt, v.Reason = false, e.ID, "previous event was deleted or changed"
+				return v, nil
+			}
+			if h, err := hashAuditEvent(e); err != nil || !bytes.Equal(h, e.Hash) {
+				v.OK, v.BrokenAt, v.Reason = false, e.ID, "event was changed"
+				return v, nil
+			}
+
+			prevHash, lastID = e.Hash, e.ID
+			v.Head = crypto.EncodeHex(e.Hash)
+			headFound = headFound || v.Head == head
+		}
+	}
+
+	if !headFound {
+		v.OK, v.BrokenAt, v.Reason = false, lastID+1, "events from the expected head onwards were deleted"
+	}
+	return v, nil
+}
+
+// auditEventContent represents the fields of an AuditEvent that are hashed, in a
+// fixed order.
+type auditEventContent struct {
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	IP         string          `json:"ip"`
+	RequestID  string          `json:"request_id"`
+	CreatedAt  string          `json:"created_at"`
+}
+
+// hashAuditEvent returns the hash of the PrevHash and the content of the AuditEvent.
+func hashAuditEvent(e *adeia.AuditEvent) ([]byte, error) {
+	content, err := json.Marshal(&auditEventContent{
+		Actor:      e.Actor,
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		Before:     e.Before,
+		After:      e.After,
+		IP:         e.IP,
+		RequestID:  e.RequestID,
+		CreatedAt:  e.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 0, len(e.PrevHash)+len(content))
+	b = append(b, e.PrevHash...)
+	return crypto.Hash(append(b, content...)), nil
+}
+
+// auditDiff returns the fields of before and after (as JSON objects) that differ.
+// When either of them is nil, all the fields of the other are returned.
+func auditDiff(before, after interface{}) (b, a json.RawMessage, err error) {
+	bm, err := toJSONObject(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	am, err := toJSONObject(after)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if bm != nil && am != nil {
+		changedB, changedA := make(map[string]interface{}), make(map[string]interface{})
+		for k, v := range bm {
+			if av, ok := am[k]; !ok || !reflect.DeepEqual(v, av) {
+				changedB[k] = v
+			}
+		}
+		for k, v := range am {
+			if bv, ok := bm[k]; !ok || !reflect.DeepEqual(v, bv) {
+				changedA[k] = v
+			}
+		}
+		bm, am = changedB, changedA
+	}
+
+	if bm != nil {
+		if b, err = json.Marshal(bm); err != nil {
+			return nil, nil, err
+		}
+	}
+	if am != nil {
+		if a, err = json.Marshal(am); err != nil {
+			return nil, nil, err
+		}
+	}
+	return b, a, nil
+}
+
+// toJSONObject returns v (a struct or map) as a JSON object, with the fields named
+// after their `json` tags. nil is returned if v is nil.
+func toJSONObject(v interface{}) (map[string]interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("%T is not a JSON object: %v", v, err)
+	}
+	return m, nil
+}
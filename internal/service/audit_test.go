@@ -0,0 +1,176 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"adeia"
+	logzap "adeia/pkg/log/zap"
+	"adeia/pkg/query"
+	"adeia/pkg/util/crypto"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// memAuditRepo is an in-memory adeia.AuditRepo.
+type memAuditRepo struct {
+	events []*adeia.AuditEvent
+}
+
+func (m *memAuditRepo) GetAll(context.Context, *query.Spec, *time.Time, *time.Time) ([]*adeia.AuditEvent, string, error) {
+	return m.events, "", nil
+}
+
+func (m *memAuditRepo) GetAfter(_ context.Context, id, limit int) ([]*adeia.AuditEvent, error) {
+	var events []*adeia.AuditEvent
+	for _, e := range m.events {
+		if e.ID > id && len(events) < limit {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (m *memAuditRepo) GetLast(context.Context) (*adeia.AuditEvent, error) {
+	if len(m.events) == 0 {
+		return nil, nil
+	}
+	return m.events[len(m.events)-1], nil
+}
+
+func (m *memAuditRepo) Insert(_ context.Context, e *adeia.AuditEvent) (int, error) {
+	c := *e
+	c.ID = len(m.events) + 1
+	m.events = append(m.events, &c)
+	return c.ID, nil
+}
+
+func setupAudit(t *testing.T) (*AuditService, *memAuditRepo) {
+	t.Parallel()
+	repo := &memAuditRepo{}
+	aud := NewAuditService(&logzap.Logger{SugaredLogger: zap.NewNop().Sugar()}, repo)
+
+	ctx := adeia.NewAuditActorContext(context.Background(), &adeia.AuditActor{
+		EmployeeID: "ADMIN1",
+		IP:         "10.0.0.1",
+		RequestID:  "req1",
+	})
+	before := &adeia.User{EmployeeID: "FOO123", Name: "Foo", Designation: "Engineer"}
+	after := &adeia.User{EmployeeID: "FOO123", Name: "Foo", Designation: "Manager"}
+	aud.Record(ctx, adeia.AuditUserCreate, adeia.AuditTargetUser, "FOO123", nil, before)
+	aud.Record(ctx, adeia.AuditUserUpdate, adeia.AuditTargetUser, "FOO123", before, after)
+	aud.Record(ctx, adeia.AuditUserDelete, adeia.AuditTargetUser, "FOO123", after, nil)
+	return aud, repo
+}
+
+func TestAuditService_Record(t *testing.T) {
+	_, repo := setupAudit(t)
+	assert.Len(t, repo.events, 3)
+
+	e := repo.events[1]
+	assert.Equal(t, "ADMIN1", e.Actor)
+	assert.Equal(t, "10.0.0.1", e.IP)
+	assert.Equal(t, "req1", e.RequestID)
+	assert.JSONEq(t, `{"designation": "Engineer"}`, string(e.Before))
+	assert.JSONEq(t, `{"designation": "Manager"}`, string(e.After))
+
+	assert.Empty(t, repo.events[0].PrevHash)
+	assert.Nil(t, repo.events[0].Before)
+	assert.Equal(t, repo.events[0].Hash, repo.events[1].PrevHash)
+	assert.Nil(t, repo.events[2].After)
+
+	t.Run("refuse to record without an actor", func(t *testing.T) {
+		aud, repo := setupAudit(t)
+		aud.Record(context.Background(), adeia.AuditUserDelete, adeia.AuditTargetUser, "FOO123", nil, nil)
+		assert.Len(t, repo.events, 3)
+	})
+}
+
+func TestAuditService_Verify(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("intact chain", func(t *testing.T) {
+		aud, _ := setupAudit(t)
+		v, err := aud.Verify(ctx, "")
+		assert.Nil(t, err)
+		assert.True(t, v.OK)
+		assert.Equal(t, 3, v.Checked)
+		assert.Len(t, v.Head, 64)
+	})
+
+	t.Run("edited event", func(t *testing.T) {
+		aud, repo := setupAudit(t)
+		repo.events[1].After = []byte(`{"designation": "CEO"}`)
+
+		v, err := aud.Verify(ctx, "")
+		assert.Nil(t, err)
+		assert.False(t, v.OK)
+		assert.Equal(t, 2, v.BrokenAt)
+	})
+
+	t.Run("edited event with a recomputed hash", func(t *testing.T) {
+		aud, repo := setupAudit(t)
+		repo.events[1].Actor = "SOMEONE"
+		repo.events[1].Hash, _ = hashAuditEvent(repo.events[1])
+
+		v, err := aud.Verify(ctx, "")
+		assert.Nil(t, err)
+		assert.False(t, v.OK)
+		assert.Equal(t, 3, v.BrokenAt)
+	})
+
+	t.Run("contain the expected head", func(t *testing.T) {
+		aud, repo := setupAudit(t)
+		head := crypto.EncodeHex(repo.events[1].Hash)
+
+		v, err := aud.Verify(ctx, strings.ToUpper(head))
+		assert.Nil(t, err)
+		assert.True(t, v.OK)
+		assert.Equal(t, 3, v.Checked)
+	})
+
+	t.Run("latest events deleted", func(t *testing.T) {
+		aud, repo := setupAudit(t)
+		head := crypto.EncodeHex(repo.events[2].Hash)
+		repo.events = repo.events[:1]
+
+		v, err := aud.Verify(ctx, head)
+		assert.Nil(t, err)
+		assert.False(t, v.OK)
+		assert.Equal(t, 2, v.BrokenAt)
+	})
+
+	t.Run("deleted event", func(t *testing.T) {
+		aud, repo := setupAudit(t)
+		repo.events = append(repo.events[:1], repo.events[2:]...)
+
+		v, err := aud.Verify(ctx, "")
+		assert.Nil(t, err)
+		assert.False(t, v.OK)
+		assert.Equal(t, 3, v.BrokenAt)
+	})
+}
+
+func TestAuditDiff(t *testing.T) {
+	t.Parallel()
+	b, a, err := auditDiff(map[string]interface{}{"level": "info"}, map[string]interface{}{"level": "debug"})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"level": "info"}`, string(b))
+	assert.JSONEq(t, `{"level": "debug"}`, string(a))
+
+	var u *adeia.User
+	b, a, err = auditDiff(u, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, b)
+	assert.Nil(t, a)
+
+	_, _, err = auditDiff("foo", nil)
+	assert.Error(t, err)
+}
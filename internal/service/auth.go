@@ -0,0 +1,458 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"adeia"
+	"adeia/internal/cache"
+	"adeia/internal/config"
+	"adeia/internal/metrics"
+	"adeia/internal/notifier"
+	"adeia/internal/store"
+	"adeia/pkg/log"
+	"adeia/pkg/util/constants"
+	"adeia/pkg/util/crypto"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Audiences of the JWTs, so that a token issued for one purpose cannot be used
+// for another.
+const (
+	accessTokenAudience = "access"
+	mfaTokenAudience    = "mfa"
+)
+
+// dummyPwdHash is compared against when no activated User exists with the email,
+// so that failed logins take the same time, whether or not the email is known.
+const dummyPwdHash = "$argon2id$v=19$m=65536,t=1,p=2$x6WGyKlmQCGC9YrjRa91Rg$QqiSWZXYyfUz8WD1geUx03bpt9Xba5CyyVPUy2B7bUE"
+
+// AuthService represents the Auth service.
+type AuthService struct {
+	log         log.Logger
+	userRepo    adeia.UserRepo
+	prefsRepo   adeia.UserPreferencesRepo
+	roleRepo    adeia.RoleRepo
+	sessionRepo adeia.SessionRepo
+	totpRepo    adeia.TOTPRepo
+	cache       cache.Cache
+	notifier    *notifier.Notifier
+	authConf    *config.AuthConfig
+	jwtSecret   []byte
+	tx          store.Transactor
+}
+
+// NewAuthService creates a new *AuthService. Failed logins are throttled as per
+// authConf, access tokens are signed using jwtSecret, and changes that span
+// multiple repos are made atomic using tx.
+func NewAuthService(
+	log log.Logger,
+	userRepo adeia.UserRepo,
+	prefsRepo adeia.UserPreferencesRepo,
+	roleRepo adeia.RoleRepo,
+	sessionRepo adeia.SessionRepo,
+	totpRepo adeia.TOTPRepo,
+	cache cache.Cache,
+	notifier *notifier.Notifier,
+	authConf *config.AuthConfig,
+	jwtSecret string,
+	tx store.Transactor,
+) *AuthService {
+	return &AuthService{
+		log, userRepo, prefsRepo, roleRepo, sessionRepo, totpRepo, cache, notifier, authConf, []byte(jwtSecret), tx,
+	}
+}
+
+// logger returns the logger of the request in ctx, if any.
+func (as *AuthService) logger(ctx context.Context) log.Logger {
+	return log.FromContext(ctx, as.log)
+}
+
+// Login verifies the email and password of a User, and creates a new Session for
+// the User. Users that are not activated cannot log in.
+//
+// If the User has enabled two-factor authentication, or their Role requires it,
+// only an MFA token is issued, and the Session is created after the second step.
+//
+// Failed logins are throttled per account and per IP, and the account is locked
+// after too many of them.
+func (as *AuthService) Login(ctx context.Context, email, password, ip, userAgent string) (*adeia.AuthTokens, error) {
+	if err := as.checkLoginThrottle(ctx, email, ip); err != nil {
+		return nil, err
+	}
+
+	u, err := as.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		as.logger(ctx).Errorf("cannot fetch user by email: %v", err)
+		return nil, adeia.ErrDatabaseError
+	} else if u == nil || !u.IsActivated {
+		as.logger(ctx).Debug("no activated user exists with the provided email " + email)
+		_, _ = crypto.ComparePwdHash(password, dummyPwdHash)
+		as.recordLoginFailure(ctx, nil, email, ip)
+		return nil, adeia.ErrInvalidCredentials
+	}
+
+	if match, err := crypto.ComparePwdHash(password, u.Password); err != nil {
+		as.logger(ctx).Errorf("cannot compare password hash: %v", err)
+		return nil, adeia.ErrInvalidCredentials
+	} else if !match {
+		as.logger(ctx).Debug("incorrect password for user " + u.EmployeeID)
+		as.recordLoginFailure(ctx, u, email, ip)
+		return nil, adeia.ErrInvalidCredentials
+	}
+
+	if step, err := as.mfaStep(ctx, u); err != nil {
+		return nil, err
+	} else if step != "" {
+		return as.issueMFAToken(u, step)
+	}
+
+	return as.newSession(ctx, u, ip, userAgent)
+}
+
+// newSession creates a new Session for the User, and issues its tokens. The failed
+// logins of the User are forgotten only here, once login is complete, so that they
+// are not reset by a correct password while the second step of login is pending.
+func (as *AuthService) newSession(ctx context.Context, u *adeia.User, ip, userAgent string) (*adeia.AuthTokens, error) {
+	refreshToken, hash, err := newRefreshToken()
+	if err != nil {
+		as.logger(ctx).Errorf("cannot generate refresh token: %v", err)
+		return nil, adeia.ErrInternalError
+	}
+
+	now := time.Now().UTC()
+	s := &adeia.Session{
+		UserID:              u.ID,
+		RefreshToken:        hash,
+		RefreshTokenExpires: now.Add(constants.RefreshTokenExpiry * time.Second),
+		CreatedAt:           now,
+		IP:                  ip,
+		UserAgent:           userAgent,
+	}
+	if s.ID, err = as.sessionRepo.Insert(ctx, s); err != nil {
+		as.logger(ctx).Warnf("cannot create new session: %v", err)
+		return nil, adeia.ErrDatabaseError
+	}
+
+	as.resetLoginFailures(ctx, u.Email)
+	metrics.Logins.WithLabelValues(metrics.LoginSuccess).Inc()
+	return as.issueTokens(u, s, refreshToken)
+}
+
+// Refresh issues new tokens for the Session of the refresh token. The refresh token
+// is rotated, so that it can only be used once. When the same token is used by
+// concurrent requests, only one of them succeeds, and the Session is revoked, as
+// the token has likely been stolen.
+func (as *AuthService) Refresh(ctx context.Context, refreshToken string) (*adeia.AuthTokens, error) {
+	b, err := crypto.DecodeBase64(refreshToken)
+	if err != nil {
+		return nil, adeia.ErrInvalidToken
+	}
+
+	s, err := as.sessionRepo.GetByRefreshToken(ctx, crypto.Hash(b))
+	if err != nil {
+		as.logger(ctx).Errorf("cannot fetch session by refresh token: %v", err)
+		return nil, adeia.ErrDatabaseError
+	} else if s == nil {
+		as.logger(ctx).Debug("session does not exist for the refresh token")
+		return nil, adeia.ErrInvalidToken
+	}
+
+	u, err := as.activeUser(ctx, s.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	newToken, hash, err := newRefreshToken()
+	if err != nil {
+		as.logger(ctx).Errorf("cannot generate refresh token: %v", err)
+		return nil, adeia.ErrInternalError
+	}
+	expires := time.Now().UTC().Add(constants.RefreshTokenExpiry * time.Second)
+	if rowsAffected, err := as.sessionRepo.UpdateRefreshToken(ctx, s, hash, expires); err != nil {
+		as.logger(ctx).Warnf("cannot update refresh token: %v", err)
+		return nil, adeia.ErrDatabaseError
+	} else if rowsAffected == 0 {
+		as.logger(ctx).Warnf("refresh token of session %d was reused, revoking it", s.ID)
+		if _, err := as.sessionRepo.DeleteByIDAndUserID(ctx, s.ID, s.UserID); err != nil {
+			as.logger(ctx).Warnf("cannot delete session: %v", err)
+		}
+		return nil, adeia.ErrInvalidToken
+	}
+
+	return as.issueTokens(u, s, newToken)
+}
+
+// Logout revokes the Session.
+func (as *AuthService) Logout(ctx context.Context, s *adeia.Session) error {
+	if _, err := as.sessionRepo.DeleteByIDAndUserID(ctx, s.ID, s.UserID); err != nil {
+		as.logger(ctx).Warnf("cannot delete session: %v", err)
+		return adeia.ErrDatabaseError
+	}
+	return nil
+}
+
+// Authenticate verifies the access token, and returns the User and Session that
+// it was issued to. Tokens of revoked Sessions, or of Users that have since been
+// deactivated, are rejected.
+func (as *AuthService) Authenticate(ctx context.Context, accessToken string) (*adeia.User, *adeia.Session, error) {
+	claims, err := as.parseAccessToken(accessToken)
+	if err != nil {
+		as.logger(ctx).Debugf("invalid access token: %v", err)
+		return nil, nil, adeia.ErrInvalidToken
+	}
+
+	sessionID, err := strconv.Atoi(claims.Id)
+	if err != nil {
+		return nil, nil, adeia.ErrInvalidToken
+	}
+	s, err := as.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		as.logger(ctx).Errorf("cannot fetch session by id: %v", err)
+		return nil, nil, adeia.ErrDatabaseError
+	} else if s == nil {
+		as.logger(ctx).Debug("session has been revoked or has expired")
+		return nil, nil, adeia.ErrInvalidToken
+	}
+
+	u, err := as.activeUser(ctx, s.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.EmployeeID != claims.Subject {
+		return nil, nil, adeia.ErrInvalidToken
+	}
+	as.loadPreferences(ctx, u)
+	return u, s, nil
+}
+
+// Permissions returns the names of the permissions granted to the User through
+// their Role. Users without a Role have no permissions.
+func (as *AuthService) Permissions(ctx context.Context, u *adeia.User) ([]string, error) {
+	if u.RoleID == nil {
+		return nil, nil
+	}
+
+	permissions, err := as.roleRepo.GetPermissions(ctx, *u.RoleID)
+	if err != nil {
+		as.logger(ctx).Errorf("cannot fetch permissions of role: %v", err)
+		return nil, adeia.ErrDatabaseError
+	}
+	return permissions, nil
+}
+
+// GetSessions returns all the Sessions of the User. The current Session is marked
+// as such.
+func (as *AuthService) GetSessions(ctx context.Context, u *adeia.User, current *adeia.Session) ([]*adeia.Session, error) {
+	sessions, err := as.sessionRepo.GetAllByUserID(ctx, u.ID)
+	if err != nil {
+		as.logger(ctx).Errorf("cannot fetch sessions: %v", err)
+		return nil, adeia.ErrDatabaseError
+	} else if sessions == nil {
+		sessions = []*adeia.Session{}
+	}
+
+	for _, s := range sessions {
+		s.Current = current != nil && s.ID == current.ID
+	}
+	return sessions, nil
+}
+
+// RevokeSession revokes the Session with the ID, if it belongs to the User.
+func (as *AuthService) RevokeSession(ctx context.Context, u *adeia.User, id int) error {
+	rowsAffected, err := as.sessionRepo.DeleteByIDAndUserID(ctx, id, u.ID)
+	if err != nil {
+		as.logger(ctx).Warnf("cannot delete session: %v", err)
+		return adeia.ErrDatabaseError
+	} else if rowsAffected == 0 {
+		as.logger(ctx).Debugf("session %d does not exist for user %s", id, u.EmployeeID)
+		return adeia.ErrResourceNotFound
+	}
+	return nil
+}
+
+// ChangePassword changes the password of the User, after verifying the current
+// password. All other Sessions of the User are revoked, so that anyone else who
+// knew the old password is logged out.
+func (as *AuthService) ChangePassword(ctx context.Context, u *adeia.User, s *adeia.Session, currentPassword, newPassword string) error {
+	if match, err := crypto.ComparePwdHash(currentPassword, u.Password); err != nil || !match {
+		as.logger(ctx).Debug("incorrect current password for user " + u.EmployeeID)
+		return adeia.ErrValidationFailed.AddValidationErr("current_password", "Password is incorrect")
+	}
+
+	if err := as.setPassword(ctx, u, newPassword); err != nil {
+		return err
+	}
+
+	if _, err := as.sessionRepo.DeleteAllByUserID(ctx, u.ID, s.ID); err != nil {
+		as.logger(ctx).Warnf("cannot revoke sessions: %v", err)
+		return adeia.ErrDatabaseError
+	}
+	return nil
+}
+
+// checkPassword checks the strength of the password of the User.
+func checkPassword(u *adeia.User, password string) error {
+	if crypto.PasswordStrength(password, u.Name, u.Email, u.EmployeeID) < constants.MinPasswordStrength {
+		return adeia.ErrValidationFailed.AddValidationErr("new_password", "Password is too weak")
+	}
+	return nil
+}
+
+// setPassword checks the strength of the password, and sets the hash of it as the
+// password of the User.
+func (as *AuthService) setPassword(ctx context.Context, u *adeia.User, password string) error {
+	if err := checkPassword(u, password); err != nil {
+		return err
+	}
+
+	hash, err := crypto.HashPassword(password)
+	if err != nil {
+		as.logger(ctx).Errorf("cannot hash password: %v", err)
+		return adeia.ErrInternalError
+	}
+
+	if err := as.userRepo.UpdatePasswordAndIsActivated(ctx, u, hash, u.IsActivated); err != nil {
+		as.logger(ctx).Warnf("cannot update password: %v", err)
+		return adeia.ErrDatabaseError
+	}
+	return nil
+}
+
+// redeemToken deletes the single-use token (from an emailed link), that is stored
+// in the cache as a hash with the prefix, and returns the User that it was issued
+// to. check is run on the User before the token is deleted, so that the token is
+// not burnt when it fails. Only one of concurrent callers can redeem a token.
+func (as *AuthService) redeemToken(ctx context.Context, prefix, token string, check func(u *adeia.User) error) (*adeia.User, error) {
+	b, err := crypto.DecodeBase64(token)
+	if err != nil {
+		return nil, adeia.ErrInvalidToken
+	}
+
+	key := prefix + crypto.EncodeHex(crypto.Hash(b))
+	var empID string
+	if err := as.cache.Get(&empID, key); err != nil {
+		as.logger(ctx).Errorf("cannot fetch token: %v", err)
+		return nil, adeia.ErrInternalError
+	} else if empID == "" {
+		as.logger(ctx).Debug("token does not exist or has expired")
+		return nil, adeia.ErrInvalidToken
+	}
+
+	u, err := as.userRepo.GetByEmpID(ctx, empID)
+	if err != nil {
+		as.logger(ctx).Errorf("cannot fetch user by employee id: %v", err)
+		return nil, adeia.ErrDatabaseError
+	} else if u == nil {
+		as.logger(ctx).Debug("user of the token no longer exists")
+		return nil, adeia.ErrInvalidToken
+	}
+	if err := check(u); err != nil {
+		return nil, err
+	}
+
+	var redeemed string
+	if err := as.cache.GetDel(&redeemed, key); err != nil {
+		as.logger(ctx).Errorf("cannot delete token: %v", err)
+		return nil, adeia.ErrInternalError
+	} else if redeemed != empID {
+		as.logger(ctx).Debug("token was redeemed concurrently")
+		return nil, adeia.ErrInvalidToken
+	}
+	return u, nil
+}
+
+// loadPreferences sets the UserPreferences of the User, so that responses and
+// emails to them are localized. The defaults are left as-is if they cannot be fetched.
+func (as *AuthService) loadPreferences(ctx context.Context, u *adeia.User) {
+	if p, err := as.prefsRepo.GetByUserID(ctx, u.ID); err != nil {
+		as.logger(ctx).Warnf("cannot fetch user preferences: %v", err)
+	} else if p != nil {
+		u.Preferences = p
+	}
+}
+
+// activeUser returns the User with the ID, if it is activated and not deleted.
+func (as *AuthService) activeUser(ctx context.Context, id int) (*adeia.User, error) {
+	u, err := as.userRepo.GetByID(ctx, id)
+	if err != nil {
+		as.logger(ctx).Errorf("cannot fetch user by id: %v", err)
+		return nil, adeia.ErrDatabaseError
+	} else if u == nil || !u.IsActivated {
+		as.logger(ctx).Debugf("user %d is deleted or deactivated", id)
+		return nil, adeia.ErrInvalidToken
+	}
+	return u, nil
+}
+
+// issueTokens issues a new access token for the Session, along with the refresh token.
+func (as *AuthService) issueTokens(u *adeia.User, s *adeia.Session, refreshToken string) (*adeia.AuthTokens, error) {
+	accessToken, err := as.newAccessToken(u, s)
+	if err != nil {
+		as.log.Errorf("cannot sign access token: %v", err)
+		return nil, adeia.ErrInternalError
+	}
+
+	return &adeia.AuthTokens{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    constants.AccessTokenExpiry,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// newAccessToken creates a signed JWT, with the employee ID of the User as the
+// subject and the Session ID as the token ID.
+func (as *AuthService) newAccessToken(u *adeia.User, s *adeia.Session) (string, error) {
+	now := time.Now()
+	claims := jwt.StandardClaims{
+		Audience:  accessTokenAudience,
+		Subject:   u.EmployeeID,
+		Id:        strconv.Itoa(s.ID),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(constants.AccessTokenExpiry * time.Second).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(as.jwtSecret)
+}
+
+// parseAccessToken verifies the signature, expiry and audience of the access
+// token, and returns its claims.
+func (as *AuthService) parseAccessToken(accessToken string) (*jwt.StandardClaims, error) {
+	claims := &jwt.StandardClaims{}
+	if err := as.parseToken(accessToken, claims); err != nil {
+		return nil, err
+	}
+	if !claims.VerifyAudience(accessTokenAudience, true) {
+		return nil, fmt.Errorf("unexpected audience: %v", claims.Audience)
+	}
+	return claims, nil
+}
+
+// parseToken verifies the signature and expiry of the JWT, and fills in its claims.
+func (as *AuthService) parseToken(token string, claims jwt.Claims) error {
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		// only accept the algorithm that we sign with, so that tokens cannot be
+		// forged by switching algorithms
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return as.jwtSecret, nil
+	})
+	return err
+}
+
+// newRefreshToken generates a random refresh token, along with its hash.
+func newRefreshToken() (token string, hash []byte, err error) {
+	b, err := crypto.GenerateRandomBytes(constants.RefreshTokenLength)
+	if err != nil {
+		return "", nil, err
+	}
+	return crypto.EncodeBase64(b), crypto.Hash(b), nil
+}
@@ -0,0 +1,203 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"adeia"
+	logzap "adeia/pkg/log/zap"
+	"adeia/pkg/util/constants"
+	"adeia/pkg/util/crypto"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestAuthService_AccessToken(t *testing.T) {
+	as := &AuthService{jwtSecret: []byte("secret")}
+	u := &adeia.User{EmployeeID: "FOO123"}
+	s := &adeia.Session{ID: 42}
+
+	t.Run("valid token", func(t *testing.T) {
+		t.Parallel()
+		token, err := as.newAccessToken(u, s)
+		assert.Nil(t, err)
+
+		claims, err := as.parseAccessToken(token)
+		assert.Nil(t, err)
+		assert.Equal(t, "FOO123", claims.Subject)
+		assert.Equal(t, "42", claims.Id)
+	})
+
+	t.Run("token signed with another secret", func(t *testing.T) {
+		t.Parallel()
+		other := &AuthService{jwtSecret: []byte("other")}
+		token, _ := other.newAccessToken(u, s)
+
+		_, err := as.parseAccessToken(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		t.Parallel()
+		claims := jwt.StandardClaims{Subject: "FOO123", Id: "42", ExpiresAt: time.Now().Add(-time.Minute).Unix()}
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(as.jwtSecret)
+
+		_, err := as.parseAccessToken(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("unsigned token", func(t *testing.T) {
+		t.Parallel()
+		claims := jwt.StandardClaims{Subject: "FOO123", Id: "42"}
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+		_, err := as.parseAccessToken(token)
+		assert.Error(t, err)
+	})
+}
+
+func TestNewRefreshToken(t *testing.T) {
+	t.Parallel()
+	token, hash, err := newRefreshToken()
+	assert.Nil(t, err)
+	assert.NotEmpty(t, token)
+	assert.Len(t, hash, 32)
+}
+
+func TestDummyPwdHash(t *testing.T) {
+	t.Parallel()
+	// an invalid hash would fail fast, and give away that the email is unknown
+	match, err := crypto.ComparePwdHash("correct horse battery staple", dummyPwdHash)
+	assert.Nil(t, err)
+	assert.False(t, match)
+}
+
+func TestAuthService_Refresh(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (*AuthService, *memSessionRepo, string) {
+		t.Parallel()
+		token, hash, _ := newRefreshToken()
+		sessions := &memSessionRepo{sessions: []*adeia.Session{{ID: 1, UserID: 1, RefreshToken: hash}}}
+		as := &AuthService{
+			log:         &logzap.Logger{SugaredLogger: zap.NewNop().Sugar()},
+			userRepo:    &memUserRepo{users: []*adeia.User{{ID: 1, EmployeeID: "FOO123", IsActivated: true}}},
+			sessionRepo: sessions,
+			jwtSecret:   []byte("secret"),
+		}
+		return as, sessions, token
+	}
+
+	t.Run("rotate the refresh token", func(t *testing.T) {
+		as, _, token := setup(t)
+
+		tokens, err := as.Refresh(ctx, token)
+		assert.Nil(t, err)
+		assert.NotEqual(t, token, tokens.RefreshToken)
+
+		_, err = as.Refresh(ctx, token)
+		assert.Equal(t, adeia.ErrInvalidToken, err)
+		_, err = as.Refresh(ctx, tokens.RefreshToken)
+		assert.Nil(t, err)
+	})
+
+	t.Run("revoke the session when the token is reused concurrently", func(t *testing.T) {
+		as, sessions, token := setup(t)
+		// another request rotates the token after this one has read the session
+		sessions.afterGet = func() { sessions.sessions[0].RefreshToken = []byte("rotated") }
+
+		_, err := as.Refresh(ctx, token)
+		assert.Equal(t, adeia.ErrInvalidToken, err)
+		assert.Empty(t, sessions.sessions)
+	})
+
+	t.Run("reject unknown tokens", func(t *testing.T) {
+		as, _, _ := setup(t)
+
+		_, err := as.Refresh(ctx, "Zm9v")
+		assert.Equal(t, adeia.ErrInvalidToken, err)
+	})
+}
+
+func TestAuthService_MFAToken(t *testing.T) {
+	as := &AuthService{jwtSecret: []byte("secret")}
+	u := &adeia.User{EmployeeID: "FOO123"}
+
+	t.Run("valid token", func(t *testing.T) {
+		t.Parallel()
+		token, err := as.newMFAToken(u, adeia.MFAStepVerify)
+		assert.Nil(t, err)
+
+		claims, err := as.parseMFAToken(token)
+		assert.Nil(t, err)
+		assert.Equal(t, "FOO123", claims.Subject)
+		assert.Equal(t, adeia.MFAStepVerify, claims.Step)
+	})
+
+	t.Run("mfa token used as access token", func(t *testing.T) {
+		t.Parallel()
+		token, _ := as.newMFAToken(u, adeia.MFAStepVerify)
+
+		_, err := as.parseAccessToken(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("access token used as mfa token", func(t *testing.T) {
+		t.Parallel()
+		token, _ := as.newAccessToken(u, &adeia.Session{ID: 42})
+
+		_, err := as.parseMFAToken(token)
+		assert.Error(t, err)
+	})
+}
+
+func TestNewRecoveryCodes(t *testing.T) {
+	t.Parallel()
+	codes, hashes, err := newRecoveryCodes()
+	assert.Nil(t, err)
+	assert.Len(t, codes, constants.RecoveryCodeCount)
+	assert.Len(t, hashes, constants.RecoveryCodeCount)
+
+	for i, c := range codes {
+		assert.Regexp(t, "^[0-9a-f]{5}-[0-9a-f]{5}$", c)
+		assert.Equal(t, hashes[i], hashRecoveryCode(strings.ToUpper(c)))
+		assert.Equal(t, hashes[i], hashRecoveryCode(strings.ReplaceAll(c, "-", "")))
+	}
+}
+
+// fakeRoleRepo is an adeia.RoleRepo with a fixed set of permissions per Role.
+type fakeRoleRepo struct {
+	adeia.RoleRepo
+	permissions map[int][]string
+}
+
+func (f *fakeRoleRepo) GetPermissions(_ context.Context, id int) ([]string, error) {
+	return f.permissions[id], nil
+}
+
+func TestAuthService_Permissions(t *testing.T) {
+	as := &AuthService{roleRepo: &fakeRoleRepo{permissions: map[int][]string{1: {"VIEW_USERS"}}}}
+	roleID := 1
+
+	t.Run("users without a role have no permissions", func(t *testing.T) {
+		t.Parallel()
+		got, err := as.Permissions(context.Background(), &adeia.User{})
+		assert.Nil(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("users get the permissions of their role", func(t *testing.T) {
+		t.Parallel()
+		got, err := as.Permissions(context.Background(), &adeia.User{RoleID: &roleID})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"VIEW_USERS"}, got)
+	})
+}
@@ -0,0 +1,169 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"adeia"
+	"adeia/internal/metrics"
+	"adeia/internal/notifier"
+)
+
+// Failed logins are counted per account (by email, so that attempts on accounts
+// that do not exist are counted too) and per IP address, in the cache. Each key
+// is suffixed with the account or IP that it is for.
+const (
+	loginFailuresKeyPrefix = "login_failures:"
+	loginDelayKeyPrefix    = "login_delay:"
+	loginLockKeyPrefix     = "login_lock:"
+)
+
+// maxLoginDelay (in seconds) is the maximum wait between failed logins, before
+// the account is locked.
+const maxLoginDelay = 60
+
+func accountThrottleKey(email string) string {
+	return "account:" + strings.ToLower(email)
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// checkLoginThrottle checks if a login can be attempted for the email, from the IP.
+func (as *AuthService) checkLoginThrottle(ctx context.Context, email, ip string) error {
+	for _, key := range []string{
+		loginLockKeyPrefix + accountThrottleKey(email),
+		loginLockKeyPrefix + ipThrottleKey(ip),
+	} {
+		if ttl, err := as.cache.TTL(key); err != nil {
+			as.logger(ctx).Errorf("cannot fetch login lock: %v", err)
+			return adeia.ErrInternalError
+		} else if ttl != 0 {
+			as.logger(ctx).Debugf("login is locked for %s, for %d seconds", key, ttl)
+			return adeia.ErrAccountLocked
+		}
+	}
+
+	if ttl, err := as.cache.TTL(loginDelayKeyPrefix + accountThrottleKey(email)); err != nil {
+		as.logger(ctx).Errorf("cannot fetch login delay: %v", err)
+		return adeia.ErrInternalError
+	} else if ttl != 0 {
+		as.logger(ctx).Debugf("login is delayed for %s, for %d seconds", email, ttl)
+		return adeia.ErrLoginThrottled
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed login for the email, from the IP. After a few
+// failures, each retry must wait twice as long as the previous one, and after
+// enough failures, the account (or IP) is locked. u is the User with the email, if
+// they exist.
+func (as *AuthService) recordLoginFailure(ctx context.Context, u *adeia.User, email, ip string) {
+	metrics.Logins.WithLabelValues(metrics.LoginFailure).Inc()
+
+	account := accountThrottleKey(email)
+	n, err := as.cache.Incr(loginFailuresKeyPrefix+account, as.authConf.FailedLoginWindow)
+	if err != nil {
+		as.logger(ctx).Errorf("cannot count failed login: %v", err)
+		return
+	}
+
+	switch {
+	case n >= as.authConf.MaxFailedLogins:
+		as.lock(ctx, account)
+		if u != nil && as.authConf.LockoutAlert {
+			// the request is done by the time the email is sent, so we don't use its ctx
+			go as.sendLockoutAlert(context.Background(), u, ip)
+		}
+	case n >= as.authConf.LoginDelayAfter:
+		delay := 1 << uint(n-as.authConf.LoginDelayAfter)
+		if delay > maxLoginDelay {
+			delay = maxLoginDelay
+		}
+		if err := as.cache.SetWithExpiry(loginDelayKeyPrefix+account, "1", delay); err != nil {
+			as.logger(ctx).Errorf("cannot set login delay: %v", err)
+		}
+	}
+
+	n, err = as.cache.Incr(loginFailuresKeyPrefix+ipThrottleKey(ip), as.authConf.FailedLoginWindow)
+	if err != nil {
+		as.logger(ctx).Errorf("cannot count failed login: %v", err)
+	} else if n >= as.authConf.MaxFailedLoginsPerIP {
+		as.lock(ctx, ipThrottleKey(ip))
+	}
+}
+
+// resetLoginFailures forgets the failed logins of the email, after a successful
+// login. Failures of the IP are not forgotten, so that an attacker cannot reset
+// them by logging in to their own account.
+func (as *AuthService) resetLoginFailures(ctx context.Context, email string) {
+	account := accountThrottleKey(email)
+	if err := as.cache.Delete(loginFailuresKeyPrefix+account, loginDelayKeyPrefix+account); err != nil {
+		as.logger(ctx).Errorf("cannot reset failed logins: %v", err)
+	}
+}
+
+// lock locks the account or IP with the key, for the lockout duration.
+func (as *AuthService) lock(ctx context.Context, key string) {
+	as.logger(ctx).Warnf("locking %s due to too many failed logins", key)
+	metrics.LoginLockouts.Inc()
+	if err := as.cache.SetWithExpiry(loginLockKeyPrefix+key, "1", as.authConf.LockoutDuration); err != nil {
+		as.logger(ctx).Errorf("cannot lock login: %v", err)
+	}
+	if err := as.cache.Delete(loginFailuresKeyPrefix+key, loginDelayKeyPrefix+key); err != nil {
+		as.logger(ctx).Errorf("cannot reset failed logins: %v", err)
+	}
+}
+
+// sendLockoutAlert notifies the User that their account has been locked, through
+// the channels that they prefer. The time at which the lock expires is shown in
+// their time zone.
+func (as *AuthService) sendLockoutAlert(ctx context.Context, u *adeia.User, ip string) {
+	as.loadPreferences(ctx, u)
+	until := time.Now().Add(time.Duration(as.authConf.LockoutDuration) * time.Second)
+	err := as.notifier.Notify(ctx, &notifier.Notification{
+		User:     u,
+		Template: "account_locked",
+		Data: map[string]interface{}{
+			"Name":     u.Name,
+			"IP":       ip,
+			"Duration": as.authConf.LockoutDuration / 60,
+			"Until":    until.In(u.Preferences.Location()).Format("2006-01-02 15:04 MST"),
+		},
+	})
+	if err != nil {
+		as.logger(ctx).Errorf("cannot send lockout alert: %v", err)
+		return
+	}
+	as.logger(ctx).Debug("sent lockout alert to user " + u.EmployeeID)
+}
+
+// accountLockKeys returns the keys of the lockout, the login delay and the failed
+// logins of the account with the email.
+func accountLockKeys(email string) []string {
+	account := accountThrottleKey(email)
+	return []string{loginLockKeyPrefix + account, loginDelayKeyPrefix + account, loginFailuresKeyPrefix + account}
+}
+
+// UnlockUser removes the login lockout of the User with the provided employee ID,
+// along with their failed logins.
+func (us *UserService) UnlockUser(ctx context.Context, empID string) error {
+	u, err := us.GetUserByEmpID(ctx, empID)
+	if err != nil {
+		return err
+	}
+
+	if err := us.cache.Delete(accountLockKeys(u.Email)...); err != nil {
+		us.logger(ctx).Errorf("cannot unlock user: %v", err)
+		return adeia.ErrInternalError
+	}
+
+	us.audit.Record(ctx, adeia.AuditUserUnlock, adeia.AuditTargetUser, u.EmployeeID, nil, nil)
+	return nil
+}
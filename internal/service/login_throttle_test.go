@@ -0,0 +1,197 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package service
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"adeia"
+	"adeia/internal/cache/redis"
+	"adeia/internal/config"
+	"adeia/internal/notifier"
+	logzap "adeia/pkg/log/zap"
+	"adeia/pkg/totp"
+	"adeia/pkg/util/crypto"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func setupThrottle(t *testing.T) (as *AuthService, mock *miniredis.Miniredis, c func()) {
+	t.Parallel()
+	mock, _ = miniredis.Run()
+	port, _ := strconv.Atoi(mock.Port())
+	r, _ := redis.New(&config.CacheConfig{Network: "tcp", Host: mock.Host(), Port: port, ConnSize: 10})
+
+	as = &AuthService{
+		log:   &logzap.Logger{SugaredLogger: zap.NewNop().Sugar()},
+		cache: r,
+		authConf: &config.AuthConfig{
+			MaxFailedLogins:      5,
+			MaxFailedLoginsPerIP: 8,
+			LoginDelayAfter:      2,
+			FailedLoginWindow:    900,
+			LockoutDuration:      600,
+		},
+	}
+	return as, mock, func() {
+		_ = r.Close()
+		mock.Close()
+	}
+}
+
+func TestAuthService_LoginThrottle(t *testing.T) {
+	const email, ip = "foo@example.com", "10.0.0.1"
+	ctx := context.Background()
+
+	t.Run("allow login without failures", func(t *testing.T) {
+		as, _, c := setupThrottle(t)
+		defer c()
+
+		assert.Nil(t, as.checkLoginThrottle(ctx, email, ip))
+	})
+
+	t.Run("delay login progressively", func(t *testing.T) {
+		as, mock, c := setupThrottle(t)
+		defer c()
+
+		as.recordLoginFailure(ctx, nil, email, ip)
+		assert.Nil(t, as.checkLoginThrottle(ctx, email, ip))
+
+		as.recordLoginFailure(ctx, nil, email, ip)
+		assert.Equal(t, adeia.ErrLoginThrottled, as.checkLoginThrottle(ctx, "FOO@example.com", ip))
+		assert.Equal(t, time.Second, mock.TTL(loginDelayKeyPrefix+accountThrottleKey(email)))
+
+		as.recordLoginFailure(ctx, nil, email, ip)
+		assert.Equal(t, 2*time.Second, mock.TTL(loginDelayKeyPrefix+accountThrottleKey(email)))
+
+		// other accounts are not affected
+		assert.Nil(t, as.checkLoginThrottle(ctx, "bar@example.com", ip))
+	})
+
+	t.Run("lock account after max failures", func(t *testing.T) {
+		as, mock, c := setupThrottle(t)
+		defer c()
+
+		for i := 0; i < 5; i++ {
+			as.recordLoginFailure(ctx, nil, email, ip)
+		}
+		assert.Equal(t, adeia.ErrAccountLocked, as.checkLoginThrottle(ctx, email, "10.0.0.2"))
+		assert.Equal(t, 600*time.Second, mock.TTL(loginLockKeyPrefix+accountThrottleKey(email)))
+
+		mock.FastForward(600 * time.Second)
+		assert.Nil(t, as.checkLoginThrottle(ctx, email, "10.0.0.2"))
+	})
+
+	t.Run("lock ip after max failures", func(t *testing.T) {
+		as, _, c := setupThrottle(t)
+		defer c()
+
+		for i := 0; i < 8; i++ {
+			as.recordLoginFailure(ctx, nil, strconv.Itoa(i)+"@example.com", ip)
+		}
+		assert.Equal(t, adeia.ErrAccountLocked, as.checkLoginThrottle(ctx, email, ip))
+		assert.Nil(t, as.checkLoginThrottle(ctx, email, "10.0.0.2"))
+	})
+
+	t.Run("reset failures on success", func(t *testing.T) {
+		as, mock, c := setupThrottle(t)
+		defer c()
+
+		for i := 0; i < 3; i++ {
+			as.recordLoginFailure(ctx, nil, email, ip)
+		}
+		as.resetLoginFailures(ctx, email)
+		assert.Nil(t, as.checkLoginThrottle(ctx, email, ip))
+		assert.False(t, mock.Exists(loginFailuresKeyPrefix+accountThrottleKey(email)))
+		assert.True(t, mock.Exists(loginFailuresKeyPrefix+ipThrottleKey(ip)))
+	})
+	t.Run("keep failures until the second step of login is complete", func(t *testing.T) {
+		as, mock, c := setupThrottle(t)
+		defer c()
+
+		hash, _ := crypto.HashPassword("correct horse battery staple")
+		secret := []byte("12345678901234567890")
+		as.userRepo = &memUserRepo{users: []*adeia.User{
+			{ID: 1, EmployeeID: "FOO123", Email: email, Password: hash, IsActivated: true},
+		}}
+		as.totpRepo = newMemTOTPRepo()
+		_ = as.totpRepo.Confirm(ctx, &adeia.UserTOTP{UserID: 1, Secret: secret}, 0)
+		as.sessionRepo = &memSessionRepo{}
+		as.jwtSecret = []byte("secret")
+		failures := loginFailuresKeyPrefix + accountThrottleKey(email)
+
+		as.recordLoginFailure(ctx, nil, email, ip)
+		tokens, err := as.Login(ctx, email, "correct horse battery staple", ip, "")
+		assert.Nil(t, err)
+		assert.NotEmpty(t, tokens.MFAToken)
+		assert.True(t, mock.Exists(failures), "a correct password must not reset failures")
+
+		_, err = as.VerifyLogin(ctx, tokens.MFAToken, "000000", ip, "")
+		assert.Equal(t, adeia.ErrInvalidOTP, err)
+		n, _ := mock.Get(failures)
+		assert.Equal(t, "2", n)
+
+		mock.FastForward(2 * time.Second)
+		tokens, err = as.Login(ctx, email, "correct horse battery staple", ip, "")
+		assert.Nil(t, err)
+		n, _ = mock.Get(failures)
+		assert.Equal(t, "2", n)
+
+		tokens, err = as.VerifyLogin(ctx, tokens.MFAToken, totp.Code(secret, totp.Step(time.Now())), ip, "")
+		assert.Nil(t, err)
+		assert.NotEmpty(t, tokens.AccessToken)
+		assert.False(t, mock.Exists(failures))
+	})
+}
+
+func TestAuthService_SendLockoutAlert(t *testing.T) {
+	setup := func(t *testing.T, p *adeia.UserPreferences) (*AuthService, *fakeMailer, *adeia.User) {
+		as, _, c := setupThrottle(t)
+		t.Cleanup(c)
+
+		m := &fakeMailer{}
+		as.notifier = notifier.New(as.log, m, &memPendingNotificationRepo{})
+		as.prefsRepo = newMemPreferencesRepo()
+		u := &adeia.User{ID: 1, EmployeeID: "FOO123", Name: "Foo", Email: "foo@example.com"}
+		if p != nil {
+			p.UserID = u.ID
+			_ = as.prefsRepo.Upsert(context.Background(), p)
+		}
+		return as, m, u
+	}
+
+	t.Run("show the lock expiry in the time zone of the user", func(t *testing.T) {
+		p := adeia.NewUserPreferences()
+		p.Locale, p.TimeZone = "ta", "Asia/Kolkata"
+		as, m, u := setup(t, p)
+
+		as.sendLockoutAlert(context.Background(), u, "10.0.0.1")
+		assert.Len(t, m.sent, 1)
+		assert.Equal(t, "ta", m.sent[0].Locale)
+		assert.Contains(t, m.sent[0].Data.(map[string]interface{})["Until"], "IST")
+	})
+
+	t.Run("use the defaults without preferences", func(t *testing.T) {
+		as, m, u := setup(t, nil)
+
+		as.sendLockoutAlert(context.Background(), u, "10.0.0.1")
+		assert.Len(t, m.sent, 1)
+		assert.Contains(t, m.sent[0].Data.(map[string]interface{})["Until"], "UTC")
+	})
+
+	t.Run("skip users that turned off email notifications", func(t *testing.T) {
+		p := adeia.NewUserPreferences()
+		p.Channels = adeia.Channels{}
+		as, m, u := setup(t, p)
+
+		as.sendLockoutAlert(context.Background(), u, "10.0.0.1")
+		assert.Empty(t, m.sent)
+	})
+}
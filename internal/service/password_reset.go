@@ -0,0 +1,94 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package service
+
+import (
+	"context"
+
+	"adeia"
+	"adeia/internal/notifier"
+	"adeia/pkg/util/constants"
+	"adeia/pkg/util/crypto"
+)
+
+const passwordResetKeyPrefix = "password_reset:"
+
+// RequestPasswordReset emails a single-use password reset link to the User with
+// the email. Nothing is reported back about whether the User exists, and the email
+// is sent in the background, so that the response (and its timing) is the same
+// either way, and accounts cannot be enumerated.
+func (as *AuthService) RequestPasswordReset(ctx context.Context, email string) error {
+	u, err := as.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		as.logger(ctx).Errorf("cannot fetch user by email: %v", err)
+		return adeia.ErrDatabaseError
+	} else if u == nil {
+		as.logger(ctx).Debug("password reset requested for non-existent email " + email)
+		return nil
+	}
+
+	// the request is done by the time the email is sent, so we don't use its ctx
+	go as.sendPasswordResetEmail(context.Background(), u)
+	return nil
+}
+
+// ResetPassword sets the password of the User that the password reset token was
+// issued to. The token can only be used once. All the Sessions of the User are
+// revoked, and the login lockout of their account is lifted, since they have
+// proven that they own its email.
+func (as *AuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	u, err := as.redeemToken(ctx, passwordResetKeyPrefix, token, func(u *adeia.User) error {
+		return checkPassword(u, newPassword)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := as.setPassword(ctx, u, newPassword); err != nil {
+		return err
+	}
+	if err := as.cache.Delete(accountLockKeys(u.Email)...); err != nil {
+		as.logger(ctx).Errorf("cannot unlock account: %v", err)
+	}
+
+	if _, err := as.sessionRepo.DeleteAllByUserID(ctx, u.ID, 0); err != nil {
+		as.logger(ctx).Warnf("cannot revoke sessions: %v", err)
+		return adeia.ErrDatabaseError
+	}
+	return nil
+}
+
+// sendPasswordResetEmail sends an email with a single-use password reset link to
+// the User. Only a hash of the token in the link is stored, so that a leaked cache
+// cannot be used to reset passwords.
+func (as *AuthService) sendPasswordResetEmail(ctx context.Context, u *adeia.User) {
+	b, err := crypto.GenerateRandomBytes(constants.PasswordResetTokenLength)
+	if err != nil {
+		as.logger(ctx).Errorf("cannot generate password reset token: %v", err)
+		return
+	}
+
+	key := passwordResetKeyPrefix + crypto.EncodeHex(crypto.Hash(b))
+	if err := as.cache.SetWithExpiry(key, u.EmployeeID, constants.PasswordResetTokenExpiry); err != nil {
+		as.logger(ctx).Errorf("cannot store password reset token: %v", err)
+		return
+	}
+
+	as.loadPreferences(ctx, u)
+	err = as.notifier.Send(&notifier.Notification{
+		User:     u,
+		Template: "password_reset",
+		Data: map[string]interface{}{
+			"Name":   u.Name,
+			"Token":  crypto.EncodeBase64(b),
+			"Expiry": constants.PasswordResetTokenExpiry / 60,
+		},
+	})
+	if err != nil {
+		as.logger(ctx).Errorf("cannot send password reset email: %v", err)
+		return
+	}
+	as.logger(ctx).Debug("sent password reset email to user " + u.EmployeeID)
+}
@@ -0,0 +1,95 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"adeia"
+	"adeia/internal/notifier"
+	"adeia/pkg/util/crypto"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthService_PasswordReset(t *testing.T) {
+	const email, password = "foo@example.com", "correct horse battery staple"
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (*AuthService, *miniredis.Miniredis, *memUserRepo, *memSessionRepo, *fakeMailer) {
+		as, mock, c := setupThrottle(t)
+		t.Cleanup(c)
+
+		users := &memUserRepo{users: []*adeia.User{
+			{ID: 1, EmployeeID: "FOO123", Name: "Foo", Email: email, IsActivated: true},
+		}}
+		sessions := &memSessionRepo{}
+		_, _ = sessions.Insert(ctx, &adeia.Session{UserID: 1})
+		m := &fakeMailer{}
+		as.userRepo, as.sessionRepo, as.prefsRepo = users, sessions, newMemPreferencesRepo()
+		as.notifier = notifier.New(as.log, m, &memPendingNotificationRepo{})
+		return as, mock, users, sessions, m
+	}
+
+	// requestToken requests a password reset, and returns the token in the email.
+	requestToken := func(t *testing.T, as *AuthService, m *fakeMailer) string {
+		assert.Nil(t, as.RequestPasswordReset(ctx, email))
+		assert.Eventually(t, func() bool { return len(m.messages()) == 1 }, time.Second, 10*time.Millisecond)
+		return m.messages()[0].Data.(map[string]interface{})["Token"].(string)
+	}
+
+	t.Run("send nothing for unknown emails", func(t *testing.T) {
+		as, mock, _, _, m := setup(t)
+
+		assert.Nil(t, as.RequestPasswordReset(ctx, "bar@example.com"))
+		time.Sleep(50 * time.Millisecond)
+		assert.Empty(t, m.messages())
+		assert.Empty(t, mock.Keys())
+	})
+
+	t.Run("reset the password, revoke sessions and lift the lockout", func(t *testing.T) {
+		as, mock, users, sessions, m := setup(t)
+		token := requestToken(t, as, m)
+		for i := 0; i < 5; i++ {
+			as.recordLoginFailure(ctx, nil, email, "10.0.0.1")
+		}
+		assert.Equal(t, adeia.ErrAccountLocked, as.checkLoginThrottle(ctx, email, "10.0.0.2"))
+
+		assert.Nil(t, as.ResetPassword(ctx, token, password))
+		match, _ := crypto.ComparePwdHash(password, users.users[0].Password)
+		assert.True(t, match)
+		assert.Empty(t, sessions.sessions)
+		assert.Nil(t, as.checkLoginThrottle(ctx, email, "10.0.0.2"))
+		for _, k := range mock.Keys() {
+			assert.NotContains(t, k, passwordResetKeyPrefix, "the token is deleted")
+		}
+	})
+
+	t.Run("reject a token that was already used", func(t *testing.T) {
+		as, _, _, _, m := setup(t)
+		token := requestToken(t, as, m)
+
+		assert.Nil(t, as.ResetPassword(ctx, token, password))
+		assert.Equal(t, adeia.ErrInvalidToken, as.ResetPassword(ctx, token, password+" again"))
+	})
+
+	t.Run("keep the token when the password is weak", func(t *testing.T) {
+		as, _, _, _, m := setup(t)
+		token := requestToken(t, as, m)
+
+		assert.Error(t, as.ResetPassword(ctx, token, "foo"))
+		assert.Nil(t, as.ResetPassword(ctx, token, password))
+	})
+
+	t.Run("reject invalid tokens", func(t *testing.T) {
+		as, _, _, _, _ := setup(t)
+
+		assert.Equal(t, adeia.ErrInvalidToken, as.ResetPassword(ctx, "Zm9v", password))
+		assert.Equal(t, adeia.ErrInvalidToken, as.ResetPassword(ctx, "!!", password))
+	})
+}
@@ -0,0 +1,345 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"adeia"
+	"adeia/internal/mailer"
+	"adeia/pkg/query"
+)
+
+// memUserRepo is an in-memory adeia.UserRepo. Lookups ignore the case of the
+// employee ID, like the citext column does.
+type memUserRepo struct {
+	users []*adeia.User
+}
+
+func (m *memUserRepo) find(match func(u *adeia.User) bool) *adeia.User {
+	for _, u := range m.users {
+		if match(u) {
+			c := *u
+			return &c
+		}
+	}
+	return nil
+}
+
+func (m *memUserRepo) byID(id int) *adeia.User {
+	for _, u := range m.users {
+		if u.ID == id {
+			return u
+		}
+	}
+	return nil
+}
+
+func (m *memUserRepo) Deactivate(_ context.Context, u *adeia.User) error {
+	now := time.Now().UTC()
+	u.IsActivated, u.DeactivatedAt = false, &now
+	stored := m.byID(u.ID)
+	stored.IsActivated, stored.DeactivatedAt = false, &now
+	return nil
+}
+
+func (m *memUserRepo) DeleteByEmpID(_ context.Context, empID string) (int64, error) {
+	for _, u := range m.users {
+		if strings.EqualFold(u.EmployeeID, empID) && u.DeletedAt == nil {
+			now := time.Now().UTC()
+			u.DeletedAt = &now
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (m *memUserRepo) GetAll(context.Context, *query.Spec) ([]*adeia.User, string, error) {
+	var users []*adeia.User
+	for _, u := range m.users {
+		if u.DeletedAt == nil {
+			users = append(users, u)
+		}
+	}
+	return users, "", nil
+}
+
+func (m *memUserRepo) GetAllInclDeleted(context.Context, *query.Spec) ([]*adeia.User, string, error) {
+	return m.users, "", nil
+}
+
+func (m *memUserRepo) GetByEmail(_ context.Context, email string) (*adeia.User, error) {
+	return m.find(func(u *adeia.User) bool { return u.Email == email && u.DeletedAt == nil }), nil
+}
+
+func (m *memUserRepo) GetByEmpID(_ context.Context, empID string) (*adeia.User, error) {
+	return m.find(func(u *adeia.User) bool { return strings.EqualFold(u.EmployeeID, empID) && u.DeletedAt == nil }), nil
+}
+
+func (m *memUserRepo) GetDeletedByEmpID(_ context.Context, empID string) (*adeia.User, error) {
+	var deleted *adeia.User
+	for _, u := range m.users {
+		if strings.EqualFold(u.EmployeeID, empID) && u.DeletedAt != nil &&
+			(deleted == nil || u.DeletedAt.After(*deleted.DeletedAt)) {
+			deleted = u
+		}
+	}
+	if deleted == nil {
+		return nil, nil
+	}
+	c := *deleted
+	return &c, nil
+}
+
+func (m *memUserRepo) GetByID(_ context.Context, id int) (*adeia.User, error) {
+	return m.find(func(u *adeia.User) bool { return u.ID == id && u.DeletedAt == nil }), nil
+}
+
+func (m *memUserRepo) Insert(_ context.Context, u *adeia.User) (int, error) {
+	c := *u
+	c.ID = len(m.users) + 1
+	m.users = append(m.users, &c)
+	return c.ID, nil
+}
+
+func (m *memUserRepo) Restore(_ context.Context, u *adeia.User) error {
+	m.byID(u.ID).DeletedAt = nil
+	u.DeletedAt = nil
+	return nil
+}
+
+func (m *memUserRepo) UpdatePasswordAndIsActivated(_ context.Context, u *adeia.User, password string, isActivated bool) error {
+	u.Password, u.IsActivated = password, isActivated
+	stored := m.byID(u.ID)
+	stored.Password, stored.IsActivated = password, isActivated
+	return nil
+}
+
+func (m *memUserRepo) UpdateProfile(_ context.Context, u *adeia.User, name, designation, department string) error {
+	u.Name, u.Designation, u.Department = name, designation, department
+	stored := m.byID(u.ID)
+	stored.Name, stored.Designation, stored.Department = name, designation, department
+	return nil
+}
+
+// memSessionRepo is an in-memory adeia.SessionRepo. withoutMFA lists the IDs of the
+// Users of each Role, that have not enabled two-factor authentication.
+type memSessionRepo struct {
+	sessions   []*adeia.Session
+	withoutMFA map[int][]int
+	// afterGet, if set, is called after a Session is read by its refresh token,
+	// to simulate concurrent requests.
+	afterGet func()
+}
+
+func (m *memSessionRepo) delete(match func(s *adeia.Session) bool) int64 {
+	var kept []*adeia.Session
+	for _, s := range m.sessions {
+		if !match(s) {
+			kept = append(kept, s)
+		}
+	}
+	n := len(m.sessions) - len(kept)
+	m.sessions = kept
+	return int64(n)
+}
+
+func (m *memSessionRepo) DeleteAllByUserID(_ context.Context, userID int, exceptID int) (int64, error) {
+	return m.delete(func(s *adeia.Session) bool { return s.UserID == userID && s.ID != exceptID }), nil
+}
+
+func (m *memSessionRepo) DeleteAllWithoutMFAByRoleID(_ context.Context, roleID int) (int64, error) {
+	return m.delete(func(s *adeia.Session) bool {
+		for _, id := range m.withoutMFA[roleID] {
+			if s.UserID == id {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+func (m *memSessionRepo) DeleteByIDAndUserID(_ context.Context, id, userID int) (int64, error) {
+	return m.delete(func(s *adeia.Session) bool { return s.ID == id && s.UserID == userID }), nil
+}
+
+func (m *memSessionRepo) GetAllByUserID(_ context.Context, userID int) ([]*adeia.Session, error) {
+	var sessions []*adeia.Session
+	for _, s := range m.sessions {
+		if s.UserID == userID {
+			sessions = append(sessions, s)
+		}
+	}
+	return sessions, nil
+}
+
+func (m *memSessionRepo) GetByID(_ context.Context, id int) (*adeia.Session, error) {
+	for _, s := range m.sessions {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memSessionRepo) GetByRefreshToken(_ context.Context, refreshToken []byte) (*adeia.Session, error) {
+	for _, s := range m.sessions {
+		if bytes.Equal(s.RefreshToken, refreshToken) {
+			c := *s
+			if m.afterGet != nil {
+				m.afterGet()
+			}
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memSessionRepo) Insert(_ context.Context, s *adeia.Session) (int, error) {
+	c := *s
+	c.ID = len(m.sessions) + 1
+	m.sessions = append(m.sessions, &c)
+	return c.ID, nil
+}
+
+func (m *memSessionRepo) UpdateRefreshToken(_ context.Context, s *adeia.Session, refreshToken []byte, expires time.Time) (int64, error) {
+	stored, _ := m.GetByID(context.Background(), s.ID)
+	if stored == nil || !bytes.Equal(stored.RefreshToken, s.RefreshToken) {
+		return 0, nil
+	}
+	stored.RefreshToken, stored.RefreshTokenExpires = refreshToken, expires
+	s.RefreshToken, s.RefreshTokenExpires = refreshToken, expires
+	return 1, nil
+}
+
+// memTOTPRepo is an in-memory adeia.TOTPRepo.
+type memTOTPRepo struct {
+	totps         map[int]*adeia.UserTOTP
+	recoveryCodes map[int][][]byte
+}
+
+func newMemTOTPRepo() *memTOTPRepo {
+	return &memTOTPRepo{totps: map[int]*adeia.UserTOTP{}, recoveryCodes: map[int][][]byte{}}
+}
+
+func (m *memTOTPRepo) Confirm(_ context.Context, t *adeia.UserTOTP, step int64) error {
+	now := time.Now().UTC()
+	t.ConfirmedAt, t.LastUsedStep = &now, step
+	c := *t
+	m.totps[t.UserID] = &c
+	return nil
+}
+
+func (m *memTOTPRepo) DeleteByUserID(_ context.Context, userID int) error {
+	delete(m.totps, userID)
+	delete(m.recoveryCodes, userID)
+	return nil
+}
+
+func (m *memTOTPRepo) GetByUserID(_ context.Context, userID int) (*adeia.UserTOTP, error) {
+	if t, ok := m.totps[userID]; ok {
+		c := *t
+		return &c, nil
+	}
+	return nil, nil
+}
+
+func (m *memTOTPRepo) ReplaceRecoveryCodes(_ context.Context, userID int, hashes [][]byte) error {
+	m.recoveryCodes[userID] = hashes
+	return nil
+}
+
+func (m *memTOTPRepo) UpdateLastUsedStep(_ context.Context, t *adeia.UserTOTP, step int64) (int64, error) {
+	stored := m.totps[t.UserID]
+	if stored == nil || stored.LastUsedStep >= step {
+		return 0, nil
+	}
+	stored.LastUsedStep = step
+	return 1, nil
+}
+
+func (m *memTOTPRepo) Upsert(_ context.Context, t *adeia.UserTOTP) error {
+	c := *t
+	m.totps[t.UserID] = &c
+	return nil
+}
+
+func (m *memTOTPRepo) UseRecoveryCode(_ context.Context, userID int, hash []byte) (int64, error) {
+	codes := m.recoveryCodes[userID]
+	for i, h := range codes {
+		if string(h) == string(hash) {
+			m.recoveryCodes[userID] = append(codes[:i:i], codes[i+1:]...)
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// memPreferencesRepo is an in-memory adeia.UserPreferencesRepo.
+type memPreferencesRepo struct {
+	prefs map[int]*adeia.UserPreferences
+}
+
+func newMemPreferencesRepo() *memPreferencesRepo {
+	return &memPreferencesRepo{prefs: make(map[int]*adeia.UserPreferences)}
+}
+
+func (m *memPreferencesRepo) GetByUserID(_ context.Context, userID int) (*adeia.UserPreferences, error) {
+	return m.prefs[userID], nil
+}
+
+func (m *memPreferencesRepo) Upsert(_ context.Context, p *adeia.UserPreferences) error {
+	c := *p
+	m.prefs[p.UserID] = &c
+	return nil
+}
+
+// fakeMailer is a mailer.Mailer that records the messages that are sent.
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []*mailer.Message
+}
+
+func (f *fakeMailer) Send(msgs ...*mailer.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, msgs...)
+	return nil
+}
+
+// messages returns the messages that were sent so far.
+func (f *fakeMailer) messages() []*mailer.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*mailer.Message(nil), f.sent...)
+}
+
+// nopTransactor is a store.Transactor that runs fn without a transaction.
+type nopTransactor struct{}
+
+func (nopTransactor) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// memPendingNotificationRepo is an in-memory adeia.PendingNotificationRepo.
+type memPendingNotificationRepo struct {
+	pending []*adeia.PendingNotification
+}
+
+func (m *memPendingNotificationRepo) DeleteAll(context.Context) ([]*adeia.PendingNotification, error) {
+	pending := m.pending
+	m.pending = nil
+	return pending, nil
+}
+
+func (m *memPendingNotificationRepo) Insert(_ context.Context, n *adeia.PendingNotification) (int, error) {
+	m.pending = append(m.pending, n)
+	n.ID = len(m.pending)
+	return n.ID, nil
+}
@@ -0,0 +1,74 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package service
+
+import (
+	"context"
+	"strconv"
+
+	"adeia"
+	"adeia/internal/store"
+	"adeia/pkg/log"
+)
+
+// RoleService represents the Role service.
+type RoleService struct {
+	log         log.Logger
+	repo        adeia.RoleRepo
+	sessionRepo adeia.SessionRepo
+	audit       adeia.AuditService
+	tx          store.Transactor
+}
+
+// NewRoleService creates a new *RoleService.
+func NewRoleService(log log.Logger, repo adeia.RoleRepo, sessionRepo adeia.SessionRepo, audit adeia.AuditService, tx store.Transactor) *RoleService {
+	return &RoleService{log, repo, sessionRepo, audit, tx}
+}
+
+// logger returns the logger of the request in ctx, if any.
+func (rs *RoleService) logger(ctx context.Context) log.Logger {
+	return log.FromContext(ctx, rs.log)
+}
+
+// UpdateRole updates whether Users with the Role with the provided ID must use
+// two-factor authentication. When it is made mandatory, the Sessions of the Users
+// that have not enabled it are revoked, so that they must enrol when they log in
+// again.
+func (rs *RoleService) UpdateRole(ctx context.Context, id int, require2FA bool) (*adeia.Role, error) {
+	r, err := rs.repo.GetByID(ctx, id)
+	if err != nil {
+		rs.logger(ctx).Errorf("cannot fetch role by id: %v", err)
+		return nil, adeia.ErrDatabaseError
+	} else if r == nil {
+		rs.logger(ctx).Debugf("role does not exist with the provided id %d", id)
+		return nil, adeia.ErrResourceNotFound
+	}
+
+	before := *r
+	var revoked int64
+	if err := inTx(ctx, rs.logger(ctx), rs.tx, func(ctx context.Context) error {
+		if err := rs.repo.UpdateRequire2FA(ctx, r, require2FA); err != nil {
+			rs.logger(ctx).Warnf("cannot update role: %v", err)
+			return adeia.ErrDatabaseError
+		}
+		if !require2FA || before.Require2FA {
+			return nil
+		}
+
+		if revoked, err = rs.sessionRepo.DeleteAllWithoutMFAByRoleID(ctx, id); err != nil {
+			rs.logger(ctx).Warnf("cannot revoke sessions without mfa: %v", err)
+			return adeia.ErrDatabaseError
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if revoked > 0 {
+		rs.logger(ctx).Infof("revoked %d sessions of role %s, that did not use two-factor authentication", revoked, r.Name)
+	}
+	rs.audit.Record(ctx, adeia.AuditRoleUpdate, adeia.AuditTargetRole, strconv.Itoa(r.ID), &before, r)
+	return r, nil
+}
@@ -0,0 +1,92 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"adeia"
+	logzap "adeia/pkg/log/zap"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// memRoleRepo is an in-memory adeia.RoleRepo.
+type memRoleRepo struct {
+	roles map[int]*adeia.Role
+}
+
+func (m *memRoleRepo) GetByID(_ context.Context, id int) (*adeia.Role, error) {
+	if r, ok := m.roles[id]; ok {
+		c := *r
+		return &c, nil
+	}
+	return nil, nil
+}
+
+func (m *memRoleRepo) GetPermissions(context.Context, int) ([]string, error) {
+	return nil, nil
+}
+
+func (m *memRoleRepo) UpdateRequire2FA(_ context.Context, r *adeia.Role, require2FA bool) error {
+	r.Require2FA = require2FA
+	m.roles[r.ID].Require2FA = require2FA
+	return nil
+}
+
+func setupRoleService(t *testing.T, require2FA bool) (*RoleService, *memRoleRepo, *memSessionRepo, *memAuditRepo) {
+	t.Parallel()
+	roles := &memRoleRepo{roles: map[int]*adeia.Role{1: {ID: 1, Name: "admin", Require2FA: require2FA}}}
+	sessions := &memSessionRepo{
+		sessions:   []*adeia.Session{{ID: 1, UserID: 1}, {ID: 2, UserID: 2}, {ID: 3, UserID: 3}},
+		withoutMFA: map[int][]int{1: {1, 2}},
+	}
+	audit := &memAuditRepo{}
+	logger := &logzap.Logger{SugaredLogger: zap.NewNop().Sugar()}
+	rs := NewRoleService(logger, roles, sessions, NewAuditService(logger, audit), nopTransactor{})
+	return rs, roles, sessions, audit
+}
+
+func TestRoleService_UpdateRole(t *testing.T) {
+	t.Run("revoke sessions without mfa when 2fa is made mandatory", func(t *testing.T) {
+		rs, roles, sessions, audit := setupRoleService(t, false)
+
+		r, err := rs.UpdateRole(adminCtx(), 1, true)
+		assert.Nil(t, err)
+		assert.True(t, r.Require2FA)
+		assert.True(t, roles.roles[1].Require2FA)
+		assert.Equal(t, []*adeia.Session{{ID: 3, UserID: 3}}, sessions.sessions)
+		assert.Len(t, audit.events, 1)
+	})
+
+	t.Run("keep sessions when 2fa was already mandatory", func(t *testing.T) {
+		rs, _, sessions, _ := setupRoleService(t, true)
+
+		_, err := rs.UpdateRole(adminCtx(), 1, true)
+		assert.Nil(t, err)
+		assert.Len(t, sessions.sessions, 3)
+	})
+
+	t.Run("keep sessions when 2fa is made optional", func(t *testing.T) {
+		rs, roles, sessions, _ := setupRoleService(t, true)
+
+		r, err := rs.UpdateRole(adminCtx(), 1, false)
+		assert.Nil(t, err)
+		assert.False(t, r.Require2FA)
+		assert.False(t, roles.roles[1].Require2FA)
+		assert.Len(t, sessions.sessions, 3)
+	})
+
+	t.Run("return not found for a missing role", func(t *testing.T) {
+		rs, _, sessions, audit := setupRoleService(t, false)
+
+		_, err := rs.UpdateRole(adminCtx(), 42, true)
+		assert.Equal(t, adeia.ErrResourceNotFound, err)
+		assert.Len(t, sessions.sessions, 3)
+		assert.Empty(t, audit.events)
+	})
+}
@@ -0,0 +1,344 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"adeia"
+	"adeia/pkg/errs"
+	"adeia/pkg/totp"
+	"adeia/pkg/util/constants"
+	"adeia/pkg/util/crypto"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// mfaClaims are the claims of an MFA token, which is a partial token that can only
+// be used for the second step of login.
+type mfaClaims struct {
+	// Step is the second step of login that the token is for.
+	Step string `json:"mfa"`
+	jwt.StandardClaims
+}
+
+// BeginTOTPEnrolment generates a new TOTP secret for the User. Two-factor
+// authentication is enabled only after the enrolment is confirmed with a code.
+func (as *AuthService) BeginTOTPEnrolment(ctx context.Context, u *adeia.User) (*adeia.TOTPEnrolment, error) {
+	t, err := as.totpRepo.GetByUserID(ctx, u.ID)
+	if err != nil {
+		as.logger(ctx).Errorf("cannot fetch totp: %v", err)
+		return nil, adeia.ErrDatabaseError
+	} else if t != nil && t.ConfirmedAt != nil {
+		as.logger(ctx).Debug("two-factor authentication is already enabled for user " + u.EmployeeID)
+		return nil, adeia.ErrResourceAlreadyExists
+	}
+
+	secret, err := crypto.GenerateRandomBytes(constants.TOTPSecretLength)
+	if err != nil {
+		as.logger(ctx).Errorf("cannot generate totp secret: %v", err)
+		return nil, adeia.ErrInternalError
+	}
+	if err := as.totpRepo.Upsert(ctx, &adeia.UserTOTP{UserID: u.ID, Secret: secret}); err != nil {
+		as.logger(ctx).Warnf("cannot save totp: %v", err)
+		return nil, adeia.ErrDatabaseError
+	}
+
+	return &adeia.TOTPEnrolment{
+		Secret: totp.EncodeSecret(secret),
+		URI:    totp.URI(constants.TOTPIssuer, u.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTPEnrolment enables two-factor authentication for the User, after
+// verifying a code from their authenticator app. New recovery codes are generated,
+// and returned.
+func (as *AuthService) ConfirmTOTPEnrolment(ctx context.Context, u *adeia.User, code string) (*adeia.TOTPConfirmation, error) {
+	t, err := as.totpRepo.GetByUserID(ctx, u.ID)
+	if err != nil {
+		as.logger(ctx).Errorf("cannot fetch totp: %v", err)
+		return nil, adeia.ErrDatabaseError
+	} else if t == nil {
+		as.logger(ctx).Debug("totp enrolment has not begun for user " + u.EmployeeID)
+		return nil, adeia.ErrResourceNotFound
+	} else if t.ConfirmedAt != nil {
+		as.logger(ctx).Debug("two-factor authentication is already enabled for user " + u.EmployeeID)
+		return nil, adeia.ErrResourceAlreadyExists
+	}
+
+	step, ok := totp.Validate(t.Secret, code, time.Now())
+	if !ok {
+		return nil, adeia.ErrInvalidOTP
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		as.logger(ctx).Errorf("cannot generate recovery codes: %v", err)
+		return nil, adeia.ErrInternalError
+	}
+
+	// the recovery codes are saved along with the confirmation, so that 2FA is never
+	// enabled without a way to recover from losing the authenticator
+	if err := inTx(ctx, as.logger(ctx), as.tx, func(ctx context.Context) error {
+		if err := as.totpRepo.Confirm(ctx, t, step); err != nil {
+			as.logger(ctx).Warnf("cannot confirm totp: %v", err)
+			return adeia.ErrDatabaseError
+		}
+		if err := as.totpRepo.ReplaceRecoveryCodes(ctx, u.ID, hashes); err != nil {
+			as.logger(ctx).Warnf("cannot save recovery codes: %v", err)
+			return adeia.ErrDatabaseError
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return &adeia.TOTPConfirmation{RecoveryCodes: codes}, nil
+}
+
+// DisableTOTP disables two-factor authentication for the User, after verifying a
+// TOTP or recovery code. It cannot be disabled if the Role of the User requires it.
+func (as *AuthService) DisableTOTP(ctx context.Context, u *adeia.User, code string) error {
+	if required, err := as.requiresMFA(ctx, u); err != nil {
+		return err
+	} else if required {
+		return adeia.ErrMFAMandatory
+	}
+
+	t, err := as.totpRepo.GetByUserID(ctx, u.ID)
+	if err != nil {
+		as.logger(ctx).Errorf("cannot fetch totp: %v", err)
+		return adeia.ErrDatabaseError
+	} else if t == nil || t.ConfirmedAt == nil {
+		as.logger(ctx).Debug("two-factor authentication is not enabled for user " + u.EmployeeID)
+		return adeia.ErrResourceNotFound
+	}
+
+	if err := as.verifyCode(ctx, t, code); err != nil {
+		return err
+	}
+	// the recovery codes and the secret are deleted together
+	return inTx(ctx, as.logger(ctx), as.tx, func(ctx context.Context) error {
+		if err := as.totpRepo.DeleteByUserID(ctx, u.ID); err != nil {
+			as.logger(ctx).Warnf("cannot delete totp: %v", err)
+			return adeia.ErrDatabaseError
+		}
+		return nil
+	})
+}
+
+// VerifyLogin completes the login of a User with two-factor authentication, by
+// verifying a TOTP or recovery code. A new Session is created for the User.
+// Incorrect codes count as failed logins, so that codes cannot be brute-forced.
+func (as *AuthService) VerifyLogin(ctx context.Context, mfaToken, code, ip, userAgent string) (*adeia.AuthTokens, error) {
+	u, err := as.mfaUser(ctx, mfaToken, adeia.MFAStepVerify)
+	if err != nil {
+		return nil, err
+	}
+	if err := as.checkLoginThrottle(ctx, u.Email, ip); err != nil {
+		return nil, err
+	}
+
+	t, err := as.totpRepo.GetByUserID(ctx, u.ID)
+	if err != nil {
+		as.logger(ctx).Errorf("cannot fetch totp: %v", err)
+		return nil, adeia.ErrDatabaseError
+	} else if t == nil || t.ConfirmedAt == nil {
+		// two-factor authentication was disabled after the MFA token was issued
+		as.logger(ctx).Debug("two-factor authentication is not enabled for user " + u.EmployeeID)
+		return nil, adeia.ErrInvalidToken
+	}
+
+	if err := as.verifyCode(ctx, t, code); err != nil {
+		if re, ok := err.(errs.ResponseError); ok && re.ErrorCode == adeia.ErrInvalidOTP.ErrorCode {
+			as.recordLoginFailure(ctx, u, u.Email, ip)
+		}
+		return nil, err
+	}
+	return as.newSession(ctx, u, ip, userAgent)
+}
+
+// BeginLoginTOTPEnrolment is the same as BeginTOTPEnrolment, but for Users that
+// must enrol in two-factor authentication before they can log in.
+func (as *AuthService) BeginLoginTOTPEnrolment(ctx context.Context, mfaToken string) (*adeia.TOTPEnrolment, error) {
+	u, err := as.mfaUser(ctx, mfaToken, adeia.MFAStepEnrol)
+	if err != nil {
+		return nil, err
+	}
+	return as.BeginTOTPEnrolment(ctx, u)
+}
+
+// ConfirmLoginTOTPEnrolment is the same as ConfirmTOTPEnrolment, but for Users that
+// must enrol in two-factor authentication before they can log in. A new Session is
+// created for the User, once the enrolment is confirmed.
+func (as *AuthService) ConfirmLoginTOTPEnrolment(ctx context.Context, mfaToken, code, ip, userAgent string) (*adeia.TOTPConfirmation, error) {
+	u, err := as.mfaUser(ctx, mfaToken, adeia.MFAStepEnrol)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := as.ConfirmTOTPEnrolment(ctx, u, code)
+	if err != nil {
+		return nil, err
+	}
+	if c.Tokens, err = as.newSession(ctx, u, ip, userAgent); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// verifyCode verifies a TOTP code, or a recovery code, of the User. Each code can
+// only be used once.
+func (as *AuthService) verifyCode(ctx context.Context, t *adeia.UserTOTP, code string) error {
+	if step, ok := totp.Validate(t.Secret, code, time.Now()); ok {
+		rowsAffected, err := as.totpRepo.UpdateLastUsedStep(ctx, t, step)
+		if err != nil {
+			as.logger(ctx).Warnf("cannot update last used step: %v", err)
+			return adeia.ErrDatabaseError
+		} else if rowsAffected == 0 {
+			as.logger(ctx).Debugf("totp code has already been used by user %d", t.UserID)
+			return adeia.ErrInvalidOTP
+		}
+		return nil
+	}
+
+	rowsAffected, err := as.totpRepo.UseRecoveryCode(ctx, t.UserID, hashRecoveryCode(code))
+	if err != nil {
+		as.logger(ctx).Warnf("cannot use recovery code: %v", err)
+		return adeia.ErrDatabaseError
+	} else if rowsAffected == 0 {
+		as.logger(ctx).Debugf("invalid code provided by user %d", t.UserID)
+		return adeia.ErrInvalidOTP
+	}
+	return nil
+}
+
+// mfaStep returns the second step of login that the User must complete, or an
+// empty string if there is none.
+func (as *AuthService) mfaStep(ctx context.Context, u *adeia.User) (string, error) {
+	t, err := as.totpRepo.GetByUserID(ctx, u.ID)
+	if err != nil {
+		as.logger(ctx).Errorf("cannot fetch totp: %v", err)
+		return "", adeia.ErrDatabaseError
+	} else if t != nil && t.ConfirmedAt != nil {
+		return adeia.MFAStepVerify, nil
+	}
+
+	if required, err := as.requiresMFA(ctx, u); err != nil {
+		return "", err
+	} else if required {
+		return adeia.MFAStepEnrol, nil
+	}
+	return "", nil
+}
+
+// requiresMFA checks if the Role of the User requires two-factor authentication.
+func (as *AuthService) requiresMFA(ctx context.Context, u *adeia.User) (bool, error) {
+	if u.RoleID == nil {
+		return false, nil
+	}
+
+	r, err := as.roleRepo.GetByID(ctx, *u.RoleID)
+	if err != nil {
+		as.logger(ctx).Errorf("cannot fetch role by id: %v", err)
+		return false, adeia.ErrDatabaseError
+	}
+	return r != nil && r.Require2FA, nil
+}
+
+// mfaUser returns the User that the MFA token was issued to, if the token is for
+// the step.
+func (as *AuthService) mfaUser(ctx context.Context, mfaToken, step string) (*adeia.User, error) {
+	claims, err := as.parseMFAToken(mfaToken)
+	if err != nil {
+		as.logger(ctx).Debugf("invalid mfa token: %v", err)
+		return nil, adeia.ErrInvalidToken
+	} else if claims.Step != step {
+		as.logger(ctx).Debugf("mfa token is for step %q, not %q", claims.Step, step)
+		return nil, adeia.ErrInvalidToken
+	}
+
+	u, err := as.userRepo.GetByEmpID(ctx, claims.Subject)
+	if err != nil {
+		as.logger(ctx).Errorf("cannot fetch user by employee id: %v", err)
+		return nil, adeia.ErrDatabaseError
+	} else if u == nil || !u.IsActivated {
+		as.logger(ctx).Debug("user is deleted or deactivated: " + claims.Subject)
+		return nil, adeia.ErrInvalidToken
+	}
+	return u, nil
+}
+
+// issueMFAToken issues an MFA token for the step, instead of the access and
+// refresh tokens.
+func (as *AuthService) issueMFAToken(u *adeia.User, step string) (*adeia.AuthTokens, error) {
+	token, err := as.newMFAToken(u, step)
+	if err != nil {
+		as.log.Errorf("cannot sign mfa token: %v", err)
+		return nil, adeia.ErrInternalError
+	}
+
+	return &adeia.AuthTokens{
+		ExpiresIn: constants.MFATokenExpiry,
+		MFAToken:  token,
+		MFAStep:   step,
+	}, nil
+}
+
+// newMFAToken creates a signed JWT, with the employee ID of the User as the
+// subject. Its audience is different from that of access tokens, so that it
+// cannot be used as one.
+func (as *AuthService) newMFAToken(u *adeia.User, step string) (string, error) {
+	now := time.Now()
+	claims := mfaClaims{
+		Step: step,
+		StandardClaims: jwt.StandardClaims{
+			Audience:  mfaTokenAudience,
+			Subject:   u.EmployeeID,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(constants.MFATokenExpiry * time.Second).Unix(),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(as.jwtSecret)
+}
+
+// parseMFAToken verifies the signature, expiry and audience of the MFA token, and
+// returns its claims.
+func (as *AuthService) parseMFAToken(mfaToken string) (*mfaClaims, error) {
+	claims := &mfaClaims{}
+	if err := as.parseToken(mfaToken, claims); err != nil {
+		return nil, err
+	}
+	if !claims.VerifyAudience(mfaTokenAudience, true) {
+		return nil, fmt.Errorf("unexpected audience: %v", claims.Audience)
+	}
+	return claims, nil
+}
+
+// newRecoveryCodes generates random recovery codes (like "1a2b3-c4d5e"), along
+// with their hashes.
+func newRecoveryCodes() (codes []string, hashes [][]byte, err error) {
+	for i := 0; i < constants.RecoveryCodeCount; i++ {
+		b, err := crypto.GenerateRandomBytes(constants.RecoveryCodeLength)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		code := crypto.EncodeHex(b)
+		codes = append(codes, code[:len(code)/2]+"-"+code[len(code)/2:])
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode returns the hash of the recovery code, ignoring its case and
+// separators.
+func hashRecoveryCode(code string) []byte {
+	code = strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+	return crypto.Hash([]byte(code))
+}
@@ -0,0 +1,174 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"adeia"
+	"adeia/pkg/totp"
+	"adeia/pkg/util/crypto"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingTransactor is a store.Transactor that counts the transactions it runs.
+type countingTransactor struct {
+	n int
+}
+
+func (c *countingTransactor) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	c.n++
+	return fn(ctx)
+}
+
+var testTOTPSecret = []byte("12345678901234567890")
+
+// setupTOTP returns an AuthService, with an activated User that has enabled
+// two-factor authentication, along with the recovery code "aaaa-bbbb".
+func setupTOTP(t *testing.T) (*AuthService, *adeia.User) {
+	as, _, c := setupThrottle(t)
+	t.Cleanup(c)
+
+	hash, _ := crypto.HashPassword("correct horse battery staple")
+	u := &adeia.User{ID: 1, EmployeeID: "FOO123", Email: "foo@example.com", Password: hash, IsActivated: true}
+	as.userRepo = &memUserRepo{users: []*adeia.User{u}}
+	as.sessionRepo = &memSessionRepo{}
+	as.roleRepo = &memRoleRepo{roles: map[int]*adeia.Role{}}
+	as.jwtSecret = []byte("secret")
+	as.tx = nopTransactor{}
+
+	repo := newMemTOTPRepo()
+	_ = repo.Confirm(context.Background(), &adeia.UserTOTP{UserID: u.ID, Secret: testTOTPSecret}, 0)
+	_ = repo.ReplaceRecoveryCodes(context.Background(), u.ID, [][]byte{hashRecoveryCode("aaaa-bbbb")})
+	as.totpRepo = repo
+	return as, u
+}
+
+func currentCode() string {
+	return totp.Code(testTOTPSecret, totp.Step(time.Now()))
+}
+
+func TestAuthService_VerifyCode(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("accept a totp code only once", func(t *testing.T) {
+		as, u := setupTOTP(t)
+		stored, _ := as.totpRepo.GetByUserID(ctx, u.ID)
+		code := currentCode()
+
+		assert.Nil(t, as.verifyCode(ctx, stored, code))
+		assert.Equal(t, adeia.ErrInvalidOTP, as.verifyCode(ctx, stored, code))
+	})
+
+	t.Run("accept a recovery code only once, ignoring case and separators", func(t *testing.T) {
+		as, u := setupTOTP(t)
+		stored, _ := as.totpRepo.GetByUserID(ctx, u.ID)
+
+		assert.Nil(t, as.verifyCode(ctx, stored, "AAAA BBBB"))
+		assert.Equal(t, adeia.ErrInvalidOTP, as.verifyCode(ctx, stored, "aaaa-bbbb"))
+	})
+
+	t.Run("reject an incorrect code", func(t *testing.T) {
+		as, u := setupTOTP(t)
+		stored, _ := as.totpRepo.GetByUserID(ctx, u.ID)
+
+		assert.Equal(t, adeia.ErrInvalidOTP, as.verifyCode(ctx, stored, "not-a-code"))
+	})
+}
+
+func TestAuthService_VerifyLogin(t *testing.T) {
+	const ip = "10.0.0.1"
+	ctx := context.Background()
+
+	t.Run("reject a code that was already used to log in", func(t *testing.T) {
+		as, u := setupTOTP(t)
+		code := currentCode()
+
+		tokens, _ := as.Login(ctx, u.Email, "correct horse battery staple", ip, "")
+		got, err := as.VerifyLogin(ctx, tokens.MFAToken, code, ip, "")
+		assert.Nil(t, err)
+		assert.NotEmpty(t, got.AccessToken)
+
+		tokens, _ = as.Login(ctx, u.Email, "correct horse battery staple", ip, "")
+		_, err = as.VerifyLogin(ctx, tokens.MFAToken, code, ip, "")
+		assert.Equal(t, adeia.ErrInvalidOTP, err)
+		assert.Len(t, as.sessionRepo.(*memSessionRepo).sessions, 1)
+	})
+
+	t.Run("reject an access token as the mfa token", func(t *testing.T) {
+		as, u := setupTOTP(t)
+		tokens, _ := as.Login(ctx, u.Email, "correct horse battery staple", ip, "")
+		got, _ := as.VerifyLogin(ctx, tokens.MFAToken, currentCode(), ip, "")
+
+		_, err := as.VerifyLogin(ctx, got.AccessToken, currentCode(), ip, "")
+		assert.Equal(t, adeia.ErrInvalidToken, err)
+	})
+}
+
+func TestAuthService_DisableTOTP(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("disable with a valid code", func(t *testing.T) {
+		as, u := setupTOTP(t)
+
+		assert.Nil(t, as.DisableTOTP(ctx, u, currentCode()))
+		stored, _ := as.totpRepo.GetByUserID(ctx, u.ID)
+		assert.Nil(t, stored)
+		assert.Empty(t, as.totpRepo.(*memTOTPRepo).recoveryCodes[u.ID])
+	})
+
+	t.Run("reject a code that was already used", func(t *testing.T) {
+		as, u := setupTOTP(t)
+		code := currentCode()
+		stored, _ := as.totpRepo.GetByUserID(ctx, u.ID)
+		_ = as.verifyCode(ctx, stored, code)
+
+		assert.Equal(t, adeia.ErrInvalidOTP, as.DisableTOTP(ctx, u, code))
+		stored, _ = as.totpRepo.GetByUserID(ctx, u.ID)
+		assert.NotNil(t, stored)
+	})
+
+	t.Run("reject when the role requires 2fa", func(t *testing.T) {
+		as, u := setupTOTP(t)
+		roleID := 1
+		u.RoleID = &roleID
+		as.roleRepo = &memRoleRepo{roles: map[int]*adeia.Role{1: {ID: 1, Require2FA: true}}}
+
+		assert.Equal(t, adeia.ErrMFAMandatory, as.DisableTOTP(ctx, u, currentCode()))
+		stored, _ := as.totpRepo.GetByUserID(ctx, u.ID)
+		assert.NotNil(t, stored)
+	})
+}
+
+func TestAuthService_ConfirmTOTPEnrolment(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("confirm and save the recovery codes in one transaction", func(t *testing.T) {
+		as, u := setupTOTP(t)
+		tx := &countingTransactor{}
+		as.tx = tx
+		_ = as.totpRepo.DeleteByUserID(ctx, u.ID)
+		_ = as.totpRepo.Upsert(ctx, &adeia.UserTOTP{UserID: u.ID, Secret: testTOTPSecret})
+
+		c, err := as.ConfirmTOTPEnrolment(ctx, u, currentCode())
+		assert.Nil(t, err)
+		assert.Len(t, c.RecoveryCodes, len(as.totpRepo.(*memTOTPRepo).recoveryCodes[u.ID]))
+		assert.NotEmpty(t, c.RecoveryCodes)
+		assert.Equal(t, 1, tx.n)
+
+		stored, _ := as.totpRepo.GetByUserID(ctx, u.ID)
+		assert.NotNil(t, stored.ConfirmedAt)
+	})
+
+	t.Run("reject when 2fa is already enabled", func(t *testing.T) {
+		as, u := setupTOTP(t)
+
+		_, err := as.ConfirmTOTPEnrolment(ctx, u, currentCode())
+		assert.Equal(t, adeia.ErrResourceAlreadyExists, err)
+	})
+}
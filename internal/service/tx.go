@@ -0,0 +1,26 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package service
+
+import (
+	"context"
+
+	"adeia"
+	"adeia/internal/store"
+	"adeia/pkg/errs"
+	"adeia/pkg/log"
+)
+
+// inTx runs fn in a transaction, using tx. fn returns the errors of the service,
+// but beginning or committing the transaction can fail with other errors; those
+// are logged, and reported as ErrDatabaseError.
+func inTx(ctx context.Context, logger log.Logger, tx store.Transactor, fn func(ctx context.Context) error) error {
+	err := tx.InTx(ctx, fn)
+	if _, ok := err.(errs.ResponseError); err != nil && !ok {
+		logger.Errorf("transaction failed: %v", err)
+		return adeia.ErrDatabaseError
+	}
+	return err
+}
@@ -0,0 +1,48 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"adeia"
+	logzap "adeia/pkg/log/zap"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// failingTransactor is a store.Transactor that runs fn, but fails to commit.
+type failingTransactor struct{}
+
+func (failingTransactor) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := fn(ctx); err != nil {
+		return err
+	}
+	return errors.New("commit failed")
+}
+
+func TestInTx(t *testing.T) {
+	logger := &logzap.Logger{SugaredLogger: zap.NewNop().Sugar()}
+	ok := func(context.Context) error { return nil }
+
+	t.Run("return nil on success", func(t *testing.T) {
+		assert.Nil(t, inTx(context.Background(), logger, nopTransactor{}, ok))
+	})
+
+	t.Run("return the error of fn as-is", func(t *testing.T) {
+		err := inTx(context.Background(), logger, failingTransactor{}, func(context.Context) error {
+			return adeia.ErrResourceNotFound
+		})
+		assert.Equal(t, adeia.ErrResourceNotFound, err)
+	})
+
+	t.Run("report failure to commit as a database error", func(t *testing.T) {
+		err := inTx(context.Background(), logger, failingTransactor{}, ok)
+		assert.Equal(t, adeia.ErrDatabaseError, err)
+	})
+}
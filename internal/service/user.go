@@ -8,29 +8,69 @@ import (
 	"context"
 
 	"adeia"
+	"adeia/internal/cache"
+	"adeia/internal/metrics"
+	"adeia/internal/notifier"
+	"adeia/internal/store"
 	"adeia/pkg/log"
+	"adeia/pkg/query"
 	"adeia/pkg/util/crypto"
+	"adeia/pkg/validation"
 )
 
+const activationKeyPrefix = "activation:"
+
 // UserService represents the User service.
 type UserService struct {
-	log  log.Logger
-	repo adeia.UserRepo
+	log       log.Logger
+	repo      adeia.UserRepo
+	prefsRepo adeia.UserPreferencesRepo
+	cache     cache.Cache
+	notifier  *notifier.Notifier
+	audit     adeia.AuditService
+	tx        store.Transactor
 }
 
 // NewUserService creates a new *UserService.
-func NewUserService(log log.Logger, repo adeia.UserRepo) *UserService {
-	return &UserService{log, repo}
+func NewUserService(
+	log log.Logger,
+	repo adeia.UserRepo,
+	prefsRepo adeia.UserPreferencesRepo,
+	cache cache.Cache,
+	notifier *notifier.Notifier,
+	audit adeia.AuditService,
+	tx store.Transactor,
+) *UserService {
+	return &UserService{log, repo, prefsRepo, cache, notifier, audit, tx}
 }
 
-// CreateUser creates a new user if does not exist.
-func (us *UserService) CreateUser(ctx context.Context, name, email, empID, designation string) (*adeia.User, error) {
-	if u, err := us.repo.GetByEmail(ctx, email); err != nil {
-		us.log.Errorf("cannot fetch user by email: %v", err)
-		return nil, adeia.ErrDatabaseError
-	} else if u != nil {
-		us.log.Debug("user already exists with the provided email " + email)
-		return nil, adeia.ErrResourceAlreadyExists
+// logger returns the logger of the request in ctx, if any.
+func (us *UserService) logger(ctx context.Context) log.Logger {
+	return log.FromContext(ctx, us.log)
+}
+
+// userFields represents the fields of a new User, along with their validation rules.
+type userFields struct {
+	Name        string `json:"name" validate:"required,max=255"`
+	Email       string `json:"email" validate:"required,email,max=120"`
+	EmployeeID  string `json:"employee_id" validate:"alnum,max=16"`
+	Designation string `json:"designation" validate:"required,max=255"`
+	Department  string `json:"department" validate:"max=255"`
+}
+
+// validateUser validates the fields of a new User, and returns the validation
+// errors of the failing fields. The rules of userFields are checked by its tests,
+// so the error is ignored.
+func validateUser(name, email, empID, designation, department string) validation.Errors {
+	errs, _ := validation.Struct(&userFields{name, email, empID, designation, department})
+	return errs
+}
+
+// CreateUser creates a new user if does not exist, and sends them an activation
+// email once it is created.
+func (us *UserService) CreateUser(ctx context.Context, name, email, empID, designation, department string) (*adeia.User, error) {
+	if errs := validateUser(name, email, empID, designation, department); len(errs) > 0 {
+		return nil, adeia.ErrValidationFailed.ValidationErr(errs)
 	}
 
 	if empID == "" {
@@ -40,12 +80,199 @@ func (us *UserService) CreateUser(ctx context.Context, name, email, empID, desig
 		adeia.WithName(name),
 		adeia.WithEmail(email),
 		adeia.WithDesignation(designation),
+		adeia.WithDepartment(department),
 		adeia.WithEmpID(empID),
 	)
 
-	if _, err := us.repo.Insert(ctx, user); err != nil {
-		us.log.Warnf("cannot create new user: %v", err)
-		return nil, adeia.ErrDatabaseError
+	if err := inTx(ctx, us.logger(ctx), us.tx, func(ctx context.Context) error {
+		if u, err := us.repo.GetByEmail(ctx, email); err != nil {
+			us.logger(ctx).Errorf("cannot fetch user by email: %v", err)
+			return adeia.ErrDatabaseError
+		} else if u != nil {
+			us.logger(ctx).Debug("user already exists with the provided email " + email)
+			return adeia.ErrResourceAlreadyExists
+		}
+
+		if u, err := us.repo.GetByEmpID(ctx, empID); err != nil {
+			us.logger(ctx).Errorf("cannot fetch user by employee id: %v", err)
+			return adeia.ErrDatabaseError
+		} else if u != nil {
+			us.logger(ctx).Debug("user already exists with the provided employee id " + empID)
+			return adeia.ErrResourceAlreadyExists
+		}
+
+		return us.insertUser(ctx, user)
+	}); err != nil {
+		return nil, err
 	}
+	us.userCreated(ctx, user)
+	us.sendActivationEmails(ctx, []*adeia.User{user})
 	return user, nil
 }
+
+// insertUser inserts the User, along with its Preferences. It must be run in a
+// transaction, so that the User is not left without its Preferences.
+func (us *UserService) insertUser(ctx context.Context, u *adeia.User) error {
+	id, err := us.repo.Insert(ctx, u)
+	if err != nil {
+		us.logger(ctx).Warnf("cannot create new user: %v", err)
+		return adeia.ErrDatabaseError
+	}
+	u.ID = id
+
+	u.Preferences.UserID = id
+	if err := us.prefsRepo.Upsert(ctx, u.Preferences); err != nil {
+		us.logger(ctx).Warnf("cannot save user preferences: %v", err)
+		return adeia.ErrDatabaseError
+	}
+	return nil
+}
+
+// userCreated records the creation of the User, once the transaction that
+// inserted it is committed.
+func (us *UserService) userCreated(ctx context.Context, u *adeia.User) {
+	metrics.UsersCreated.Inc()
+	us.audit.Record(ctx, adeia.AuditUserCreate, adeia.AuditTargetUser, u.EmployeeID, nil, u)
+}
+
+// GetUserByEmpID returns the User with the provided employee ID.
+func (us *UserService) GetUserByEmpID(ctx context.Context, empID string) (*adeia.User, error) {
+	u, err := us.repo.GetByEmpID(ctx, empID)
+	if err != nil {
+		us.logger(ctx).Errorf("cannot fetch user by employee id: %v", err)
+		return nil, adeia.ErrDatabaseError
+	} else if u == nil {
+		us.logger(ctx).Debug("user does not exist with the provided employee id " + empID)
+		return nil, adeia.ErrResourceNotFound
+	}
+	return u, nil
+}
+
+// GetAllUsers returns a page of Users, as specified by spec, along with the cursor
+// to the next page. Deleted Users are returned only when inclDeleted is true.
+func (us *UserService) GetAllUsers(ctx context.Context, spec *query.Spec, inclDeleted bool) ([]*adeia.User, string, error) {
+	getAll := us.repo.GetAll
+	if inclDeleted {
+		getAll = us.repo.GetAllInclDeleted
+	}
+
+	users, nextCursor, err := getAll(ctx, spec)
+	if err != nil {
+		us.logger(ctx).Errorf("cannot fetch users: %v", err)
+		return nil, "", adeia.ErrDatabaseError
+	} else if users == nil {
+		users = []*adeia.User{}
+	}
+	return users, nextCursor, nil
+}
+
+// UpdateUser updates the profile fields of the User with the provided employee ID.
+// Fields that are empty are left unchanged.
+func (us *UserService) UpdateUser(ctx context.Context, empID, name, designation, department string) (*adeia.User, error) {
+	u, err := us.GetUserByEmpID(ctx, empID)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		name = u.Name
+	}
+	if designation == "" {
+		designation = u.Designation
+	}
+	if department == "" {
+		department = u.Department
+	}
+
+	before := *u
+	if err := us.repo.UpdateProfile(ctx, u, name, designation, department); err != nil {
+		us.logger(ctx).Warnf("cannot update user: %v", err)
+		return nil, adeia.ErrDatabaseError
+	}
+
+	us.audit.Record(ctx, adeia.AuditUserUpdate, adeia.AuditTargetUser, u.EmployeeID, &before, u)
+	return u, nil
+}
+
+// DeactivateUser deactivates the User with the provided employee ID, so that
+// the User can no longer access the system.
+func (us *UserService) DeactivateUser(ctx context.Context, empID string) (*adeia.User, error) {
+	u, err := us.GetUserByEmpID(ctx, empID)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *u
+	if err := us.repo.Deactivate(ctx, u); err != nil {
+		us.logger(ctx).Warnf("cannot deactivate user: %v", err)
+		return nil, adeia.ErrDatabaseError
+	}
+
+	us.audit.Record(ctx, adeia.AuditUserDeactivate, adeia.AuditTargetUser, u.EmployeeID, &before, u)
+	return u, nil
+}
+
+// DeleteUser soft-deletes the User with the provided employee ID. The User can be
+// restored later using RestoreUser.
+func (us *UserService) DeleteUser(ctx context.Context, empID string) error {
+	// fetched for the audit log
+	u, err := us.GetUserByEmpID(ctx, empID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := us.repo.DeleteByEmpID(ctx, empID)
+	if err != nil {
+		us.logger(ctx).Warnf("cannot delete user: %v", err)
+		return adeia.ErrDatabaseError
+	} else if rowsAffected == 0 {
+		us.logger(ctx).Debug("user does not exist with the provided employee id " + empID)
+		return adeia.ErrResourceNotFound
+	}
+
+	us.audit.Record(ctx, adeia.AuditUserDelete, adeia.AuditTargetUser, u.EmployeeID, u, nil)
+	return nil
+}
+
+// RestoreUser restores the soft-deleted User with the provided employee ID. A User
+// cannot be restored if an active User has since taken the same employee ID or email.
+func (us *UserService) RestoreUser(ctx context.Context, empID string) (*adeia.User, error) {
+	u, err := us.repo.GetDeletedByEmpID(ctx, empID)
+	if err != nil {
+		us.logger(ctx).Errorf("cannot fetch deleted user by employee id: %v", err)
+		return nil, adeia.ErrDatabaseError
+	} else if u == nil {
+		us.logger(ctx).Debug("deleted user does not exist with the provided employee id " + empID)
+		return nil, adeia.ErrResourceNotFound
+	}
+
+	before := *u
+	if err := inTx(ctx, us.logger(ctx), us.tx, func(ctx context.Context) error {
+		if existing, err := us.repo.GetByEmpID(ctx, u.EmployeeID); err != nil {
+			us.logger(ctx).Errorf("cannot fetch user by employee id: %v", err)
+			return adeia.ErrDatabaseError
+		} else if existing != nil {
+			us.logger(ctx).Debug("active user already exists with the employee id " + u.EmployeeID)
+			return adeia.ErrResourceConflict
+		}
+
+		if existing, err := us.repo.GetByEmail(ctx, u.Email); err != nil {
+			us.logger(ctx).Errorf("cannot fetch user by email: %v", err)
+			return adeia.ErrDatabaseError
+		} else if existing != nil {
+			us.logger(ctx).Debug("active user already exists with the email " + u.Email)
+			return adeia.ErrResourceConflict
+		}
+
+		if err := us.repo.Restore(ctx, u); err != nil {
+			us.logger(ctx).Warnf("cannot restore user: %v", err)
+			return adeia.ErrDatabaseError
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	us.audit.Record(ctx, adeia.AuditUserRestore, adeia.AuditTargetUser, u.EmployeeID, &before, u)
+	return u, nil
+}
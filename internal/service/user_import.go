@@ -0,0 +1,234 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"adeia"
+	"adeia/internal/notifier"
+	"adeia/pkg/util/constants"
+	"adeia/pkg/util/crypto"
+)
+
+// importColumns is the list of columns that a CSV file of Users must have. The
+// department column is optional, like the department of a new User.
+var importColumns = []string{"name", "email", "employee_id", "designation"}
+
+// ImportUsers creates Users in bulk from a CSV file, that has a header row with
+// the importColumns (in any order). Every row is validated using the same rules as
+// CreateUser, and a per-row report is returned. The Users are created in a single
+// transaction, so nothing is created if any of them cannot be, and nothing is
+// created when dryRun is true. Activation emails are sent to the created Users
+// before returning.
+func (us *UserService) ImportUsers(ctx context.Context, r io.Reader, dryRun bool) (*adeia.UserImportReport, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, adeia.ErrValidationFailed.AddValidationErr("file", "Please upload a valid CSV file")
+	}
+	cols, err := mapColumns(header)
+	if err != nil {
+		us.logger(ctx).Debugf("invalid csv header: %v", err)
+		return nil, adeia.ErrValidationFailed.AddValidationErr("file", "Please upload a CSV file with the columns: "+
+			strings.Join(importColumns, ", "))
+	}
+
+	report := &adeia.UserImportReport{DryRun: dryRun, Rows: []*adeia.UserImportRow{}}
+	var created []*adeia.User
+	if err := inTx(ctx, us.logger(ctx), us.tx, func(ctx context.Context) error {
+		created, err = us.importRows(ctx, cr, len(header), cols, report)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, u := range created {
+		us.userCreated(ctx, u)
+	}
+	if len(created) > 0 {
+		report.ActivationEmailsSent = us.sendActivationEmails(ctx, created)
+	}
+	return report, nil
+}
+
+// importRows validates the rows of the CSV file, adding them to the report, and
+// inserts the valid ones (unless it is a dry-run). It returns the inserted Users.
+func (us *UserService) importRows(ctx context.Context, cr *csv.Reader, nCols int, cols map[string]int, report *adeia.UserImportReport) ([]*adeia.User, error) {
+	seenEmails := make(map[string]int)
+	seenEmpIDs := make(map[string]int)
+	var created []*adeia.User
+
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+
+		row := &adeia.UserImportRow{Row: line}
+		report.Rows = append(report.Rows, row)
+
+		var pErr *csv.ParseError
+		if errors.As(err, &pErr) && pErr.Err == csv.ErrFieldCount {
+			row.Status = adeia.UserImportInvalid
+			e := adeia.ErrValidationFailed.Msgf("Row must have %d columns", nCols)
+			row.Error = &e
+			report.Invalid++
+			continue
+		} else if err != nil {
+			return nil, adeia.ErrValidationFailed.AddValidationErr("file", "Please upload a valid CSV file")
+		}
+
+		field := func(name string) string {
+			i, ok := cols[name]
+			if !ok {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		name, email, empID := field("name"), field("email"), field("employee_id")
+		designation, department := field("designation"), field("department")
+		row.Email, row.EmployeeID = email, empID
+
+		if errs := validateUser(name, email, empID, designation, department); len(errs) > 0 {
+			row.Status = adeia.UserImportInvalid
+			e := adeia.ErrValidationFailed.ValidationErr(errs)
+			row.Error = &e
+			report.Invalid++
+			continue
+		}
+
+		dup, err := us.findDuplicate(ctx, email, empID, seenEmails, seenEmpIDs)
+		if err != nil {
+			return nil, err
+		} else if dup != "" {
+			row.Status = adeia.UserImportDuplicate
+			e := adeia.ErrResourceAlreadyExists.Msg(dup)
+			row.Error = &e
+			report.Duplicate++
+			continue
+		}
+		seenEmails[strings.ToLower(email)] = line
+		if empID != "" {
+			seenEmpIDs[strings.ToLower(empID)] = line
+		}
+
+		if empID == "" {
+			empID = crypto.NewEmpID()
+			row.EmployeeID = empID
+		}
+		user := adeia.NewUser(
+			adeia.WithName(name),
+			adeia.WithEmail(email),
+			adeia.WithDesignation(designation),
+			adeia.WithDepartment(department),
+			adeia.WithEmpID(empID),
+		)
+
+		if !report.DryRun {
+			if err := us.insertUser(ctx, user); err != nil {
+				return nil, err
+			}
+			created = append(created, user)
+		}
+		row.Status = adeia.UserImportCreated
+		report.Created++
+	}
+	return created, nil
+}
+
+// mapColumns maps the importColumns to their index in the header.
+func mapColumns(header []string) (map[string]int, error) {
+	cols := make(map[string]int)
+	for i, h := range header {
+		// strip the BOM that spreadsheet apps like to add
+		h = strings.TrimPrefix(h, "\ufeff")
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+
+	for _, c := range importColumns {
+		if _, ok := cols[c]; !ok {
+			return nil, fmt.Errorf("missing column: %v", c)
+		}
+	}
+	return cols, nil
+}
+
+// findDuplicate returns a message describing why the email or employee ID is a
+// duplicate, either of an earlier row in the file or of an existing User. An empty
+// string is returned when there are no duplicates.
+func (us *UserService) findDuplicate(ctx context.Context, email, empID string, seenEmails, seenEmpIDs map[string]int) (string, error) {
+	if line, ok := seenEmails[strings.ToLower(email)]; ok {
+		return fmt.Sprintf("Email is the same as row %d", line), nil
+	}
+	if line, ok := seenEmpIDs[strings.ToLower(empID)]; ok && empID != "" {
+		return fmt.Sprintf("Employee ID is the same as row %d", line), nil
+	}
+
+	if u, err := us.repo.GetByEmail(ctx, email); err != nil {
+		us.logger(ctx).Errorf("cannot fetch user by email: %v", err)
+		return "", adeia.ErrDatabaseError
+	} else if u != nil {
+		return "A user already exists with the email", nil
+	}
+
+	if empID == "" {
+		return "", nil
+	}
+	if u, err := us.repo.GetByEmpID(ctx, empID); err != nil {
+		us.logger(ctx).Errorf("cannot fetch user by employee id: %v", err)
+		return "", adeia.ErrDatabaseError
+	} else if u != nil {
+		return "A user already exists with the employee ID", nil
+	}
+	return "", nil
+}
+
+// sendActivationEmails sends an email with a single-use activation link to each of
+// the users, and returns the no. of emails that were sent. Only a hash of the token
+// in the link is stored, so that a leaked cache cannot be used to activate accounts.
+// The Users that are not sent an email can be sent a password reset link instead.
+func (us *UserService) sendActivationEmails(ctx context.Context, users []*adeia.User) int {
+	notifications := make([]*notifier.Notification, 0, len(users))
+	for _, u := range users {
+		b, err := crypto.GenerateRandomBytes(constants.ActivationTokenLength)
+		if err != nil {
+			us.logger(ctx).Errorf("cannot generate activation token: %v", err)
+			continue
+		}
+
+		key := activationKeyPrefix + crypto.EncodeHex(crypto.Hash(b))
+		if err := us.cache.SetWithExpiry(key, u.EmployeeID, constants.ActivationTokenExpiry); err != nil {
+			us.logger(ctx).Errorf("cannot store activation token: %v", err)
+			continue
+		}
+
+		notifications = append(notifications, &notifier.Notification{
+			User:     u,
+			Template: "account_activation",
+			Data: map[string]string{
+				"Name":  u.Name,
+				"Token": crypto.EncodeBase64(b),
+			},
+		})
+	}
+
+	if len(notifications) == 0 {
+		return 0
+	}
+	if err := us.notifier.Send(notifications...); err != nil {
+		us.logger(ctx).Errorf("cannot send activation emails: %v", err)
+		return 0
+	}
+	us.logger(ctx).Infof("sent %d activation emails", len(notifications))
+	return len(notifications)
+}
@@ -0,0 +1,115 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"adeia"
+	"adeia/pkg/errs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failingUserRepo is a memUserRepo whose Insert fails after n Users are inserted.
+type failingUserRepo struct {
+	*memUserRepo
+	n int
+}
+
+func (f *failingUserRepo) Insert(ctx context.Context, u *adeia.User) (int, error) {
+	if len(f.users) >= f.n {
+		return 0, errors.New("insert failed")
+	}
+	return f.memUserRepo.Insert(ctx, u)
+}
+
+func TestUserService_ImportUsers(t *testing.T) {
+	const csv = "name,email,employee_id,designation,department\n" +
+		"Foo,foo@example.com,FOO123,Engineer,R&D\n" +
+		"Bar,bar@example.com,,Manager,\n" +
+		"Baz,not-an-email,,Engineer,R&D\n" +
+		"Qux,FOO@example.com,,Engineer,R&D\n" +
+		"Quux,existing@example.com,,Engineer,R&D\n" +
+		"Corge,corge@example.com\n"
+
+	setup := func(t *testing.T) (*UserService, *userServiceDeps) {
+		us, d := setupUserService(t)
+		d.repo.users = []*adeia.User{{ID: 1, EmployeeID: "OLD1", Email: "existing@example.com"}}
+		return us, d
+	}
+
+	t.Run("report each row and create the valid ones", func(t *testing.T) {
+		us, d := setup(t)
+
+		report, err := us.ImportUsers(adminCtx(), strings.NewReader(csv), false)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, report.Created)
+		assert.Equal(t, 2, report.Duplicate)
+		assert.Equal(t, 2, report.Invalid)
+		assert.Equal(t, 2, report.ActivationEmailsSent)
+
+		var statuses []string
+		for _, row := range report.Rows {
+			statuses = append(statuses, row.Status)
+		}
+		assert.Equal(t, []string{
+			adeia.UserImportCreated,
+			adeia.UserImportCreated,
+			adeia.UserImportInvalid,
+			adeia.UserImportDuplicate,
+			adeia.UserImportDuplicate,
+			adeia.UserImportInvalid,
+		}, statuses)
+		assert.NotEmpty(t, report.Rows[1].EmployeeID, "employee id is generated")
+
+		assert.Len(t, d.repo.users, 3)
+		assert.Len(t, d.audit.events, 2)
+		assert.Len(t, d.mailer.sent, 2)
+		assert.Len(t, d.cache.Keys(), 2, "an activation token is stored for each user")
+	})
+
+	t.Run("create nothing on a dry-run", func(t *testing.T) {
+		us, d := setup(t)
+
+		report, err := us.ImportUsers(adminCtx(), strings.NewReader(csv), true)
+		assert.Nil(t, err)
+		assert.True(t, report.DryRun)
+		assert.Equal(t, 2, report.Created)
+		assert.Len(t, d.repo.users, 1)
+		assert.Empty(t, d.mailer.sent)
+	})
+
+	t.Run("allow files without a department column", func(t *testing.T) {
+		us, _ := setup(t)
+
+		report, err := us.ImportUsers(adminCtx(), strings.NewReader("name,email,employee_id,designation\nFoo,foo@example.com,,Engineer\n"), false)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, report.Created)
+	})
+
+	t.Run("reject files without the required columns", func(t *testing.T) {
+		us, _ := setup(t)
+
+		_, err := us.ImportUsers(adminCtx(), strings.NewReader("name,email\nFoo,foo@example.com\n"), false)
+		assert.Equal(t, adeia.ErrValidationFailed.ErrorCode, err.(errs.ResponseError).ErrorCode)
+		assert.Equal(t, "Please upload a CSV file with the columns: name, email, employee_id, designation",
+			err.(errs.ResponseError).ValidationErrors["file"])
+	})
+
+	t.Run("fail the import and send no emails when an insert fails", func(t *testing.T) {
+		us, d := setup(t)
+		us.repo = &failingUserRepo{d.repo, 2}
+
+		report, err := us.ImportUsers(adminCtx(), strings.NewReader(csv), false)
+		assert.Equal(t, adeia.ErrDatabaseError, err)
+		assert.Nil(t, report)
+		assert.Empty(t, d.audit.events)
+		assert.Empty(t, d.mailer.sent)
+	})
+}
@@ -0,0 +1,71 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package service
+
+import (
+	"context"
+
+	"adeia"
+)
+
+// GetUserPreferences returns the UserPreferences of the User with the provided
+// employee ID. The defaults are returned if the User has not saved any preferences.
+func (us *UserService) GetUserPreferences(ctx context.Context, empID string) (*adeia.UserPreferences, error) {
+	u, err := us.GetUserByEmpID(ctx, empID)
+	if err != nil {
+		return nil, err
+	}
+	return us.getPreferences(ctx, u)
+}
+
+// UpdateUserPreferences applies the patch to the UserPreferences of the User with
+// the provided employee ID.
+func (us *UserService) UpdateUserPreferences(ctx context.Context, empID string, patch *adeia.UserPreferencesPatch) (*adeia.UserPreferences, error) {
+	u, err := us.GetUserByEmpID(ctx, empID)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := us.getPreferences(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *p
+	if patch.Channels != nil {
+		p.Channels = adeia.Channels(*patch.Channels)
+	}
+	if patch.EmailDigest != nil {
+		p.EmailDigest = *patch.EmailDigest
+	}
+	if patch.Locale != nil && *patch.Locale != "" {
+		p.Locale = *patch.Locale
+	}
+	if patch.TimeZone != nil && *patch.TimeZone != "" {
+		p.TimeZone = *patch.TimeZone
+	}
+
+	if err := us.prefsRepo.Upsert(ctx, p); err != nil {
+		us.logger(ctx).Warnf("cannot update user preferences: %v", err)
+		return nil, adeia.ErrDatabaseError
+	}
+
+	us.audit.Record(ctx, adeia.AuditUserPreferencesUpdate, adeia.AuditTargetUser, u.EmployeeID, &before, p)
+	return p, nil
+}
+
+// getPreferences returns the UserPreferences of the User, or the defaults if the
+// User has not saved any preferences.
+func (us *UserService) getPreferences(ctx context.Context, u *adeia.User) (*adeia.UserPreferences, error) {
+	p, err := us.prefsRepo.GetByUserID(ctx, u.ID)
+	if err != nil {
+		us.logger(ctx).Errorf("cannot fetch user preferences: %v", err)
+		return nil, adeia.ErrDatabaseError
+	} else if p == nil {
+		p = adeia.NewUserPreferences()
+		p.UserID = u.ID
+	}
+	return p, nil
+}
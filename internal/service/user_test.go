@@ -0,0 +1,263 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package service
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"adeia"
+	"adeia/internal/cache/redis"
+	"adeia/internal/config"
+	"adeia/internal/notifier"
+	"adeia/pkg/errs"
+	logzap "adeia/pkg/log/zap"
+	"adeia/pkg/validation"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// userServiceDeps holds the fakes that a UserService from setupUserService uses.
+type userServiceDeps struct {
+	repo   *memUserRepo
+	mailer *fakeMailer
+	audit  *memAuditRepo
+	cache  *miniredis.Miniredis
+}
+
+func setupUserService(t *testing.T) (*UserService, *userServiceDeps) {
+	t.Parallel()
+	mock, _ := miniredis.Run()
+	port, _ := strconv.Atoi(mock.Port())
+	r, _ := redis.New(&config.CacheConfig{Network: "tcp", Host: mock.Host(), Port: port, ConnSize: 10})
+	t.Cleanup(func() {
+		_ = r.Close()
+		mock.Close()
+	})
+
+	logger := &logzap.Logger{SugaredLogger: zap.NewNop().Sugar()}
+	d := &userServiceDeps{repo: &memUserRepo{}, mailer: &fakeMailer{}, audit: &memAuditRepo{}, cache: mock}
+	us := NewUserService(
+		logger,
+		d.repo,
+		newMemPreferencesRepo(),
+		r,
+		notifier.New(logger, d.mailer, &memPendingNotificationRepo{}),
+		NewAuditService(logger, d.audit),
+		nopTransactor{},
+	)
+	return us, d
+}
+
+// adminCtx returns a context that carries the AuditActor of an admin.
+func adminCtx() context.Context {
+	return adeia.NewAuditActorContext(context.Background(), &adeia.AuditActor{EmployeeID: "ADMIN1"})
+}
+
+func TestUserFields(t *testing.T) {
+	assert.NoError(t, validation.Check(userFields{}))
+}
+
+func TestUserService_CreateUser(t *testing.T) {
+	t.Run("create user without a department", func(t *testing.T) {
+		us, d := setupUserService(t)
+
+		u, err := us.CreateUser(adminCtx(), "Foo", "foo@example.com", "", "Engineer", "")
+		assert.Nil(t, err)
+		assert.NotEmpty(t, u.EmployeeID)
+		assert.Len(t, d.repo.users, 1)
+		assert.Len(t, d.audit.events, 1)
+		assert.Len(t, d.mailer.sent, 1)
+		assert.Len(t, d.cache.Keys(), 1, "an activation token is stored for the user")
+	})
+
+	t.Run("reject invalid fields", func(t *testing.T) {
+		us, d := setupUserService(t)
+
+		_, err := us.CreateUser(adminCtx(), "", "not-an-email", "", "Engineer", "")
+		assert.Equal(t, adeia.ErrValidationFailed.ErrorCode, err.(errs.ResponseError).ErrorCode)
+		assert.Empty(t, d.repo.users)
+		assert.Empty(t, d.mailer.sent)
+	})
+
+	t.Run("reject existing email", func(t *testing.T) {
+		us, d := setupUserService(t)
+		d.repo.users = []*adeia.User{{ID: 1, EmployeeID: "FOO123", Email: "foo@example.com"}}
+
+		_, err := us.CreateUser(adminCtx(), "Foo", "foo@example.com", "", "Engineer", "")
+		assert.Equal(t, adeia.ErrResourceAlreadyExists, err)
+	})
+
+	t.Run("reject existing employee ID", func(t *testing.T) {
+		us, d := setupUserService(t)
+		d.repo.users = []*adeia.User{{ID: 1, EmployeeID: "FOO123", Email: "foo@example.com"}}
+
+		_, err := us.CreateUser(adminCtx(), "Bar", "bar@example.com", "foo123", "Engineer", "")
+		assert.Equal(t, adeia.ErrResourceAlreadyExists, err)
+		assert.Len(t, d.repo.users, 1)
+		assert.Empty(t, d.mailer.sent)
+	})
+}
+
+type txCtxKey struct{}
+
+// markingTransactor is a store.Transactor that marks the ctx of its transactions.
+type markingTransactor struct{}
+
+func (markingTransactor) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(context.WithValue(ctx, txCtxKey{}, true))
+}
+
+// txUserRepo is a memUserRepo that records whether each lookup by employee ID
+// ran in a transaction.
+type txUserRepo struct {
+	*memUserRepo
+	inTx []bool
+}
+
+func (r *txUserRepo) GetByEmpID(ctx context.Context, empID string) (*adeia.User, error) {
+	r.inTx = append(r.inTx, ctx.Value(txCtxKey{}) != nil)
+	return r.memUserRepo.GetByEmpID(ctx, empID)
+}
+
+func TestUserService_RestoreUser(t *testing.T) {
+	earlier := time.Now().UTC().Add(-2 * time.Hour)
+	later := earlier.Add(time.Hour)
+
+	t.Run("restore the most-recently deleted user", func(t *testing.T) {
+		us, d := setupUserService(t)
+		d.repo.users = []*adeia.User{
+			{ID: 1, EmployeeID: "FOO123", Email: "old@example.com", DeletedAt: &earlier},
+			{ID: 2, EmployeeID: "FOO123", Email: "foo@example.com", DeletedAt: &later},
+		}
+
+		u, err := us.RestoreUser(adminCtx(), "FOO123")
+		assert.Nil(t, err)
+		assert.Equal(t, 2, u.ID)
+		assert.Nil(t, u.DeletedAt)
+		assert.Nil(t, d.repo.users[1].DeletedAt)
+		assert.NotNil(t, d.repo.users[0].DeletedAt)
+		assert.Len(t, d.audit.events, 1)
+	})
+
+	t.Run("return not found for an active user", func(t *testing.T) {
+		us, d := setupUserService(t)
+		d.repo.users = []*adeia.User{{ID: 1, EmployeeID: "FOO123", Email: "foo@example.com"}}
+
+		_, err := us.RestoreUser(adminCtx(), "FOO123")
+		assert.Equal(t, adeia.ErrResourceNotFound, err)
+	})
+
+	t.Run("return not found for a missing user", func(t *testing.T) {
+		us, _ := setupUserService(t)
+
+		_, err := us.RestoreUser(adminCtx(), "FOO123")
+		assert.Equal(t, adeia.ErrResourceNotFound, err)
+	})
+
+	t.Run("reject when the employee ID is taken by an active user", func(t *testing.T) {
+		us, d := setupUserService(t)
+		d.repo.users = []*adeia.User{
+			{ID: 1, EmployeeID: "FOO123", Email: "old@example.com", DeletedAt: &earlier},
+			{ID: 2, EmployeeID: "FOO123", Email: "new@example.com"},
+		}
+
+		_, err := us.RestoreUser(adminCtx(), "FOO123")
+		assert.Equal(t, adeia.ErrResourceConflict, err)
+		assert.NotNil(t, d.repo.users[0].DeletedAt)
+		assert.Empty(t, d.audit.events)
+	})
+
+	t.Run("reject when the email is taken by an active user", func(t *testing.T) {
+		us, d := setupUserService(t)
+		d.repo.users = []*adeia.User{
+			{ID: 1, EmployeeID: "FOO123", Email: "foo@example.com", DeletedAt: &earlier},
+			{ID: 2, EmployeeID: "BAR456", Email: "foo@example.com"},
+		}
+
+		_, err := us.RestoreUser(adminCtx(), "FOO123")
+		assert.Equal(t, adeia.ErrResourceConflict, err)
+		assert.NotNil(t, d.repo.users[0].DeletedAt)
+	})
+
+	t.Run("check for conflicts in the restoring transaction", func(t *testing.T) {
+		us, d := setupUserService(t)
+		d.repo.users = []*adeia.User{{ID: 1, EmployeeID: "FOO123", Email: "foo@example.com", DeletedAt: &earlier}}
+		repo := &txUserRepo{memUserRepo: d.repo}
+		us.repo, us.tx = repo, markingTransactor{}
+
+		_, err := us.RestoreUser(adminCtx(), "FOO123")
+		assert.Nil(t, err)
+		assert.Equal(t, []bool{true}, repo.inTx)
+	})
+}
+
+func TestUserService_GetUserByEmpID(t *testing.T) {
+	t.Run("return the user", func(t *testing.T) {
+		us, d := setupUserService(t)
+		d.repo.users = []*adeia.User{{ID: 1, EmployeeID: "FOO123"}}
+
+		u, err := us.GetUserByEmpID(context.Background(), "FOO123")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, u.ID)
+	})
+
+	t.Run("return not found for a deleted user", func(t *testing.T) {
+		us, d := setupUserService(t)
+		deletedAt := time.Now().UTC()
+		d.repo.users = []*adeia.User{{ID: 1, EmployeeID: "FOO123", DeletedAt: &deletedAt}}
+
+		_, err := us.GetUserByEmpID(context.Background(), "FOO123")
+		assert.Equal(t, adeia.ErrResourceNotFound, err)
+	})
+}
+
+func TestUserService_UpdateUser(t *testing.T) {
+	t.Run("update only the fields that are set", func(t *testing.T) {
+		us, d := setupUserService(t)
+		d.repo.users = []*adeia.User{{ID: 1, EmployeeID: "FOO123", Name: "Foo", Designation: "Engineer", Department: "R&D"}}
+
+		u, err := us.UpdateUser(adminCtx(), "FOO123", "", "Manager", "")
+		assert.Nil(t, err)
+		assert.Equal(t, "Foo", u.Name)
+		assert.Equal(t, "Manager", u.Designation)
+		assert.Equal(t, "R&D", u.Department)
+		assert.Equal(t, "Manager", d.repo.users[0].Designation)
+		assert.Len(t, d.audit.events, 1)
+	})
+
+	t.Run("return not found for a missing user", func(t *testing.T) {
+		us, d := setupUserService(t)
+
+		_, err := us.UpdateUser(adminCtx(), "FOO123", "Bar", "", "")
+		assert.Equal(t, adeia.ErrResourceNotFound, err)
+		assert.Empty(t, d.audit.events)
+	})
+}
+
+func TestUserService_DeactivateUser(t *testing.T) {
+	t.Run("deactivate the user", func(t *testing.T) {
+		us, d := setupUserService(t)
+		d.repo.users = []*adeia.User{{ID: 1, EmployeeID: "FOO123", Password: "hash", IsActivated: true}}
+
+		u, err := us.DeactivateUser(adminCtx(), "FOO123")
+		assert.Nil(t, err)
+		assert.False(t, u.IsActivated)
+		assert.Equal(t, "hash", d.repo.users[0].Password)
+		assert.False(t, d.repo.users[0].IsActivated)
+		assert.NotNil(t, d.repo.users[0].DeactivatedAt)
+	})
+
+	t.Run("return not found for a missing user", func(t *testing.T) {
+		us, _ := setupUserService(t)
+
+		_, err := us.DeactivateUser(adminCtx(), "FOO123")
+		assert.Equal(t, adeia.ErrResourceNotFound, err)
+	})
+}
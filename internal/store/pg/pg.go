@@ -7,7 +7,11 @@ package pg
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 
 	"adeia/internal/config"
 	"adeia/pkg/util/ioutil"
@@ -16,6 +20,10 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
+// queryColumns lists the columns of all the tables in the current schema.
+const queryColumns = "SELECT table_name, column_name FROM information_schema.columns " +
+	"WHERE table_schema = current_schema()"
+
 var escapeDSNValue = stringutil.NewEscaper(`\`, `'`, `\`)
 
 // PostgresDB represents an instance of the Postgres database connection.
@@ -64,9 +72,98 @@ func buildDSN(conf *config.DBConfig) (dsn string) {
 	return
 }
 
+// CheckSchema checks if the tables in schema exist, along with their columns, so
+// that it is known whether all the migrations have been run. schema maps each table
+// to its columns. An error listing the missing tables and columns is returned
+// otherwise.
+func (p *PostgresDB) CheckSchema(ctx context.Context, schema map[string][]string) error {
+	var rows []struct {
+		Table  string `db:"table_name"`
+		Column string `db:"column_name"`
+	}
+	if err := p.SelectContext(ctx, &rows, queryColumns); err != nil {
+		return err
+	}
+
+	existing := make(map[string]map[string]bool)
+	for _, r := range rows {
+		if existing[r.Table] == nil {
+			existing[r.Table] = make(map[string]bool)
+		}
+		existing[r.Table][r.Column] = true
+	}
+
+	var missingTables, missingColumns []string
+	for table, columns := range schema {
+		if existing[table] == nil {
+			missingTables = append(missingTables, table)
+			continue
+		}
+		for _, c := range columns {
+			if !existing[table][c] {
+				missingColumns = append(missingColumns, table+"."+c)
+			}
+		}
+	}
+	// keep the error stable, irrespective of map ordering
+	sort.Strings(missingTables)
+	sort.Strings(missingColumns)
+
+	var problems []string
+	if len(missingTables) > 0 {
+		problems = append(problems, "missing tables: "+strings.Join(missingTables, ", "))
+	}
+	if len(missingColumns) > 0 {
+		problems = append(problems, "missing columns: "+strings.Join(missingColumns, ", "))
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+type txCtxKey struct{}
+
+// InTx runs fn in a transaction, that is committed if fn returns nil and rolled
+// back otherwise. The queries run with the ctx passed to fn are a part of the
+// transaction. If ctx already carries a transaction, fn runs in it instead, so
+// that the outermost InTx decides whether it is committed.
+func (p *PostgresDB) InTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(txCtxKey{}).(*sqlx.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := p.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txCtxKey{}, tx)); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%v (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// ext returns the transaction carried by ctx, or the database if there is none.
+func (p *PostgresDB) ext(ctx context.Context) sqlx.ExtContext {
+	if tx, ok := ctx.Value(txCtxKey{}).(*sqlx.Tx); ok {
+		return tx
+	}
+	return p.DB
+}
+
 // Insert inserts a row into the database. It returns the lastInsertID.
 func (p *PostgresDB) Insert(ctx context.Context, query string, args ...interface{}) (lastInsertID int, err error) {
-	err = p.QueryRowContext(ctx, query, args...).Scan(&lastInsertID)
+	err = p.ext(ctx).QueryRowxContext(ctx, query, args...).Scan(&lastInsertID)
 	if err != nil {
 		return 0, err
 	}
@@ -81,8 +178,8 @@ func (p *PostgresDB) InsertNamed(ctx context.Context, namedQuery string, arg int
 		return 0, err
 	}
 
-	query = p.Rebind(query)
-	err = p.GetContext(ctx, &lastInsertID, query, args...)
+	e := p.ext(ctx)
+	err = sqlx.GetContext(ctx, e, &lastInsertID, e.Rebind(query), args...)
 	if err != nil {
 		return 0, err
 	}
@@ -91,7 +188,7 @@ func (p *PostgresDB) InsertNamed(ctx context.Context, namedQuery string, arg int
 
 // GetMany is a generic database SELECT that returns multiple records.
 func (p *PostgresDB) GetMany(ctx context.Context, dest interface{}, query string, args ...interface{}) (err error) {
-	rows, err := p.QueryxContext(ctx, query, args...)
+	rows, err := p.ext(ctx).QueryxContext(ctx, query, args...)
 	if err != nil {
 		return
 	}
@@ -105,7 +202,7 @@ func (p *PostgresDB) GetMany(ctx context.Context, dest interface{}, query string
 
 // GetOne is a generic database SELECT that returns a single record.
 func (p *PostgresDB) GetOne(ctx context.Context, dest interface{}, query string, args ...interface{}) (ok bool, err error) {
-	if err := p.GetContext(ctx, dest, query, args...); err != nil {
+	if err := sqlx.GetContext(ctx, p.ext(ctx), dest, query, args...); err != nil {
 		if err == sql.ErrNoRows {
 			return false, nil
 		}
@@ -130,7 +227,7 @@ func (p *PostgresDB) Delete(ctx context.Context, query string, args ...interface
 }
 
 func (p *PostgresDB) exec(ctx context.Context, query string, args ...interface{}) (rowsAffected int64, err error) {
-	result, err := p.ExecContext(ctx, query, args...)
+	result, err := p.ext(ctx).ExecContext(ctx, query, args...)
 	if err != nil {
 		return 0, err
 	}
@@ -144,7 +241,7 @@ func (p *PostgresDB) exec(ctx context.Context, query string, args ...interface{}
 }
 
 func (p *PostgresDB) execNamed(ctx context.Context, namedQuery string, arg interface{}) (rowsAffected int64, err error) {
-	result, err := p.NamedExecContext(ctx, namedQuery, arg)
+	result, err := sqlx.NamedExecContext(ctx, p.ext(ctx), namedQuery, arg)
 	if err != nil {
 		return 0, err
 	}
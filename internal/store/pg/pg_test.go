@@ -118,6 +118,7 @@ func TestBuildDSN(t *testing.T) {
 	}
 
 	for _, tc := range testCases {
+		tc := tc
 		t.Run(tc.msg, func(t *testing.T) {
 			t.Parallel()
 			got := buildDSN(tc.in)
@@ -545,3 +546,146 @@ func TestPostgresDB_InsertNamed(t *testing.T) {
 		assert.Equal(t, want, got)
 	})
 }
+
+func TestPostgresDB_CheckSchema(t *testing.T) {
+	schema := map[string][]string{
+		"users":    {"id", "deleted_at"},
+		"roles":    {"id", "require_2fa"},
+		"sessions": {"id"},
+	}
+	columns := func(cols ...[2]string) *sqlmock.Rows {
+		rows := sqlmock.NewRows([]string{"table_name", "column_name"})
+		for _, c := range cols {
+			rows.AddRow(c[0], c[1])
+		}
+		return rows
+	}
+
+	t.Run("return error when query fails", func(t *testing.T) {
+		p, mock, c := setup(t)
+		defer c()
+
+		mock.
+			ExpectQuery("SELECT table_name, column_name FROM information_schema.columns (.+)").
+			WillReturnError(errors.New("query failed"))
+		err := p.CheckSchema(context.Background(), schema)
+
+		assert.Nil(t, mock.ExpectationsWereMet())
+		assert.Error(t, err)
+	})
+
+	t.Run("return error listing missing tables and columns", func(t *testing.T) {
+		p, mock, c := setup(t)
+		defer c()
+
+		mock.
+			ExpectQuery("SELECT table_name, column_name FROM information_schema.columns (.+)").
+			WillReturnRows(columns([2]string{"users", "id"}, [2]string{"roles", "id"}))
+		err := p.CheckSchema(context.Background(), schema)
+
+		assert.Nil(t, mock.ExpectationsWereMet())
+		assert.EqualError(t, err, "missing tables: sessions; missing columns: roles.require_2fa, users.deleted_at")
+	})
+
+	t.Run("return nil when all tables and columns exist", func(t *testing.T) {
+		p, mock, c := setup(t)
+		defer c()
+
+		mock.
+			ExpectQuery("SELECT table_name, column_name FROM information_schema.columns (.+)").
+			WillReturnRows(columns(
+				[2]string{"users", "id"},
+				[2]string{"users", "deleted_at"},
+				[2]string{"users", "unused"},
+				[2]string{"roles", "id"},
+				[2]string{"roles", "require_2fa"},
+				[2]string{"sessions", "id"},
+			))
+		err := p.CheckSchema(context.Background(), schema)
+
+		assert.Nil(t, mock.ExpectationsWereMet())
+		assert.Nil(t, err)
+	})
+}
+
+func TestPostgresDB_InTx(t *testing.T) {
+	query := "DELETE FROM table WHERE col1=$1"
+
+	t.Run("commit when fn succeeds", func(t *testing.T) {
+		p, mock, c := setup(t)
+		defer c()
+
+		mock.ExpectBegin()
+		mock.
+			ExpectExec("DELETE FROM table WHERE col1=(.+)").
+			WithArgs("arg1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		err := p.InTx(context.Background(), func(ctx context.Context) error {
+			_, err := p.Delete(ctx, query, "arg1")
+			return err
+		})
+
+		assert.Nil(t, mock.ExpectationsWereMet())
+		assert.Nil(t, err)
+	})
+
+	t.Run("rollback when fn fails", func(t *testing.T) {
+		p, mock, c := setup(t)
+		defer c()
+
+		mock.ExpectBegin()
+		mock.
+			ExpectExec("DELETE FROM table WHERE col1=(.+)").
+			WithArgs("arg1").
+			WillReturnError(errors.New("exec failed"))
+		mock.ExpectRollback()
+		err := p.InTx(context.Background(), func(ctx context.Context) error {
+			_, err := p.Delete(ctx, query, "arg1")
+			return err
+		})
+
+		assert.Nil(t, mock.ExpectationsWereMet())
+		assert.EqualError(t, err, "exec failed")
+	})
+
+	t.Run("join the transaction in ctx", func(t *testing.T) {
+		p, mock, c := setup(t)
+		defer c()
+
+		mock.ExpectBegin()
+		mock.
+			ExpectExec("DELETE FROM table WHERE col1=(.+)").
+			WithArgs("arg1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectRollback()
+		err := p.InTx(context.Background(), func(ctx context.Context) error {
+			if err := p.InTx(ctx, func(ctx context.Context) error {
+				_, err := p.Delete(ctx, query, "arg1")
+				return err
+			}); err != nil {
+				return err
+			}
+			return errors.New("outer failed")
+		})
+
+		assert.Nil(t, mock.ExpectationsWereMet())
+		assert.EqualError(t, err, "outer failed")
+	})
+
+	t.Run("return error when begin fails", func(t *testing.T) {
+		p, mock, c := setup(t)
+		defer c()
+
+		mock.ExpectBegin().WillReturnError(errors.New("begin failed"))
+		called := false
+		err := p.InTx(context.Background(), func(context.Context) error {
+			called = true
+			return nil
+		})
+
+		assert.Nil(t, mock.ExpectationsWereMet())
+		assert.Error(t, err)
+		assert.False(t, called)
+	})
+}
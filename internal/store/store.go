@@ -32,11 +32,18 @@ type Updater interface {
 	UpdateNamed(ctx context.Context, query string, arg interface{}) (rowsAffected int64, err error)
 }
 
+// Transactor is the interface for running queries in a transaction. The queries
+// that are run with the ctx passed to fn are a part of the transaction.
+type Transactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
 // DB is the interface for all the methods of the database.
 type DB interface {
 	io.Closer
 	Deleter
 	Getter
 	Inserter
+	Transactor
 	Updater
 }
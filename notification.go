@@ -0,0 +1,48 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package adeia
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// PendingNotification represents a notification that is held until it is sent in
+// an email digest. The recipient is stored along with the content, so that the
+// digest can be sent without loading the User again.
+type PendingNotification struct {
+	// ID is the auto-incremented primary key of the PendingNotification.
+	ID int `db:"id"`
+
+	// UserID is the ID of the User that the notification is for.
+	UserID int `db:"user_id"`
+
+	// Email is the email address of the User.
+	Email string `db:"email"`
+
+	// Name is the name of the User, that the digest is addressed to.
+	Name string `db:"name"`
+
+	// Locale is the locale of the User, that the digest is rendered in.
+	Locale string `db:"locale"`
+
+	// Template is the name of the email template of the notification, like
+	// "account_locked".
+	Template string `db:"template"`
+
+	// Data is the JSON-encoded data of the template.
+	Data json.RawMessage `db:"data"`
+
+	// CreatedAt is the time at which the notification was created.
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// PendingNotificationRepo is the interface for all the repository functions on the
+// PendingNotification model.
+type PendingNotificationRepo interface {
+	DeleteAll(ctx context.Context) ([]*PendingNotification, error)
+	Insert(ctx context.Context, n *PendingNotification) (lastInsertID int, err error)
+}
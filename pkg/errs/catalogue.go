@@ -0,0 +1,92 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package errs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"adeia/pkg/util/constants"
+)
+
+// Entry represents an entry in the error catalogue, describing an ErrorCode.
+type Entry struct {
+	// ErrorCode is the ErrorCode of the ResponseError.
+	ErrorCode string `json:"code"`
+
+	// StatusCode is the HTTP response code that is returned for the error.
+	StatusCode int `json:"status"`
+
+	// Title is the default Message of the ResponseError.
+	Title string `json:"title,omitempty"`
+
+	// Description describes when the error is returned.
+	Description string `json:"description"`
+
+	// DocsURL is a link to the documentation of the error.
+	DocsURL string `json:"docs_url"`
+}
+
+// catalogue holds all the registered errors, keyed by their ErrorCode.
+var catalogue = struct {
+	sync.RWMutex
+	entries map[string]Entry
+}{entries: make(map[string]Entry)}
+
+// Register adds the ResponseError to the error catalogue, along with a description,
+// and returns it as-is, so that errors can be registered when they are declared:
+//
+//	var ErrFoo = errs.Register(errs.ResponseError{...}, "description")
+//
+// It panics if the ErrorCode is already registered, as ErrorCodes must be unique.
+func Register(re ResponseError, description string) ResponseError {
+	catalogue.Lock()
+	defer catalogue.Unlock()
+
+	if _, ok := catalogue.entries[re.ErrorCode]; ok {
+		panic(fmt.Sprintf("errs: error code %q is already registered", re.ErrorCode))
+	}
+
+	catalogue.entries[re.ErrorCode] = Entry{
+		ErrorCode:   re.ErrorCode,
+		StatusCode:  re.StatusCode,
+		Title:       re.Message,
+		Description: description,
+		DocsURL:     docsURL(re.ErrorCode),
+	}
+	return re
+}
+
+// Lookup returns the catalogue Entry of the ErrorCode.
+func Lookup(code string) (Entry, bool) {
+	catalogue.RLock()
+	defer catalogue.RUnlock()
+
+	e, ok := catalogue.entries[code]
+	return e, ok
+}
+
+// Catalogue returns all the registered Entries, sorted by their ErrorCode.
+func Catalogue() []Entry {
+	catalogue.RLock()
+	defer catalogue.RUnlock()
+
+	entries := make([]Entry, 0, len(catalogue.entries))
+	for _, e := range catalogue.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ErrorCode < entries[j].ErrorCode
+	})
+	return entries
+}
+
+// docsURL returns the link to the documentation of the ErrorCode. Each ErrorCode
+// is a heading in the docs, and so has an anchor.
+func docsURL(code string) string {
+	return constants.ErrorDocsURL + "#" + strings.ToLower(code)
+}
@@ -0,0 +1,53 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package errs
+
+import (
+	"net/http"
+	"testing"
+
+	"adeia/pkg/util/constants"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegister(t *testing.T) {
+	t.Run("register and lookup error", func(t *testing.T) {
+		re := ResponseError{StatusCode: http.StatusTeapot, ErrorCode: "TEST_REGISTER", Message: "foo"}
+		got := Register(re, "bar")
+		assert.Equal(t, re, got)
+
+		e, ok := Lookup("TEST_REGISTER")
+		assert.True(t, ok)
+		assert.Equal(t, Entry{
+			ErrorCode:   "TEST_REGISTER",
+			StatusCode:  http.StatusTeapot,
+			Title:       "foo",
+			Description: "bar",
+			DocsURL:     constants.ErrorDocsURL + "#test_register",
+		}, e)
+	})
+
+	t.Run("panic on duplicate error code", func(t *testing.T) {
+		re := ResponseError{StatusCode: http.StatusTeapot, ErrorCode: "TEST_DUPLICATE"}
+		Register(re, "foo")
+		assert.Panics(t, func() { Register(re, "bar") })
+	})
+
+	t.Run("lookup unknown error code", func(t *testing.T) {
+		_, ok := Lookup("TEST_UNKNOWN")
+		assert.False(t, ok)
+	})
+}
+
+func TestCatalogue(t *testing.T) {
+	Register(ResponseError{ErrorCode: "TEST_CATALOGUE_B"}, "")
+	Register(ResponseError{ErrorCode: "TEST_CATALOGUE_A"}, "")
+
+	entries := Catalogue()
+	for i := 1; i < len(entries); i++ {
+		assert.Less(t, entries[i-1].ErrorCode, entries[i].ErrorCode)
+	}
+}
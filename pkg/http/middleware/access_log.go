@@ -0,0 +1,79 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// AccessLogEntry represents the details of a request and its response, that are
+// logged by the AccessLog middleware.
+type AccessLogEntry struct {
+	Method    string
+	Path      string
+	Status    int
+	Bytes     int
+	Duration  time.Duration
+	RemoteIP  string
+	UserAgent string
+	RequestID string
+}
+
+// AccessLog returns a middleware that calls logFn with each request and its
+// details, once its response is written.
+func AccessLog(logFn func(r *http.Request, e *AccessLogEntry)) Func {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+
+			logFn(r, &AccessLogEntry{
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Status:    rec.status(),
+				Bytes:     rec.bytes,
+				Duration:  time.Since(start),
+				RemoteIP:  RemoteIP(r),
+				UserAgent: r.UserAgent(),
+				RequestID: RequestIDFromContext(r.Context()),
+			})
+		})
+	}
+}
+
+// responseRecorder is a http.ResponseWriter that records the status code and the
+// no. of bytes written.
+type responseRecorder struct {
+	http.ResponseWriter
+	code  int
+	bytes int
+}
+
+func (rr *responseRecorder) WriteHeader(code int) {
+	if rr.code == 0 {
+		rr.code = code
+	}
+	rr.ResponseWriter.WriteHeader(code)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if rr.code == 0 {
+		rr.code = http.StatusOK
+	}
+	n, err := rr.ResponseWriter.Write(b)
+	rr.bytes += n
+	return n, err
+}
+
+// status returns the status code of the response, which is 200 if nothing was
+// written.
+func (rr *responseRecorder) status() int {
+	if rr.code == 0 {
+		return http.StatusOK
+	}
+	return rr.code
+}
@@ -0,0 +1,59 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessLog(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantStatus int
+		wantBytes  int
+	}{
+		{
+			"record status and bytes",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte("hello"))
+			},
+			http.StatusCreated,
+			5,
+		},
+		{
+			"default to 200",
+			func(w http.ResponseWriter, r *http.Request) {},
+			http.StatusOK,
+			0,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var got *AccessLogEntry
+			h := AccessLog(func(_ *http.Request, e *AccessLogEntry) {
+				got = e
+			})(tc.handler)
+
+			r := httptest.NewRequest(http.MethodPost, "/foo", nil)
+			r.RemoteAddr = "10.0.0.1:1234"
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			assert.Equal(t, http.MethodPost, got.Method)
+			assert.Equal(t, "/foo", got.Path)
+			assert.Equal(t, "10.0.0.1", got.RemoteIP)
+			assert.Equal(t, tc.wantStatus, got.Status)
+			assert.Equal(t, tc.wantBytes, got.Bytes)
+		})
+	}
+}
@@ -0,0 +1,114 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type clientIPCtxKey struct{}
+
+// ClientIP returns a middleware that resolves the IP address of the client using
+// the KeyFunc (like ForwardedIP), and adds it to the request context, so that it
+// is resolved the same way everywhere.
+func ClientIP(key KeyFunc) Func {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), clientIPCtxKey{}, key(r))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ClientIPFromRequest returns the IP address of the client from the request
+// context. If the ClientIP middleware did not run, the RemoteIP is returned.
+func ClientIPFromRequest(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPCtxKey{}).(string); ok {
+		return ip
+	}
+	return RemoteIP(r)
+}
+
+// RemoteIP is a KeyFunc that returns the IP address of the client that made the
+// request.
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ForwardedIP returns a KeyFunc that returns the IP address of the client that made
+// the request, when it is made through the trusted proxies. If the request is from a
+// trusted proxy, the X-Forwarded-For header is read from right to left, and the
+// first address that is not a trusted proxy is returned. Otherwise, the header is
+// ignored, since clients can set it to anything.
+func ForwardedIP(trusted []*net.IPNet) KeyFunc {
+	isTrusted := func(s string) bool {
+		ip := net.ParseIP(strings.TrimSpace(s))
+		if ip == nil {
+			return false
+		}
+		for _, n := range trusted {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(r *http.Request) string {
+		ip := RemoteIP(r)
+		if !isTrusted(ip) {
+			return ip
+		}
+
+		hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop == "" {
+				continue
+			}
+			if !isTrusted(hop) {
+				return hop
+			}
+			ip = hop
+		}
+		// all the hops are trusted, so the first one is the client
+		return ip
+	}
+}
+
+// ParseCIDRs parses the IP addresses and CIDR ranges, like "10.0.0.1" or
+// "10.0.0.0/8". IP addresses are treated as ranges of a single address.
+func ParseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		if !strings.Contains(c, "/") {
+			ip := net.ParseIP(c)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid ip address: %q", c)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr: %q", c)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
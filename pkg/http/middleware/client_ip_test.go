@@ -0,0 +1,78 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIP(t *testing.T) {
+	t.Parallel()
+	trusted, _ := ParseCIDRs([]string{"10.0.0.1"})
+	var got string
+	h := ClientIP(ForwardedIP(trusted))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = ClientIPFromRequest(r)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "198.51.100.1", got)
+
+	// without the middleware, the remote address is used
+	assert.Equal(t, "10.0.0.1", ClientIPFromRequest(r))
+}
+
+func TestForwardedIP(t *testing.T) {
+	trusted, err := ParseCIDRs([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatalf("cannot parse cidrs: %v", err)
+	}
+	key := ForwardedIP(trusted)
+
+	testcases := []struct {
+		remote string
+		xff    string
+		want   string
+		msg    string
+	}{
+		{"203.0.113.7:1234", "198.51.100.1", "203.0.113.7", "header of untrusted client is ignored"},
+		{"10.0.0.1:1234", "", "10.0.0.1", "proxy without header"},
+		{"10.0.0.1:1234", "198.51.100.1", "198.51.100.1", "client behind proxy"},
+		{"10.0.0.1:1234", "6.6.6.6, 198.51.100.1, 192.168.1.1", "198.51.100.1", "spoofed hops are ignored"},
+		{"10.0.0.1:1234", "10.1.1.1, 10.2.2.2", "10.1.1.1", "all hops are trusted"},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.msg, func(t *testing.T) {
+			t.Parallel()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tc.remote
+			if tc.xff != "" {
+				r.Header.Set("X-Forwarded-For", tc.xff)
+			}
+			assert.Equal(t, tc.want, key(r))
+		})
+	}
+}
+
+func TestParseCIDRs(t *testing.T) {
+	t.Parallel()
+	nets, err := ParseCIDRs([]string{"10.0.0.0/8", "192.168.1.1", "::1"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.1/32", "::1/128"}, []string{
+		nets[0].String(), nets[1].String(), nets[2].String(),
+	})
+
+	_, err = ParseCIDRs([]string{"foo"})
+	assert.Error(t, err)
+	_, err = ParseCIDRs([]string{"10.0.0.0/99"})
+	assert.Error(t, err)
+}
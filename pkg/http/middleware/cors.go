@@ -0,0 +1,89 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CORSOptions represents the options of the CORS middleware.
+type CORSOptions struct {
+	// AllowedOrigins is the list of origins that can make cross-origin requests,
+	// like "https://adeia.example.com". "*" allows all origins.
+	AllowedOrigins []string
+
+	// AllowedMethods is the list of methods allowed in cross-origin requests.
+	AllowedMethods []string
+
+	// AllowedHeaders is the list of request headers allowed in cross-origin requests.
+	AllowedHeaders []string
+
+	// ExposedHeaders is the list of response headers that browsers expose to clients.
+	ExposedHeaders []string
+
+	// AllowCredentials represents whether cookies and the Authorization header can
+	// be sent in cross-origin requests.
+	AllowCredentials bool
+
+	// MaxAge (in seconds) is the time for which browsers can cache preflight responses.
+	MaxAge int
+}
+
+// CORS returns a middleware that handles Cross-Origin Resource Sharing, so that
+// the API can be used from browsers on the allowed origins. Preflight requests are
+// responded to without calling the next handler.
+func CORS(opts CORSOptions) Func {
+	allowAll := false
+	allowed := make(map[string]bool, len(opts.AllowedOrigins))
+	for _, o := range opts.AllowedOrigins {
+		if o == "*" {
+			allowAll = true
+		}
+		allowed[strings.ToLower(o)] = true
+	}
+	methods := strings.Join(opts.AllowedMethods, ", ")
+	headers := strings.Join(opts.AllowedHeaders, ", ")
+	exposed := strings.Join(opts.ExposedHeaders, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			if origin == "" || !(allowAll || allowed[strings.ToLower(origin)]) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// the origin is echoed instead of "*", since "*" is not allowed with
+			// credentials
+			h.Set("Access-Control-Allow-Origin", origin)
+			if opts.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+				h.Set("Access-Control-Allow-Methods", methods)
+				if headers != "" {
+					h.Set("Access-Control-Allow-Headers", headers)
+				}
+				if opts.MaxAge > 0 {
+					h.Set("Access-Control-Max-Age", strconv.Itoa(opts.MaxAge))
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			if exposed != "" {
+				h.Set("Access-Control-Expose-Headers", exposed)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORS(t *testing.T) {
+	opts := CORSOptions{
+		AllowedOrigins:   []string{"https://adeia.example.com"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders:   []string{"Authorization"},
+		ExposedHeaders:   []string{"X-Request-ID"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	t.Run("allow request from allowed origin", func(t *testing.T) {
+		t.Parallel()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Origin", "https://adeia.example.com")
+		rr := httptest.NewRecorder()
+		CORS(opts)(ok).ServeHTTP(rr, r)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "https://adeia.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", rr.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, "X-Request-ID", rr.Header().Get("Access-Control-Expose-Headers"))
+	})
+
+	t.Run("respond to preflight request", func(t *testing.T) {
+		t.Parallel()
+		r := httptest.NewRequest(http.MethodOptions, "/", nil)
+		r.Header.Set("Origin", "https://adeia.example.com")
+		r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		rr := httptest.NewRecorder()
+		CORS(opts)(ok).ServeHTTP(rr, r)
+
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		assert.Equal(t, "GET, POST", rr.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "Authorization", rr.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "600", rr.Header().Get("Access-Control-Max-Age"))
+	})
+
+	t.Run("ignore request from other origin", func(t *testing.T) {
+		t.Parallel()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Origin", "https://evil.example.com")
+		rr := httptest.NewRecorder()
+		CORS(opts)(ok).ServeHTTP(rr, r)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
@@ -0,0 +1,130 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package middleware
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// gzipMinSize (in bytes) is the minimum size of a response to be compressed, as
+// compressing smaller ones is not worth it.
+const gzipMinSize = 1024
+
+var gzipWriters = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(nil)
+	},
+}
+
+// Gzip returns a middleware that compresses responses using gzip, for clients that
+// accept it. Responses smaller than 1KiB, and those that are already encoded, are
+// sent as-is.
+func Gzip() Func {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if !acceptsGzip(r) || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			gw := &gzipResponseWriter{ResponseWriter: w}
+			defer gw.Close()
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, e := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		parts := strings.Split(strings.TrimSpace(e), ";")
+		if strings.EqualFold(parts[0], "gzip") {
+			return len(parts) == 1 || strings.TrimSpace(parts[1]) != "q=0"
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter buffers the start of the response, until it is known whether
+// it is large enough to be compressed.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz     *gzip.Writer
+	buf    []byte
+	code   int
+	passed bool // whether the response is sent as-is
+}
+
+func (gw *gzipResponseWriter) WriteHeader(code int) {
+	if gw.code == 0 {
+		gw.code = code
+	}
+}
+
+func (gw *gzipResponseWriter) Write(b []byte) (int, error) {
+	if gw.code == 0 {
+		gw.code = http.StatusOK
+	}
+	if gw.gz != nil {
+		return gw.gz.Write(b)
+	}
+	if gw.passed {
+		return gw.ResponseWriter.Write(b)
+	}
+
+	gw.buf = append(gw.buf, b...)
+	if len(gw.buf) >= gzipMinSize {
+		if err := gw.start(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// start decides whether to compress the response, based on its headers and
+// what has been buffered, and writes the buffer.
+func (gw *gzipResponseWriter) start() error {
+	h := gw.ResponseWriter.Header()
+	if len(gw.buf) >= gzipMinSize && h.Get("Content-Encoding") == "" {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		gw.ResponseWriter.WriteHeader(gw.code)
+
+		gw.gz = gzipWriters.Get().(*gzip.Writer)
+		gw.gz.Reset(gw.ResponseWriter)
+		_, err := gw.gz.Write(gw.buf)
+		gw.buf = nil
+		return err
+	}
+
+	gw.passed = true
+	gw.ResponseWriter.WriteHeader(gw.code)
+	if len(gw.buf) == 0 {
+		return nil
+	}
+	_, err := gw.ResponseWriter.Write(gw.buf)
+	gw.buf = nil
+	return err
+}
+
+// Close flushes the response, and releases the gzip.Writer.
+func (gw *gzipResponseWriter) Close() {
+	if gw.gz == nil && !gw.passed {
+		if gw.code == 0 {
+			// nothing was written
+			return
+		}
+		_ = gw.start()
+	}
+	if gw.gz != nil {
+		_ = gw.gz.Close()
+		gw.gz.Reset(nil)
+		gzipWriters.Put(gw.gz)
+		gw.gz = nil
+	}
+}
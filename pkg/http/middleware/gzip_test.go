@@ -0,0 +1,73 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package middleware
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGzip(t *testing.T) {
+	large := strings.Repeat("a", 2*gzipMinSize)
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		body           string
+		wantGzip       bool
+	}{
+		{"compress large response", "gzip, deflate", large, true},
+		{"skip small response", "gzip", "small", false},
+		{"skip when gzip is not accepted", "deflate", large, false},
+		{"skip when gzip is refused", "gzip;q=0", large, false},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			h := Gzip()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, r)
+
+			assert.Equal(t, http.StatusCreated, rr.Code)
+			if !tc.wantGzip {
+				assert.Empty(t, rr.Header().Get("Content-Encoding"))
+				assert.Equal(t, tc.body, rr.Body.String())
+				return
+			}
+
+			assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+			gz, err := gzip.NewReader(rr.Body)
+			assert.Nil(t, err)
+			got, _ := ioutil.ReadAll(gz)
+			assert.Equal(t, tc.body, string(got))
+		})
+	}
+
+	t.Run("write status of empty response", func(t *testing.T) {
+		t.Parallel()
+		h := Gzip()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+
+		r := httptest.NewRequest(http.MethodDelete, "/", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, r)
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+	})
+}
@@ -0,0 +1,138 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimitResult is the result of a request being checked against a Limiter.
+type RateLimitResult struct {
+	// Allowed represents whether the request is within the limit.
+	Allowed bool
+
+	// Limit is the maximum no. of requests allowed in the window of the Limiter.
+	Limit int
+
+	// Remaining is the no. of requests that can still be made in the window.
+	Remaining int
+
+	// Reset is the time after which requests are available again, as per the
+	// algorithm of the Limiter.
+	Reset time.Duration
+
+	// RetryAfter is the time after which the next request is allowed, if this one
+	// is not.
+	RetryAfter time.Duration
+}
+
+// Limiter is the interface for rate-limiting algorithms. Allow records a request
+// for the key (usually identifying the client), and checks if it is within the limit.
+type Limiter interface {
+	Allow(key string) (*RateLimitResult, error)
+}
+
+// KeyFunc returns the key of the client that made the request, that it is
+// rate-limited by.
+type KeyFunc func(r *http.Request) string
+
+// RateLimitOpt represents the optional function to modify the rate-limit middleware.
+type RateLimitOpt func(rl *rateLimit)
+
+// WithKeyFunc sets the KeyFunc of the rate-limit middleware. By default, clients
+// are rate-limited by their IP address.
+func WithKeyFunc(f KeyFunc) RateLimitOpt {
+	return func(rl *rateLimit) {
+		rl.key = f
+	}
+}
+
+// WithRouteLimiter overrides the Limiter for requests with the method and URL path,
+// like stricter limits for login. The route is limited separately from the others.
+func WithRouteLimiter(method, path string, l Limiter) RateLimitOpt {
+	return func(rl *rateLimit) {
+		rl.routes[method+" "+path] = l
+	}
+}
+
+// WithLimitHandler sets the handler that responds to requests that exceed the limit.
+// By default, a plain-text 429 response is sent.
+func WithLimitHandler(h http.HandlerFunc) RateLimitOpt {
+	return func(rl *rateLimit) {
+		rl.onLimit = h
+	}
+}
+
+// WithErrorHandler sets the func that is called when the Limiter returns an error,
+// usually to log it. Requests are allowed when the Limiter fails.
+func WithErrorHandler(f func(r *http.Request, err error)) RateLimitOpt {
+	return func(rl *rateLimit) {
+		rl.onError = f
+	}
+}
+
+type rateLimit struct {
+	limiter Limiter
+	routes  map[string]Limiter
+	key     KeyFunc
+	onLimit http.HandlerFunc
+	onError func(r *http.Request, err error)
+}
+
+// RateLimit returns a middleware that rate-limits requests using the Limiter.
+// The RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers (as in the
+// IETF draft "RateLimit Header Fields for HTTP") are set on every response, and
+// Retry-After is set on responses to requests that exceed the limit.
+func RateLimit(l Limiter, opts ...RateLimitOpt) Func {
+	rl := &rateLimit{
+		limiter: l,
+		routes:  make(map[string]Limiter),
+		key:     RemoteIP,
+		onLimit: func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		},
+		onError: func(*http.Request, error) {},
+	}
+	for _, opt := range opts {
+		opt(rl)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limiter, key := rl.limiter, rl.key(r)
+			if l, ok := rl.routes[r.Method+" "+r.URL.Path]; ok {
+				limiter, key = l, r.Method+" "+r.URL.Path+":"+key
+			}
+
+			res, err := limiter.Allow(key)
+			if err != nil {
+				rl.onError(r, err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", seconds(res.Reset))
+			if !res.Allowed {
+				h.Set("Retry-After", seconds(res.RetryAfter))
+				rl.onLimit(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// seconds formats the duration as whole seconds, rounded up, so that clients do
+// not retry too early.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,172 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/mediocregopher/radix/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func TestTokenBucket_Allow(t *testing.T) {
+	t.Parallel()
+	clock := &fakeClock{time.Unix(1000, 0)}
+	tb := NewTokenBucket(2, 10*time.Second)
+	tb.now = clock.now
+
+	res, _ := tb.Allow("foo")
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+
+	res, _ = tb.Allow("foo")
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, 10*time.Second, res.Reset)
+
+	res, _ = tb.Allow("foo")
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 5*time.Second, res.RetryAfter)
+
+	// other keys have their own buckets
+	res, _ = tb.Allow("bar")
+	assert.True(t, res.Allowed)
+
+	clock.t = clock.t.Add(5 * time.Second)
+	res, _ = tb.Allow("foo")
+	assert.True(t, res.Allowed)
+
+	// full buckets are cleaned up
+	clock.t = clock.t.Add(time.Minute)
+	_, _ = tb.Allow("baz")
+	assert.Len(t, tb.buckets, 1)
+}
+
+func TestSlidingWindow_Allow(t *testing.T) {
+	t.Parallel()
+	mock, _ := miniredis.Run()
+	defer mock.Close()
+	pool, _ := radix.NewPool("tcp", mock.Addr(), 1)
+	defer pool.Close()
+
+	// the time is taken from redis
+	now := time.Unix(1000, 0)
+	mock.SetTime(now)
+	sw := NewSlidingWindow(pool, 2, 10*time.Second, "ratelimit:")
+
+	res, err := sw.Allow("foo")
+	assert.Nil(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+	assert.Equal(t, 10*time.Second, res.Reset)
+
+	now = now.Add(4 * time.Second)
+	mock.SetTime(now)
+	res, _ = sw.Allow("foo")
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	// the first request leaves the window first
+	assert.Equal(t, 6*time.Second, res.Reset)
+
+	res, _ = sw.Allow("foo")
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 6*time.Second, res.RetryAfter)
+
+	// the first request leaves the window
+	now = now.Add(6 * time.Second)
+	mock.SetTime(now)
+	res, _ = sw.Allow("foo")
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 4*time.Second, res.Reset)
+	assert.True(t, mock.Exists("ratelimit:foo"))
+}
+
+type errLimiter struct{}
+
+func (errLimiter) Allow(string) (*RateLimitResult, error) {
+	return nil, errors.New("limiter is down")
+}
+
+func TestRateLimit(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	newRequest := func(method, path string) *http.Request {
+		r := httptest.NewRequest(method, path, nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		return r
+	}
+
+	t.Run("set headers and reject requests over the limit", func(t *testing.T) {
+		t.Parallel()
+		h := RateLimit(NewTokenBucket(1, time.Minute))(ok)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, newRequest(http.MethodGet, "/"))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "1", rr.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "60", rr.Header().Get("RateLimit-Reset"))
+
+		rr = httptest.NewRecorder()
+		h.ServeHTTP(rr, newRequest(http.MethodGet, "/"))
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+	})
+
+	t.Run("use route limiter", func(t *testing.T) {
+		t.Parallel()
+		var limited bool
+		h := RateLimit(
+			NewTokenBucket(10, time.Minute),
+			WithRouteLimiter(http.MethodPost, "/login", NewTokenBucket(1, time.Minute)),
+			WithLimitHandler(func(w http.ResponseWriter, r *http.Request) {
+				limited = true
+				w.WriteHeader(http.StatusTooManyRequests)
+			}),
+		)(ok)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, newRequest(http.MethodPost, "/login"))
+		assert.Equal(t, "1", rr.Header().Get("RateLimit-Limit"))
+
+		rr = httptest.NewRecorder()
+		h.ServeHTTP(rr, newRequest(http.MethodPost, "/login"))
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.True(t, limited)
+
+		// other routes use the default limiter
+		rr = httptest.NewRecorder()
+		h.ServeHTTP(rr, newRequest(http.MethodGet, "/login"))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "10", rr.Header().Get("RateLimit-Limit"))
+	})
+
+	t.Run("allow requests when limiter fails", func(t *testing.T) {
+		t.Parallel()
+		var gotErr error
+		h := RateLimit(errLimiter{}, WithErrorHandler(func(r *http.Request, err error) {
+			gotErr = err
+		}))(ok)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, newRequest(http.MethodGet, "/"))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Error(t, gotErr)
+	})
+}
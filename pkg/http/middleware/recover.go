@@ -0,0 +1,31 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package middleware
+
+import (
+	"net/http"
+	"runtime/debug"
+)
+
+// Recover returns a middleware that recovers from panics in the handlers, so that
+// a panic fails only its request, and not the whole server. onPanic is called with
+// the recovered value and the stack trace, and must write the response.
+func Recover(onPanic func(w http.ResponseWriter, r *http.Request, v interface{}, stack []byte)) Func {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				// http.ErrAbortHandler is used to abort a response on purpose, so it
+				// is re-panicked for the server to handle
+				if v := recover(); v != nil && v != http.ErrAbortHandler {
+					onPanic(w, r, v, debug.Stack())
+				} else if v != nil {
+					panic(v)
+				}
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecover(t *testing.T) {
+	t.Run("call onPanic on panic", func(t *testing.T) {
+		t.Parallel()
+		var got interface{}
+		h := Recover(func(w http.ResponseWriter, r *http.Request, v interface{}, stack []byte) {
+			got = v
+			assert.NotEmpty(t, stack)
+			w.WriteHeader(http.StatusInternalServerError)
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}))
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, "boom", got)
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+
+	t.Run("re-panic on ErrAbortHandler", func(t *testing.T) {
+		t.Parallel()
+		h := Recover(func(http.ResponseWriter, *http.Request, interface{}, []byte) {
+			t.Error("onPanic must not be called")
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		}))
+
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+	})
+}
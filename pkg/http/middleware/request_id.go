@@ -0,0 +1,65 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader is the header that carries the ID of a request.
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDCtxKey struct{}
+
+// RequestID returns a middleware that assigns an ID to each request, and adds it
+// to the request context and the response headers. The ID in the X-Request-ID
+// header of the request is used if it is valid (like when set by a load-balancer),
+// so that a request can be traced across services.
+func RequestID() Func {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !isValidRequestID(id) {
+				id = newRequestID()
+			}
+
+			w.Header().Set(RequestIDHeader, id)
+			ctx := context.WithValue(r.Context(), requestIDCtxKey{}, id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequestIDFromContext returns the ID of the request from the context. An empty
+// string is returned if there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDCtxKey{}).(string)
+	return id
+}
+
+// isValidRequestID checks if the ID is short, and only contains characters that
+// are safe to log.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && !(r >= '0' && r <= '9') && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	// rand.Read only fails if the OS cannot provide randomness, in which case an
+	// all-zero ID is still usable
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
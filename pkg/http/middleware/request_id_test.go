@@ -0,0 +1,51 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		reuse    bool
+	}{
+		{"generate id when absent", "", false},
+		{"reuse valid id", "abc-123_DEF", true},
+		{"replace invalid id", "abc\n123", false},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var got string
+			h := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = RequestIDFromContext(r.Context())
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.incoming != "" {
+				r.Header.Set(RequestIDHeader, tc.incoming)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, r)
+
+			assert.NotEmpty(t, got)
+			assert.Equal(t, got, rr.Header().Get(RequestIDHeader))
+			if tc.reuse {
+				assert.Equal(t, tc.incoming, got)
+			} else {
+				assert.Len(t, got, 32)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// SecurityHeadersOptions represents the options of the SecurityHeaders middleware.
+type SecurityHeadersOptions struct {
+	// HSTSMaxAge (in seconds) is the max-age of the Strict-Transport-Security header,
+	// which is only sent over TLS. 0 disables the header.
+	HSTSMaxAge int
+}
+
+// SecurityHeaders returns a middleware that sets headers that harden responses
+// against common browser-based attacks, like clickjacking and MIME-sniffing. Since
+// the API only serves JSON, all content is forbidden from being loaded or framed.
+func SecurityHeaders(opts SecurityHeadersOptions) Func {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("X-Content-Type-Options", "nosniff")
+			h.Set("X-Frame-Options", "DENY")
+			h.Set("Referrer-Policy", "no-referrer")
+			h.Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+			if r.TLS != nil && opts.HSTSMaxAge > 0 {
+				h.Set("Strict-Transport-Security", "max-age="+strconv.Itoa(opts.HSTSMaxAge)+"; includeSubDomains")
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,38 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	h := SecurityHeaders(SecurityHeadersOptions{HSTSMaxAge: 60})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	t.Run("set headers", func(t *testing.T) {
+		t.Parallel()
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, "nosniff", rr.Header().Get("X-Content-Type-Options"))
+		assert.Equal(t, "DENY", rr.Header().Get("X-Frame-Options"))
+		assert.Empty(t, rr.Header().Get("Strict-Transport-Security"))
+	})
+
+	t.Run("set hsts over tls", func(t *testing.T) {
+		t.Parallel()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.TLS = &tls.ConnectionState{}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, r)
+
+		assert.Equal(t, "max-age=60; includeSubDomains", rr.Header().Get("Strict-Transport-Security"))
+	})
+}
@@ -0,0 +1,112 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/mediocregopher/radix/v3"
+)
+
+// slidingWindowScript records a request in the sorted set of the key, scored by its
+// time (in ms), after removing the requests that are out of the window. The request
+// is only recorded if it is within the limit. It returns whether the request is
+// allowed, the no. of requests in the window, and the time until the oldest one
+// leaves the window.
+//
+// The time is taken from Redis, so that the clocks of the replicas of the server
+// need not be in sync. Since TIME is non-deterministic, the effects of the script
+// are replicated instead of the script itself.
+var slidingWindowScript = radix.NewEvalScript(1, `
+redis.replicate_commands()
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local count = redis.call("ZCARD", KEYS[1])
+local allowed = 0
+if count < limit then
+	redis.call("ZADD", KEYS[1], now, ARGV[3])
+	count = count + 1
+	allowed = 1
+end
+redis.call("PEXPIRE", KEYS[1], window)
+
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+return {allowed, count, tonumber(oldest[2] or now) + window - now}
+`)
+
+// SlidingWindow is a Redis-backed Limiter, that uses the sliding window log
+// algorithm. The times of the requests of each key in the last window are stored
+// in Redis, so the limit is shared by all the replicas of the server.
+type SlidingWindow struct {
+	client radix.Client
+	limit  int
+	window time.Duration
+	prefix string
+
+	// id and seq make the members of the sets unique across the replicas, since
+	// requests can be made at the same ms.
+	id  string
+	seq uint64
+}
+
+// NewSlidingWindow creates a new *SlidingWindow, that allows limit requests in any
+// window of time. Keys are stored in Redis with the prefix.
+func NewSlidingWindow(client radix.Client, limit int, window time.Duration, prefix string) *SlidingWindow {
+	return &SlidingWindow{
+		client: client,
+		limit:  limit,
+		window: window,
+		prefix: prefix,
+		id:     instanceID(),
+	}
+}
+
+// instanceID returns a random ID for a SlidingWindow. The time is included, in case
+// the random bytes cannot be read.
+func instanceID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return strconv.FormatInt(time.Now().UnixNano(), 36) + hex.EncodeToString(b)
+}
+
+// Allow records the request in the window of the key, if it is within the limit.
+// The limit is partly available again when the oldest request in the window leaves
+// it, which is when it resets.
+func (sw *SlidingWindow) Allow(key string) (*RateLimitResult, error) {
+	member := sw.id + "-" + strconv.FormatUint(atomic.AddUint64(&sw.seq, 1), 10)
+
+	var reply []int64
+	err := sw.client.Do(slidingWindowScript.Cmd(&reply, sw.prefix+key,
+		strconv.FormatInt(int64(sw.window/time.Millisecond), 10),
+		strconv.Itoa(sw.limit),
+		member,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, count := reply[0] == 1, int(reply[1])
+	// the oldest request is the next one to leave the window
+	untilOldestExpires := time.Duration(reply[2]) * time.Millisecond
+
+	res := &RateLimitResult{
+		Allowed:   allowed,
+		Limit:     sw.limit,
+		Remaining: sw.limit - count,
+		Reset:     untilOldestExpires,
+	}
+	if !allowed {
+		res.RetryAfter = untilOldestExpires
+	}
+	return res, nil
+}
@@ -0,0 +1,96 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package middleware
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenBucket is an in-memory Limiter, that uses the token bucket algorithm. Each
+// key has a bucket of tokens that refills at a constant rate, and each request
+// takes a token from it. Since the buckets are in-memory, the limit applies to
+// each replica of the server separately.
+type TokenBucket struct {
+	mu       sync.Mutex
+	capacity float64
+	rate     float64 // tokens per second
+	buckets  map[string]*bucket
+	now      func() time.Time
+	cleaned  time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// NewTokenBucket creates a new *TokenBucket, that allows bursts of upto limit
+// requests, and refills completely over window.
+func NewTokenBucket(limit int, window time.Duration) *TokenBucket {
+	return &TokenBucket{
+		capacity: float64(limit),
+		rate:     float64(limit) / window.Seconds(),
+		buckets:  make(map[string]*bucket),
+		now:      time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of the key, if there is one.
+func (tb *TokenBucket) Allow(key string) (*RateLimitResult, error) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := tb.now()
+	tb.cleanup(now)
+
+	b, ok := tb.buckets[key]
+	if !ok {
+		b = &bucket{tokens: tb.capacity, updated: now}
+		tb.buckets[key] = b
+	}
+	b.refill(now, tb.rate, tb.capacity)
+
+	res := &RateLimitResult{Limit: int(tb.capacity)}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = tb.duration(1 - b.tokens)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = tb.duration(tb.capacity - b.tokens)
+	return res, nil
+}
+
+// cleanup removes the buckets that have refilled completely, since they are the
+// same as new ones. It runs at most once per refill period, so that the map does
+// not grow with every client ever seen.
+func (tb *TokenBucket) cleanup(now time.Time) {
+	period := tb.duration(tb.capacity)
+	if now.Sub(tb.cleaned) < period {
+		return
+	}
+
+	for k, b := range tb.buckets {
+		if now.Sub(b.updated) >= period {
+			delete(tb.buckets, k)
+		}
+	}
+	tb.cleaned = now
+}
+
+// duration returns the time it takes to refill the no. of tokens.
+func (tb *TokenBucket) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / tb.rate * float64(time.Second))
+}
+
+func (b *bucket) refill(now time.Time, rate, capacity float64) {
+	b.tokens += now.Sub(b.updated).Seconds() * rate
+	if b.tokens > capacity {
+		b.tokens = capacity
+	}
+	b.updated = now
+}
@@ -0,0 +1,207 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+// Package i18n provides message catalogues for translating user-facing messages,
+// like error messages and emails, to the locale of the user.
+package i18n
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"adeia/pkg/http/middleware"
+
+	"github.com/golang/gddo/httputil/header"
+)
+
+// DefaultLocale is the locale that is used when a message is not available in
+// the requested locale.
+const DefaultLocale = "en"
+
+const catalogueExt = ".json"
+
+// Catalogue holds the messages of all the locales, keyed by their message key.
+// Error messages are keyed as "errors.<ErrorCode>", and email messages as
+// "<template>.<name>".
+type Catalogue struct {
+	messages map[string]map[string]string
+}
+
+// Load loads all the message catalogues in dir. Each catalogue is a flat JSON
+// object of keys and messages, named after its locale, like "ta.json".
+func Load(dir string) (*Catalogue, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+catalogueExt))
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Catalogue{make(map[string]map[string]string)}
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		var msgs map[string]string
+		if err := json.Unmarshal(b, &msgs); err != nil {
+			return nil, fmt.Errorf("cannot parse catalogue %q: %v", f, err)
+		}
+		c.messages[normalize(strings.TrimSuffix(filepath.Base(f), catalogueExt))] = msgs
+	}
+	return c, nil
+}
+
+// Locales returns all the supported locales, sorted. DefaultLocale is always supported.
+func (c *Catalogue) Locales() []string {
+	locales := []string{DefaultLocale}
+	for l := range c.messages {
+		if l != DefaultLocale {
+			locales = append(locales, l)
+		}
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Lookup returns the message of the key in the locale. If the locale doesn't have
+// the message, its base language (for "ta-IN", "ta") and then DefaultLocale are tried.
+func (c *Catalogue) Lookup(locale, key string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	for _, l := range fallbacks(locale) {
+		if msg, ok := c.messages[l][key]; ok {
+			return msg, true
+		}
+	}
+	return "", false
+}
+
+// Match returns the supported locale that best matches the Accept-Language header,
+// or DefaultLocale if none of them match.
+func (c *Catalogue) Match(h http.Header) string {
+	specs := header.ParseAccept(h, "Accept-Language")
+	sort.SliceStable(specs, func(i, j int) bool {
+		return specs[i].Q > specs[j].Q
+	})
+
+	for _, s := range specs {
+		if s.Q == 0 {
+			continue
+		}
+		if l := c.supported(s.Value); l != "" {
+			return l
+		}
+	}
+	return DefaultLocale
+}
+
+// supported returns the locale, or its base language, if any of them is supported.
+func (c *Catalogue) supported(locale string) string {
+	for _, l := range candidates(locale) {
+		if _, ok := c.messages[l]; ok || l == DefaultLocale {
+			return l
+		}
+	}
+	return ""
+}
+
+// Localizer translates messages to a single locale.
+type Localizer struct {
+	catalogue *Catalogue
+	locale    string
+}
+
+// NewLocalizer creates a new *Localizer that translates to the locale.
+func NewLocalizer(c *Catalogue, locale string) *Localizer {
+	return &Localizer{c, normalize(locale)}
+}
+
+// Locale returns the locale of the Localizer. It returns DefaultLocale, if l is nil.
+func (l *Localizer) Locale() string {
+	if l == nil || l.locale == "" {
+		return DefaultLocale
+	}
+	return l.locale
+}
+
+// Translate returns the message of the key, formatted with args (like fmt.Sprintf).
+// The fallback is used when the message is not present in any catalogue, or when
+// l is nil.
+func (l *Localizer) Translate(key, fallback string, args ...interface{}) string {
+	msg := fallback
+	if l != nil {
+		if m, ok := l.catalogue.Lookup(l.locale, key); ok {
+			msg = m
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx that carries the Localizer.
+func NewContext(ctx context.Context, l *Localizer) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the Localizer carried by ctx, or nil if there is none. A
+// nil *Localizer can be used, and always returns the fallback messages.
+func FromContext(ctx context.Context) *Localizer {
+	l, _ := ctx.Value(ctxKey{}).(*Localizer)
+	return l
+}
+
+// Middleware returns a middleware that adds a Localizer to the request context.
+// The locale returned by preferred (like the saved locale of the authenticated
+// user) is used if it is supported, and the locale that best matches the
+// Accept-Language header otherwise. preferred can be nil.
+func Middleware(c *Catalogue, preferred func(r *http.Request) string) middleware.Func {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			locale := ""
+			if preferred != nil {
+				if p := preferred(r); p != "" {
+					locale = c.supported(p)
+				}
+			}
+			if locale == "" {
+				locale = c.Match(r.Header)
+			}
+
+			l := NewLocalizer(c, locale)
+			w.Header().Set("Content-Language", l.Locale())
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), l)))
+		})
+	}
+}
+
+// candidates returns the locale, followed by its base language, if any.
+func candidates(locale string) []string {
+	locale = normalize(locale)
+	c := []string{locale}
+	if i := strings.IndexByte(locale, '-'); i > 0 {
+		c = append(c, locale[:i])
+	}
+	return c
+}
+
+// fallbacks returns the locales to look up messages in, in order, for the locale.
+func fallbacks(locale string) []string {
+	return append(candidates(locale), DefaultLocale)
+}
+
+// normalize converts locales like "ta_IN" or "TA-in" to "ta-in".
+func normalize(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
@@ -0,0 +1,180 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package i18n
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setup(t *testing.T) *Catalogue {
+	dir, err := ioutil.TempDir("", "i18n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	files := map[string]string{
+		"en.json":    `{"greeting": "Hello %s", "only_en": "english"}`,
+		"ta.json":    `{"greeting": "வணக்கம் %s"}`,
+		"pt_BR.json": `{"greeting": "Olá %s"}`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("load catalogues", func(t *testing.T) {
+		t.Parallel()
+		c := setup(t)
+		assert.Equal(t, []string{"en", "pt-br", "ta"}, c.Locales())
+	})
+
+	t.Run("non-existent dir", func(t *testing.T) {
+		t.Parallel()
+		c, err := Load("non-existent")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"en"}, c.Locales())
+	})
+
+	t.Run("invalid catalogue", func(t *testing.T) {
+		t.Parallel()
+		dir, _ := ioutil.TempDir("", "i18n")
+		defer os.RemoveAll(dir)
+		_ = ioutil.WriteFile(filepath.Join(dir, "ta.json"), []byte("{"), 0600)
+
+		_, err := Load(dir)
+		assert.Error(t, err)
+	})
+}
+
+func TestCatalogue_Lookup(t *testing.T) {
+	c := setup(t)
+
+	testcases := []struct {
+		locale string
+		key    string
+		want   string
+		ok     bool
+		msg    string
+	}{
+		{"ta", "greeting", "வணக்கம் %s", true, "exact locale"},
+		{"ta-IN", "greeting", "வணக்கம் %s", true, "fallback to base language"},
+		{"PT_br", "greeting", "Olá %s", true, "locales are normalized"},
+		{"ta", "only_en", "english", true, "fallback to default locale"},
+		{"ta", "foobar", "", false, "unknown key"},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.msg, func(t *testing.T) {
+			t.Parallel()
+			got, ok := c.Lookup(tc.locale, tc.key)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestCatalogue_Match(t *testing.T) {
+	c := setup(t)
+
+	testcases := []struct {
+		accept string
+		want   string
+		msg    string
+	}{
+		{"", "en", "no header"},
+		{"ta", "ta", "supported locale"},
+		{"ta-IN,ta;q=0.9", "ta", "base language of locale"},
+		{"fr, ta;q=0.5", "ta", "skip unsupported locales"},
+		{"en;q=0.5, pt-BR", "pt-br", "highest quality first"},
+		{"fr, de", "en", "no supported locale"},
+		{"ta;q=0", "en", "not acceptable"},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.msg, func(t *testing.T) {
+			t.Parallel()
+			h := http.Header{}
+			if tc.accept != "" {
+				h.Set("Accept-Language", tc.accept)
+			}
+			assert.Equal(t, tc.want, c.Match(h))
+		})
+	}
+}
+
+func TestLocalizer_Translate(t *testing.T) {
+	c := setup(t)
+
+	t.Run("translate message", func(t *testing.T) {
+		t.Parallel()
+		l := NewLocalizer(c, "ta")
+		assert.Equal(t, "வணக்கம் foo", l.Translate("greeting", "Hi %s", "foo"))
+	})
+
+	t.Run("fallback message", func(t *testing.T) {
+		t.Parallel()
+		l := NewLocalizer(c, "ta")
+		assert.Equal(t, "Bye foo", l.Translate("farewell", "Bye %s", "foo"))
+	})
+
+	t.Run("nil localizer", func(t *testing.T) {
+		t.Parallel()
+		var l *Localizer
+		assert.Equal(t, "Hi foo", l.Translate("greeting", "Hi %s", "foo"))
+		assert.Equal(t, DefaultLocale, l.Locale())
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+	c := setup(t)
+
+	serve := func(preferred func(*http.Request) string, acceptLanguage string) (*Localizer, *httptest.ResponseRecorder) {
+		var got *Localizer
+		h := Middleware(c, preferred)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = FromContext(r.Context())
+		}))
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Language", acceptLanguage)
+		h.ServeHTTP(w, r)
+		return got, w
+	}
+
+	t.Run("use Accept-Language", func(t *testing.T) {
+		got, w := serve(nil, "ta-IN")
+		assert.Equal(t, "ta", got.Locale())
+		assert.Equal(t, "ta", w.Header().Get("Content-Language"))
+	})
+
+	t.Run("prefer the preferred locale", func(t *testing.T) {
+		got, w := serve(func(*http.Request) string { return "ta-IN" }, "en")
+		assert.Equal(t, "ta", got.Locale())
+		assert.Equal(t, "ta", w.Header().Get("Content-Language"))
+	})
+
+	t.Run("ignore unsupported preferred locales", func(t *testing.T) {
+		got, _ := serve(func(*http.Request) string { return "fr" }, "ta")
+		assert.Equal(t, "ta", got.Locale())
+	})
+}
@@ -4,6 +4,8 @@
 
 package log
 
+import "context"
+
 // Logger is the interface for all the functions of a logger.
 type Logger interface {
 	Debug(args ...interface{})
@@ -15,4 +17,31 @@ type Logger interface {
 	Sync() error
 	Warn(args ...interface{})
 	Warnf(template string, args ...interface{})
+
+	// With returns a child Logger that adds the structured fields to every log
+	// line. The fields are passed as alternating keys and values, like
+	// With("request_id", id, "employee_id", empID).
+	With(fields ...interface{}) Logger
+}
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx that carries the Logger, which is usually
+// request-scoped, with fields identifying the request.
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the Logger carried by ctx, or fallback if there is none.
+func FromContext(ctx context.Context, fallback Logger) Logger {
+	if l, ok := ctx.Value(ctxKey{}).(Logger); ok {
+		return l
+	}
+	return fallback
+}
+
+// Leveler is implemented by loggers whose level can be changed at runtime.
+type Leveler interface {
+	Level() string
+	SetLevel(level string) error
 }
@@ -0,0 +1,33 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package log
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type nopLogger struct {
+	Logger
+	name string
+}
+
+func TestFromContext(t *testing.T) {
+	fallback := &nopLogger{name: "fallback"}
+
+	t.Run("return logger in context", func(t *testing.T) {
+		t.Parallel()
+		l := &nopLogger{name: "request"}
+		ctx := NewContext(context.Background(), l)
+		assert.Same(t, l, FromContext(ctx, fallback))
+	})
+
+	t.Run("return fallback when context has no logger", func(t *testing.T) {
+		t.Parallel()
+		assert.Same(t, fallback, FromContext(context.Background(), fallback))
+	})
+}
@@ -6,12 +6,18 @@ package zap
 
 import (
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"adeia/internal/config"
+	"adeia/pkg/log"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 // levels is a map of supported log levels.
@@ -27,27 +33,123 @@ var levels = map[string]zapcore.Level{
 // Logger represents a logger that can log messages.
 type Logger struct {
 	*zap.SugaredLogger
+	level zap.AtomicLevel
 }
 
-// New creates a new Logger with the specified conf.
+// New creates a new Logger with the specified conf. Paths other than stdout and
+// stderr are files, that are rotated as per conf.Rotation.
 func New(conf *config.LoggerConfig) (*Logger, error) {
 	level, err := parseLevel(conf.Level)
 	if err != nil {
 		return nil, err
 	}
 
-	// TODO: switch to custom config
-	// TODO: setup log rotation
-	cfg := zap.NewDevelopmentConfig()
-	cfg.Level = zap.NewAtomicLevelAt(level)
-	cfg.OutputPaths = conf.Paths
+	enc, err := newEncoder(conf.Encoding)
+	if err != nil {
+		return nil, err
+	}
 
-	l, err := cfg.Build(zap.AddCallerSkip(1))
+	out, err := newWriteSyncer(conf.Paths, &conf.Rotation)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Logger{l.Sugar()}, nil
+	atom := zap.NewAtomicLevelAt(level)
+	core := zapcore.NewCore(enc, out, atom)
+	if conf.Sampling.Initial > 0 {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, conf.Sampling.Initial, conf.Sampling.Thereafter)
+	}
+
+	opts := []zap.Option{zap.ErrorOutput(zapcore.Lock(os.Stderr)), zap.AddCallerSkip(1)}
+	if conf.Caller {
+		opts = append(opts, zap.AddCaller())
+	}
+	if conf.StacktraceLevel != "" {
+		stLevel, err := parseLevel(conf.StacktraceLevel)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, zap.AddStacktrace(stLevel))
+	}
+
+	return &Logger{zap.New(core, opts...).Sugar(), atom}, nil
+}
+
+// With returns a child Logger that adds the structured fields (alternating keys
+// and values) to every log line. It shares the level of its parent.
+func (l *Logger) With(fields ...interface{}) log.Logger {
+	return &Logger{l.SugaredLogger.With(fields...), l.level}
+}
+
+// Level returns the current log level of the Logger.
+func (l *Logger) Level() string {
+	return l.level.Level().String()
+}
+
+// SetLevel changes the log level of the Logger, and all its children, at runtime.
+func (l *Logger) SetLevel(s string) error {
+	level, err := parseLevel(s)
+	if err != nil {
+		return err
+	}
+	l.level.SetLevel(level)
+	return nil
+}
+
+// newEncoder returns the zapcore.Encoder for the encoding, which is either console
+// (human-readable, for development) or json (for production).
+func newEncoder(encoding string) (zapcore.Encoder, error) {
+	switch strings.ToLower(encoding) {
+	case "", "console":
+		return zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig()), nil
+	case "json":
+		cfg := zap.NewProductionEncoderConfig()
+		cfg.EncodeTime = zapcore.ISO8601TimeEncoder
+		return zapcore.NewJSONEncoder(cfg), nil
+	}
+
+	return nil, fmt.Errorf("specified log encoding %q is not one of ['console', 'json']", encoding)
+}
+
+// newWriteSyncer returns a zapcore.WriteSyncer that writes to all the paths. Files
+// are written using a lumberjack.Logger, that rotates them as per rotation.
+func newWriteSyncer(paths []string, rotation *config.LogRotationConfig) (zapcore.WriteSyncer, error) {
+	syncers := make([]zapcore.WriteSyncer, 0, len(paths))
+	for _, path := range paths {
+		switch path {
+		case "stdout":
+			syncers = append(syncers, zapcore.Lock(os.Stdout))
+		case "stderr":
+			syncers = append(syncers, zapcore.Lock(os.Stderr))
+		default:
+			// lumberjack opens the file lazily, so check that it can be written to
+			if err := checkWritable(path); err != nil {
+				return nil, err
+			}
+			syncers = append(syncers, zapcore.AddSync(&lumberjack.Logger{
+				Filename:   path,
+				MaxSize:    rotation.MaxSize,
+				MaxAge:     rotation.MaxAge,
+				MaxBackups: rotation.MaxBackups,
+				Compress:   rotation.Compress,
+			}))
+		}
+	}
+
+	return zapcore.NewMultiWriteSyncer(syncers...), nil
+}
+
+// checkWritable checks if the log file at path can be opened for writing, creating
+// it (and its directory) if it does not exist.
+func checkWritable(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("cannot create log directory: %v", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("cannot open log file: %v", err)
+	}
+	return f.Close()
 }
 
 // parseLevel returns the appropriate zapcore.Level for the passed-in string.
@@ -5,15 +5,16 @@
 package zap
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 
 	"adeia/internal/config"
 
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestParseLevel(t *testing.T) {
@@ -50,22 +51,78 @@ func TestNew(t *testing.T) {
 		assert.Error(t, err)
 	})
 
+	t.Run("return error on invalid encoding", func(t *testing.T) {
+		t.Parallel()
+		c := &config.LoggerConfig{Level: "debug", Encoding: "xml"}
+		_, err := New(c)
+		assert.Error(t, err)
+	})
+
+	t.Run("return error on invalid stacktrace level", func(t *testing.T) {
+		t.Parallel()
+		c := &config.LoggerConfig{Level: "debug", StacktraceLevel: "foobar123"}
+		_, err := New(c)
+		assert.Error(t, err)
+	})
+
 	t.Run("return logger on valid config", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "logs", "adeia.log")
 		c := &config.LoggerConfig{
-			Level: "debug",
-			Paths: []string{"stderr"},
+			Level:           "info",
+			Encoding:        "json",
+			Paths:           []string{path},
+			StacktraceLevel: "error",
+			Sampling:        config.LogSamplingConfig{Initial: 100, Thereafter: 100},
+			Rotation:        config.LogRotationConfig{MaxSize: 1},
 		}
 		got, err := New(c)
+		assert.Nil(t, err)
 
-		cfg := zap.NewDevelopmentConfig()
-		cfg.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
-		cfg.OutputPaths = []string{"stderr"}
-		l, _ := cfg.Build(zap.AddCallerSkip(1))
-		want := &Logger{l.Sugar()}
+		got.Debug("foo")
+		got.With("request_id", "bar").Info("baz")
+		assert.Nil(t, got.Sync())
 
+		b, err := ioutil.ReadFile(path)
 		assert.Nil(t, err)
-		if !cmp.Equal(want, got, cmpopts.IgnoreUnexported(zap.SugaredLogger{})) {
-			t.Errorf("want: %v, got :%v", want, got)
-		}
+		var line map[string]interface{}
+		assert.Nil(t, json.Unmarshal(b, &line), "only the info line should be logged, as json")
+		assert.Equal(t, "baz", line["msg"])
+		assert.Equal(t, "bar", line["request_id"])
 	})
 }
+
+func TestLogger_SetLevel(t *testing.T) {
+	t.Parallel()
+	core, logs := observer.New(zap.DebugLevel)
+	atom := zap.NewAtomicLevelAt(zap.InfoLevel)
+	l := &Logger{zap.New(core, zap.IncreaseLevel(atom)).Sugar(), atom}
+	child := l.With("foo", "bar")
+
+	child.Debug("dropped")
+	assert.Equal(t, "info", l.Level())
+
+	assert.Error(t, l.SetLevel("foobar123"))
+	assert.Nil(t, l.SetLevel("debug"))
+	assert.Equal(t, "debug", l.Level())
+
+	// children share the level of their parent
+	child.Debug("logged")
+	assert.Equal(t, 1, logs.Len())
+	assert.Equal(t, "logged", logs.All()[0].Message)
+}
+
+func TestLogger_With(t *testing.T) {
+	t.Parallel()
+	core, logs := observer.New(zap.DebugLevel)
+	l := &Logger{SugaredLogger: zap.New(core).Sugar()}
+
+	l.With("request_id", "foo").With("employee_id", "bar").Info("hello")
+	l.Info("world")
+
+	entries := logs.AllUntimed()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, map[string]interface{}{"request_id": "foo", "employee_id": "bar"}, entries[0].ContextMap())
+	// the parent logger is not changed
+	assert.Empty(t, entries[1].Context)
+}
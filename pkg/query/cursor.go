@@ -0,0 +1,109 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"adeia/pkg/util/crypto"
+)
+
+// Cursor represents the position of the last row of a page. It is sent to the
+// client as an opaque string, and the next page starts right after it.
+type Cursor struct {
+	// Sort is the sort param that the Cursor was created for. A Cursor is only
+	// valid for the same sort order.
+	Sort string `json:"s"`
+
+	// Value is the value of the sort column in the last row.
+	Value string `json:"v"`
+
+	// ID is the value of the id column in the last row.
+	ID int `json:"id"`
+}
+
+func (c *Cursor) encode() (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return crypto.EncodeBase64(b), nil
+}
+
+func decodeCursor(s string) (*Cursor, error) {
+	b, err := crypto.DecodeBase64(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// NextCursor trims the extra row fetched by Build off rows, and returns the cursor
+// that points to the next page. An empty string is returned when there are no more
+// rows. rows must be a pointer to a slice of structs (or pointers to structs), with
+// `db` tags for the sort and id columns.
+func (s *Spec) NextCursor(rows interface{}) (string, error) {
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return "", errors.New("rows must be a pointer to a slice")
+	}
+
+	slice := v.Elem()
+	if slice.Len() <= s.Limit {
+		return "", nil
+	}
+	slice.Set(slice.Slice(0, s.Limit))
+
+	last := reflect.Indirect(slice.Index(s.Limit - 1))
+	value, ok := columnValue(last, s.Sort.Column)
+	if !ok {
+		return "", fmt.Errorf("no field with db tag %q", s.Sort.Column)
+	}
+	id, ok := columnValue(last, idColumn)
+	if !ok {
+		return "", fmt.Errorf("no field with db tag %q", idColumn)
+	}
+	n, ok := id.(int)
+	if !ok {
+		return "", fmt.Errorf("column %q must be an int", idColumn)
+	}
+
+	sortBy := s.Sort.Field
+	if s.Sort.Desc {
+		sortBy = "-" + sortBy
+	}
+
+	c := &Cursor{Sort: sortBy, Value: formatValue(value), ID: n}
+	return c.encode()
+}
+
+// columnValue returns the value of the field with the db tag col, in the struct v.
+func columnValue(v reflect.Value, col string) (interface{}, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("db") == col {
+			return v.Field(i).Interface(), true
+		}
+	}
+	return nil, false
+}
+
+// formatValue formats v as a string that the database can parse back into the
+// column's type.
+func formatValue(v interface{}) string {
+	if t, ok := v.(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,67 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type row struct {
+	ID   int    `db:"id"`
+	Name string `db:"full_name"`
+}
+
+func TestSpec_NextCursor(t *testing.T) {
+	s := &Spec{Limit: 2, Sort: Sort{Field: "name", Column: "full_name", Desc: true}}
+
+	t.Run("no more rows", func(t *testing.T) {
+		t.Parallel()
+		rows := []*row{{1, "foo"}, {2, "bar"}}
+		got, err := s.NextCursor(&rows)
+
+		assert.Nil(t, err)
+		assert.Empty(t, got)
+		assert.Len(t, rows, 2)
+	})
+
+	t.Run("more rows", func(t *testing.T) {
+		t.Parallel()
+		rows := []*row{{1, "foo"}, {2, "bar"}, {3, "baz"}}
+		got, err := s.NextCursor(&rows)
+
+		assert.Nil(t, err)
+		assert.Len(t, rows, 2, "extra row must be trimmed")
+
+		c, err := decodeCursor(got)
+		assert.Nil(t, err)
+		assert.Equal(t, &Cursor{Sort: "-name", Value: "bar", ID: 2}, c)
+	})
+
+	t.Run("slice of structs", func(t *testing.T) {
+		t.Parallel()
+		rows := []row{{1, "foo"}, {2, "bar"}, {3, "baz"}}
+		got, err := s.NextCursor(&rows)
+
+		assert.Nil(t, err)
+		assert.NotEmpty(t, got)
+		assert.Len(t, rows, 2)
+	})
+
+	t.Run("rows is not a pointer to a slice", func(t *testing.T) {
+		t.Parallel()
+		_, err := s.NextCursor([]row{})
+		assert.Error(t, err)
+	})
+
+	t.Run("sort column is missing", func(t *testing.T) {
+		t.Parallel()
+		s := &Spec{Limit: 1, Sort: Sort{Field: "email", Column: "email"}}
+		rows := []row{{1, "foo"}, {2, "bar"}}
+		_, err := s.NextCursor(&rows)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,244 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package query
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"adeia/pkg/util/constants"
+)
+
+const (
+	limitParam  = "limit"
+	cursorParam = "cursor"
+	sortParam   = "sort"
+
+	// idColumn is the column used as the tie-breaker when paginating. It must be
+	// unique, so that every row has a well-defined position.
+	idColumn = "id"
+)
+
+var filterParam = regexp.MustCompile(`^filter\[(\w+)]$`)
+
+// Error is the error returned when the query params of a list request are invalid.
+type Error struct {
+	// Param is the query param that is invalid.
+	Param string
+
+	// Message is a message for the user, describing the details of the error.
+	Message string
+}
+
+// Error returns the Message of the Error.
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid query param %q: %s", e.Param, e.Message)
+}
+
+// Type is the type of the values of a filterable field. Filter values are parsed
+// into their Type, so that invalid values are rejected before reaching the database.
+type Type int
+
+// Types of filterable fields.
+const (
+	// String is the Type of text fields. It is the default.
+	String Type = iota
+
+	// Bool is the Type of boolean fields, like `true` or `false`.
+	Bool
+
+	// Int is the Type of integer fields.
+	Int
+
+	// Time is the Type of timestamp fields, in RFC 3339 format.
+	Time
+)
+
+// Field represents a filterable column, along with the Type of its values.
+type Field struct {
+	Column string
+	Type   Type
+}
+
+// Options represents the list of fields that a list endpoint allows sorting and
+// filtering on. Fields are the names used in the query params, and are mapped to
+// their respective columns, so that user input never reaches the SQL directly.
+type Options struct {
+	// Sortable maps the sortable fields to columns.
+	Sortable map[string]string
+
+	// Filterable maps the filterable fields to columns, along with their Types.
+	Filterable map[string]Field
+
+	// DefaultSort is the field to sort on when no sort param is present. A leading
+	// "-" sorts in descending order.
+	DefaultSort string
+}
+
+// Sort represents the sort order of a list.
+type Sort struct {
+	Field  string
+	Column string
+	Desc   bool
+}
+
+// Filter represents an equality filter on a column. Value is parsed as per the
+// Type of the field.
+type Filter struct {
+	Field  string
+	Column string
+	Value  interface{}
+}
+
+// Spec represents a parsed, whitelisted list query.
+type Spec struct {
+	Limit   int
+	Sort    Sort
+	Filters []Filter
+	Cursor  *Cursor
+}
+
+// Parse parses the list query params (like `?limit=10&cursor=...&sort=-name&filter[designation]=foo`)
+// into a Spec. Only the fields in opts are allowed; an *Error is returned otherwise.
+func Parse(q url.Values, opts *Options) (*Spec, error) {
+	s := &Spec{Limit: constants.DefaultPageSize}
+
+	if l := q.Get(limitParam); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit < 1 {
+			return nil, &Error{limitParam, "Limit must be a positive number"}
+		}
+		if limit > constants.MaxPageSize {
+			limit = constants.MaxPageSize
+		}
+		s.Limit = limit
+	}
+
+	sortBy := q.Get(sortParam)
+	if sortBy == "" {
+		sortBy = opts.DefaultSort
+	}
+	s.Sort.Desc = strings.HasPrefix(sortBy, "-")
+	s.Sort.Field = strings.TrimPrefix(sortBy, "-")
+	col, ok := opts.Sortable[s.Sort.Field]
+	if !ok {
+		return nil, &Error{sortParam, fmt.Sprintf("Cannot sort by %v", s.Sort.Field)}
+	}
+	s.Sort.Column = col
+
+	for param, values := range q {
+		m := filterParam.FindStringSubmatch(param)
+		if m == nil {
+			continue
+		}
+
+		field, ok := opts.Filterable[m[1]]
+		if !ok {
+			return nil, &Error{param, fmt.Sprintf("Cannot filter by %v", m[1])}
+		}
+		v, msg := parseValue(values[0], field.Type)
+		if msg != "" {
+			return nil, &Error{param, msg}
+		}
+		s.Filters = append(s.Filters, Filter{Field: m[1], Column: field.Column, Value: v})
+	}
+	// keep the generated SQL stable, irrespective of map ordering
+	sort.Slice(s.Filters, func(i, j int) bool {
+		return s.Filters[i].Field < s.Filters[j].Field
+	})
+
+	if c := q.Get(cursorParam); c != "" {
+		cursor, err := decodeCursor(c)
+		if err != nil || cursor.Sort != sortBy {
+			return nil, &Error{cursorParam, "Cursor is invalid"}
+		}
+		s.Cursor = cursor
+	}
+
+	return s, nil
+}
+
+// parseValue parses the filter value v as per its Type. A message for the user is
+// returned if it is invalid.
+func parseValue(v string, t Type) (interface{}, string) {
+	switch t {
+	case Bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, "Must be true or false"
+		}
+		return b, ""
+	case Int:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, "Must be a number"
+		}
+		return n, ""
+	case Time:
+		tm, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, "Must be a time in RFC 3339 format"
+		}
+		return tm.UTC(), ""
+	}
+	return v, ""
+}
+
+// Build appends the conditions, the filters, the keyset condition, the sort order
+// and the limit of the Spec to the base query. The base query must only contain the
+// SELECT and FROM clauses; the conditions of its WHERE clause are passed in conds
+// instead, and args are their args. One row more than the limit is fetched, so that
+// NextCursor can tell if there are more rows.
+func (s *Spec) Build(base string, conds []string, args ...interface{}) (query string, queryArgs []interface{}) {
+	queryArgs = append(queryArgs, args...)
+	placeholder := func(v interface{}) string {
+		queryArgs = append(queryArgs, v)
+		return "$" + strconv.Itoa(len(queryArgs))
+	}
+
+	conds = append([]string(nil), conds...)
+	for _, f := range s.Filters {
+		conds = append(conds, f.Column+"="+placeholder(f.Value))
+	}
+
+	// the id column is the tie-breaker, which is not needed when sorting by it
+	byID := s.Sort.Column == idColumn
+	op := ">"
+	if s.Sort.Desc {
+		op = "<"
+	}
+	if s.Cursor != nil && byID {
+		conds = append(conds, fmt.Sprintf("%s %s %s", idColumn, op, placeholder(s.Cursor.ID)))
+	} else if s.Cursor != nil {
+		conds = append(conds, fmt.Sprintf(
+			"(%s, %s) %s (%s, %s)",
+			s.Sort.Column, idColumn, op, placeholder(s.Cursor.Value), placeholder(s.Cursor.ID),
+		))
+	}
+
+	var b strings.Builder
+	b.WriteString(base)
+	if len(conds) > 0 {
+		b.WriteString(" WHERE ")
+		b.WriteString(strings.Join(conds, " AND "))
+	}
+
+	dir := "ASC"
+	if s.Sort.Desc {
+		dir = "DESC"
+	}
+	fmt.Fprintf(&b, " ORDER BY %s %s", s.Sort.Column, dir)
+	if !byID {
+		fmt.Fprintf(&b, ", %s %s", idColumn, dir)
+	}
+	fmt.Fprintf(&b, " LIMIT %s", placeholder(s.Limit+1))
+
+	return b.String(), queryArgs
+}
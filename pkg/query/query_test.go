@@ -0,0 +1,158 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package query
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"adeia/pkg/util/constants"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testOpts = &Options{
+	Sortable: map[string]string{
+		"id":   "id",
+		"name": "full_name",
+	},
+	Filterable: map[string]Field{
+		"designation": {Column: "designation"},
+		"active":      {Column: "is_activated", Type: Bool},
+		"age":         {Column: "age", Type: Int},
+		"since":       {Column: "created_at", Type: Time},
+	},
+	DefaultSort: "id",
+}
+
+func TestParse(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		t.Parallel()
+		got, err := Parse(url.Values{}, testOpts)
+
+		assert.Nil(t, err)
+		assert.Equal(t, &Spec{
+			Limit: constants.DefaultPageSize,
+			Sort:  Sort{Field: "id", Column: "id"},
+		}, got)
+	})
+
+	t.Run("limit, sort and filters", func(t *testing.T) {
+		t.Parallel()
+		q, _ := url.ParseQuery("limit=5&sort=-name&filter[designation]=prof&filter[active]=true" +
+			"&filter[age]=30&filter[since]=2020-01-02T03:04:05Z")
+		got, err := Parse(q, testOpts)
+
+		assert.Nil(t, err)
+		assert.Equal(t, &Spec{
+			Limit: 5,
+			Sort:  Sort{Field: "name", Column: "full_name", Desc: true},
+			Filters: []Filter{
+				{Field: "active", Column: "is_activated", Value: true},
+				{Field: "age", Column: "age", Value: 30},
+				{Field: "designation", Column: "designation", Value: "prof"},
+				{Field: "since", Column: "created_at", Value: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+			},
+		}, got)
+	})
+
+	t.Run("limit is capped", func(t *testing.T) {
+		t.Parallel()
+		q, _ := url.ParseQuery("limit=100000")
+		got, err := Parse(q, testOpts)
+
+		assert.Nil(t, err)
+		assert.Equal(t, constants.MaxPageSize, got.Limit)
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		testcases := []struct {
+			in    string
+			param string
+			msg   string
+		}{
+			{"limit=foo", limitParam, "limit is not a number"},
+			{"limit=0", limitParam, "limit is not positive"},
+			{"sort=password", sortParam, "sort field is not whitelisted"},
+			{"filter[password]=foo", "filter[password]", "filter field is not whitelisted"},
+			{"filter[active]=yes", "filter[active]", "bool filter is invalid"},
+			{"filter[age]=old", "filter[age]", "int filter is invalid"},
+			{"filter[since]=yesterday", "filter[since]", "time filter is invalid"},
+			{"cursor=foo", cursorParam, "cursor is malformed"},
+		}
+		for _, tc := range testcases {
+			tc := tc
+			t.Run(tc.msg, func(t *testing.T) {
+				t.Parallel()
+				q, _ := url.ParseQuery(tc.in)
+				_, err := Parse(q, testOpts)
+
+				if assert.IsType(t, &Error{}, err) {
+					assert.Equal(t, tc.param, err.(*Error).Param)
+				}
+			})
+		}
+	})
+
+	t.Run("cursor for a different sort order", func(t *testing.T) {
+		t.Parallel()
+		c, _ := (&Cursor{Sort: "name", Value: "foo", ID: 1}).encode()
+		q := url.Values{"sort": {"-name"}, "cursor": {c}}
+		_, err := Parse(q, testOpts)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("valid cursor", func(t *testing.T) {
+		t.Parallel()
+		want := &Cursor{Sort: "-name", Value: "foo", ID: 1}
+		c, _ := want.encode()
+		q := url.Values{"sort": {"-name"}, "cursor": {c}}
+		got, err := Parse(q, testOpts)
+
+		assert.Nil(t, err)
+		assert.Equal(t, want, got.Cursor)
+	})
+}
+
+func TestSpec_Build(t *testing.T) {
+	t.Run("without conditions", func(t *testing.T) {
+		t.Parallel()
+		s := &Spec{Limit: 10, Sort: Sort{Field: "id", Column: "id"}}
+		got, args := s.Build("SELECT * FROM users", nil)
+
+		assert.Equal(t, "SELECT * FROM users ORDER BY id ASC LIMIT $1", got)
+		assert.Equal(t, []interface{}{11}, args)
+	})
+
+	t.Run("with cursor sorted by id", func(t *testing.T) {
+		t.Parallel()
+		s := &Spec{
+			Limit:  10,
+			Sort:   Sort{Field: "id", Column: "id", Desc: true},
+			Cursor: &Cursor{Sort: "-id", Value: "42", ID: 42},
+		}
+		got, args := s.Build("SELECT * FROM users", []string{"deleted_at IS NULL"})
+
+		assert.Equal(t, "SELECT * FROM users WHERE deleted_at IS NULL AND id < $1 ORDER BY id DESC LIMIT $2", got)
+		assert.Equal(t, []interface{}{42, 11}, args)
+	})
+
+	t.Run("with filters and cursor", func(t *testing.T) {
+		t.Parallel()
+		s := &Spec{
+			Limit:   10,
+			Sort:    Sort{Field: "name", Column: "full_name", Desc: true},
+			Filters: []Filter{{Field: "designation", Column: "designation", Value: "prof"}},
+			Cursor:  &Cursor{Sort: "-name", Value: "foo", ID: 42},
+		}
+		got, args := s.Build("SELECT * FROM users", []string{"deleted_at IS NULL", "org=$1"}, 7)
+
+		assert.Equal(t, "SELECT * FROM users WHERE deleted_at IS NULL AND org=$1 AND designation=$2 AND "+
+			"(full_name, id) < ($3, $4) ORDER BY full_name DESC, id DESC LIMIT $5", got)
+		assert.Equal(t, []interface{}{7, "prof", "foo", 42, 11}, args)
+	})
+}
@@ -0,0 +1,98 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+// Package totp implements time-based one-time passwords (RFC 6238), as used by
+// authenticator apps.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" // #nosec G505 -- HMAC-SHA1 is what authenticator apps support
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Digits is the number of digits in a code.
+	Digits = 6
+
+	// Period (in seconds) is the time for which a code is valid.
+	Period = 30
+
+	// Skew is the number of periods before and after the current one, whose codes
+	// are accepted too, to allow for clock drift.
+	Skew = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// EncodeSecret encodes the secret in base32, as expected by authenticator apps.
+func EncodeSecret(secret []byte) string {
+	return encoding.EncodeToString(secret)
+}
+
+// URI returns the otpauth:// URI of the secret, that is usually shown as a QR code
+// for authenticator apps to scan.
+// https://github.com/google/google-authenticator/wiki/Key-Uri-Format
+func URI(issuer, account string, secret []byte) string {
+	q := url.Values{}
+	q.Set("secret", EncodeSecret(secret))
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(Digits))
+	q.Set("period", fmt.Sprint(Period))
+
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+// Step returns the time step (the number of periods since the Unix epoch) of t.
+func Step(t time.Time) int64 {
+	return t.Unix() / Period
+}
+
+// Code returns the code of the secret for the time step.
+func Code(secret []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// dynamic truncation, see RFC 4226, section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	n := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, n%mod)
+}
+
+// Validate checks if the code is valid for the secret at time t, and returns the
+// time step that it matched. Spaces in the code are ignored.
+//
+// Codes must only be accepted once, so callers must store the matched step and
+// reject codes of the same or earlier steps.
+func Validate(secret []byte, code string, t time.Time) (step int64, ok bool) {
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	current := Step(t)
+	for s := current - Skew; s <= current+Skew; s++ {
+		if subtle.ConstantTimeCompare([]byte(Code(secret, s)), []byte(code)) == 1 {
+			return s, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,74 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package totp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// secret is the SHA1 secret of the test vectors in RFC 6238, appendix B.
+var secret = []byte("12345678901234567890")
+
+func TestCode(t *testing.T) {
+	// the test vectors are 8 digits long, so we compare the last 6 digits
+	testcases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.want, func(t *testing.T) {
+			t.Parallel()
+			got := Code(secret, Step(time.Unix(tc.unix, 0)))
+			assert.Equal(t, tc.want[2:], got)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+
+	testcases := []struct {
+		code     string
+		wantStep int64
+		wantOK   bool
+		msg      string
+	}{
+		{Code(secret, current), current, true, "current step"},
+		{Code(secret, current-1), current - 1, true, "previous step"},
+		{Code(secret, current+1), current + 1, true, "next step"},
+		{Code(secret, current-2), 0, false, "outside skew"},
+		{"050 471", current, true, "spaces are ignored"},
+		{"12345", 0, false, "too short"},
+		{"", 0, false, "empty"},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.msg, func(t *testing.T) {
+			t.Parallel()
+			step, ok := Validate(secret, tc.code, now)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.wantStep, step)
+		})
+	}
+}
+
+func TestURI(t *testing.T) {
+	t.Parallel()
+	got := URI("adeia", "foo@example.com", []byte("foo"))
+	want := "otpauth://totp/adeia:foo@example.com?algorithm=SHA1&digits=6&issuer=adeia&period=30&secret=MZXW6"
+	assert.Equal(t, want, got)
+}
@@ -7,14 +7,58 @@ package constants
 const (
 	// MaxReqBodySize (in bytes; default: 1MiB)
 	MaxReqBodySize = 1048576
+	// MaxUploadSize (in bytes; default: 10MiB)
+	MaxUploadSize = 10485760
+	// DefaultPageSize is the no. of items returned by list endpoints, when no limit is specified.
+	DefaultPageSize = 20
+	// MaxPageSize is the maximum no. of items returned by list endpoints.
+	MaxPageSize = 100
 	// APIVersion represents the current major version of the API. It is used as URL prefix.
 	APIVersion = "v1"
 
+	// ErrorDocsURL is the link to the documentation of all error codes.
+	ErrorDocsURL = "https://arkn98.github.io/adeia/api-reference/errors/"
+
 	// EmployeeIDLength represents the length of the generated employee IDs.
 	EmployeeIDLength = 6
 	// EmployeeIDChars represents the list of possible characters that can occur in an employee ID.
 	EmployeeIDChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
+	// ActivationTokenLength represents the length (in bytes) of account activation tokens.
+	ActivationTokenLength = 32
+	// ActivationTokenExpiry (in seconds; default: 3 days)
+	ActivationTokenExpiry = 259200
+
+	// AccessTokenExpiry (in seconds; default: 15 minutes)
+	AccessTokenExpiry = 900
+	// RefreshTokenLength represents the length (in bytes) of refresh tokens.
+	RefreshTokenLength = 32
+	// RefreshTokenExpiry (in seconds; default: 7 days)
+	RefreshTokenExpiry = 604800
+	// PasswordResetTokenLength represents the length (in bytes) of password reset tokens.
+	PasswordResetTokenLength = 32
+	// PasswordResetTokenExpiry (in seconds; default: 1 hour)
+	PasswordResetTokenExpiry = 3600
+	// MinPasswordStrength is the minimum strength (on a scale of 0 - 4) of passwords.
+	MinPasswordStrength = 3
+
+	// TOTPIssuer is the issuer shown by authenticator apps, for the TOTP secrets.
+	TOTPIssuer = "adeia"
+	// TOTPSecretLength represents the length (in bytes) of TOTP secrets.
+	TOTPSecretLength = 20
+	// RecoveryCodeCount is the no. of recovery codes that are generated on enrolling
+	// in two-factor authentication.
+	RecoveryCodeCount = 10
+	// RecoveryCodeLength represents the length (in bytes) of recovery codes.
+	RecoveryCodeLength = 5
+	// MFATokenExpiry (in seconds; default: 5 minutes) is the time within which the
+	// second step of login must be completed.
+	MFATokenExpiry = 300
+
+	// DigestInterval (in seconds; default: 1 day) is the interval at which the
+	// notification digest emails are sent.
+	DigestInterval = 86400
+
 	// ==========
 	// Keys of env variables to override the config
 	// ==========
@@ -25,6 +69,11 @@ const (
 	// EnvServerJWTSecretKey is the env key for server's jwt secret.
 	EnvServerJWTSecretKey = EnvPrefix + "_SERVER_JWT_SECRET"
 
+	// Storage keys
+
+	// EnvStorageSigningSecretKey is the env key for the secret used to sign download links.
+	EnvStorageSigningSecretKey = EnvPrefix + "_STORAGE_SIGNING_SECRET"
+
 	// Mailer keys
 
 	// EnvMailerUsernameKey is the env key for mailer username.
@@ -5,6 +5,7 @@
 package crypto
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
@@ -50,6 +51,13 @@ func Hash(b []byte) []byte {
 	return h.Sum(nil)
 }
 
+// HMAC computes the HMAC of the given byte slice using SHA256 and the provided key.
+func HMAC(key, b []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(b)
+	return h.Sum(nil)
+}
+
 // HashPassword uses argon2id to generate a hash from the password.
 func HashPassword(p string) (hash string, err error) {
 	return argon2id.CreateHash(p, argon2id.DefaultParams)
@@ -66,7 +74,9 @@ func NewEmpID() string {
 }
 
 // PasswordStrength returns the strength of a password (on a scale of 0 - 4).
-func PasswordStrength(password string) int {
-	// TODO: add site-specific, user-specific inputs to penalize weak passwords
-	return zxcvbn.PasswordStrength(password, []string{}).Score
+// Passwords that contain any of the userInputs (like the name or email of the
+// user) are penalized.
+func PasswordStrength(password string, userInputs ...string) int {
+	inputs := append([]string{"adeia"}, userInputs...)
+	return zxcvbn.PasswordStrength(password, inputs).Score
 }
@@ -15,7 +15,9 @@ import (
 
 	"adeia"
 	"adeia/pkg/errs"
+	"adeia/pkg/i18n"
 	"adeia/pkg/util/constants"
+	"adeia/pkg/validation"
 
 	"github.com/golang/gddo/httputil/header"
 )
@@ -25,6 +27,8 @@ var errFailedToWriteResponse = errors.New("failed to write response")
 const (
 	statusRequestEntityTooLargeMessage = "http: request body too large"
 	statusJSONUnknownField             = "json: unknown field "
+
+	problemContentType = "application/problem+json"
 )
 
 // DecodeJSONBody decodes a JSON http.Request.Body into the provided interface, dest,
@@ -112,7 +116,9 @@ func isReqMalformedErr(err error) bool {
 		adeia.ErrInvalidRequestBody.Error(),
 		adeia.ErrValidationFailed.Error(),
 		adeia.ErrRequestBodyTooLarge.Error(),
-		adeia.ErrUnknownField.Error():
+		adeia.ErrUnknownField.Error(),
+		adeia.ErrMissingFile.Error(),
+		adeia.ErrUnsupportedFileType.Error():
 		return true
 	}
 
@@ -120,34 +126,50 @@ func isReqMalformedErr(err error) bool {
 }
 
 // Decode is a wrapper around DecodeJSONBody that decodes the request body into
-// a destination interface. And if DecodeJSONBody returns an error, an appropriate
-// error response is sent back. So the controller calling this method need not
-// write a response. But, the errors returned when writing the error response are
-// ignored; so in that case, a response is not 100% guaranteed.
+// a destination interface, and then validates it using the rules in its `validate`
+// struct tags (see the validation package). And if DecodeJSONBody returns an error,
+// or the validation fails, an appropriate error response is sent back. So the
+// controller calling this method need not write a response. But, the errors returned
+// when writing the error response are ignored; so in that case, a response is not
+// 100% guaranteed.
 func Decode(w http.ResponseWriter, r *http.Request, dest interface{}) error {
 	if err := DecodeJSONBody(w, r, dest); err != nil {
 		if isReqMalformedErr(err) {
-			_ = RespondWithErr(w, err.(errs.ResponseError))
+			_ = RespondWithErr(w, r, err.(errs.ResponseError))
 			return fmt.Errorf("malformed request body: %v", err)
 		}
 
 		// some other error
-		_ = RespondWithErr(w, adeia.ErrParseReqBodyFailed)
+		_ = RespondWithErr(w, r, adeia.ErrParseReqBodyFailed)
 		return fmt.Errorf("cannot parse request body: %v", err)
 	}
 
+	vErrs, err := validation.Struct(dest)
+	if err != nil {
+		_ = RespondWithErr(w, r, adeia.ErrInternalError)
+		return fmt.Errorf("cannot validate request body: %v", err)
+	} else if len(vErrs) > 0 {
+		err := adeia.ErrValidationFailed.ValidationErr(vErrs)
+		_ = RespondWithErr(w, r, err)
+		return fmt.Errorf("malformed request body: %v", err)
+	}
+
 	return nil
 }
 
 // Respond is a util that writes a HTTP statusCode and payload, as a HTTP JSON response.
 func Respond(w http.ResponseWriter, statusCode int, payload interface{}) error {
+	return respond(w, statusCode, "application/json", payload)
+}
+
+func respond(w http.ResponseWriter, statusCode int, contentType string, payload interface{}) error {
 	resp, err := json.Marshal(payload)
 	if err != nil {
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return fmt.Errorf("failed to marshal payload to JSON: %v", err)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(statusCode)
 	_, err = w.Write(resp)
 	if err != nil {
@@ -163,22 +185,126 @@ type errorResponse struct {
 
 type dataResponse struct {
 	Data interface{} `json:"data"`
+	Meta *Meta       `json:"meta,omitempty"`
+}
+
+// Meta represents the metadata of a data response, like pagination links.
+type Meta struct {
+	// NextCursor is the cursor that points to the next page of a list.
+	NextCursor string `json:"next_cursor,omitempty"`
+
+	// Next is the link to the next page of a list.
+	Next string `json:"next,omitempty"`
+}
+
+// problem represents an RFC 7807 problem details object.
+// https://tools.ietf.org/html/rfc7807
+type problem struct {
+	Type             string            `json:"type"`
+	Title            string            `json:"title"`
+	Status           int               `json:"status"`
+	Detail           string            `json:"detail,omitempty"`
+	Code             string            `json:"code"`
+	ValidationErrors map[string]string `json:"validation_errors,omitempty"`
+}
+
+// newProblem converts a ResponseError into a problem, using its entry in the error
+// catalogue for the type and title. The title is translated using the Localizer l.
+func newProblem(err errs.ResponseError, l *i18n.Localizer) *problem {
+	p := &problem{
+		Type:             "about:blank",
+		Title:            http.StatusText(err.StatusCode),
+		Status:           err.StatusCode,
+		Detail:           err.Message,
+		Code:             err.ErrorCode,
+		ValidationErrors: err.ValidationErrors,
+	}
+	if e, ok := errs.Lookup(err.ErrorCode); ok {
+		p.Type = e.DocsURL
+		if e.Title != "" {
+			p.Title = l.Translate(errorMessageKey(e.ErrorCode), e.Title)
+		}
+	}
+	return p
+}
+
+// wantsProblem checks if the client prefers an application/problem+json response
+// over an application/json one, using the Accept header.
+func wantsProblem(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+
+	var problemQ, jsonQ float64
+	for _, spec := range header.ParseAccept(r.Header, "Accept") {
+		switch spec.Value {
+		case problemContentType:
+			problemQ = spec.Q
+		case "application/json":
+			jsonQ = spec.Q
+		}
+	}
+	return problemQ > 0 && problemQ >= jsonQ
 }
 
 // RespondWithErr is a wrapper around Respond that structures the response to be
 // a error response, with fields like error code, validation errors, message, etc.
-func RespondWithErr(w http.ResponseWriter, err errs.ResponseError) error {
+// Clients can opt-in to RFC 7807 (application/problem+json) responses, using the
+// Accept header of the request r.
+// The default message of the error is translated to the locale of the request (see
+// the i18n package), while custom messages are sent as-is.
+func RespondWithErr(w http.ResponseWriter, r *http.Request, err errs.ResponseError) error {
+	var l *i18n.Localizer
+	if r != nil {
+		l = i18n.FromContext(r.Context())
+	}
+	if e, ok := errs.Lookup(err.ErrorCode); ok && e.Title != "" && err.Message == e.Title {
+		err.Message = l.Translate(errorMessageKey(err.ErrorCode), err.Message)
+	}
+
+	if wantsProblem(r) {
+		return respond(w, err.StatusCode, problemContentType, newProblem(err, l))
+	}
+
 	payload := &errorResponse{err}
 	return Respond(w, err.StatusCode, payload)
 }
 
+// errorMessageKey returns the key of the message of the ErrorCode in the i18n
+// message catalogues.
+func errorMessageKey(code string) string {
+	return "errors." + code
+}
+
 // RespondWithData is a wrapper around Respond that structures the response to be
 // a data response.
 func RespondWithData(w http.ResponseWriter, statusCode int, data interface{}) error {
-	payload := &dataResponse{data}
+	payload := &dataResponse{Data: data}
+	return Respond(w, statusCode, payload)
+}
+
+// RespondWithDataAndMeta is a wrapper around Respond that structures the response to
+// be a data response, along with its metadata.
+func RespondWithDataAndMeta(w http.ResponseWriter, statusCode int, data interface{}, meta *Meta) error {
+	payload := &dataResponse{Data: data, Meta: meta}
 	return Respond(w, statusCode, payload)
 }
 
+// RespondWithPage is a wrapper around RespondWithDataAndMeta that responds with a
+// page of a list. The metadata contains the cursor and link to the next page, if
+// nextCursor is non-empty.
+func RespondWithPage(w http.ResponseWriter, r *http.Request, statusCode int, data interface{}, nextCursor string) error {
+	var meta *Meta
+	if nextCursor != "" {
+		next := *r.URL
+		q := next.Query()
+		q.Set("cursor", nextCursor)
+		next.RawQuery = q.Encode()
+		meta = &Meta{NextCursor: nextCursor, Next: next.RequestURI()}
+	}
+	return RespondWithDataAndMeta(w, statusCode, data, meta)
+}
+
 // LogWarner is an interface for a logger that can Warnf(). This is specifically used
 // when logging errors that occur when writing a HTTP response.
 type LogWarner interface {
@@ -17,6 +17,7 @@ import (
 
 	"adeia"
 	"adeia/pkg/errs"
+	"adeia/pkg/i18n"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -57,9 +58,12 @@ func TestIsReqMalformedErr(t *testing.T) {
 		{adeia.ErrValidationFailed, true, "req malformed error"},
 		{adeia.ErrRequestBodyTooLarge, true, "req malformed error"},
 		{adeia.ErrUnknownField, true, "req malformed error"},
+		{adeia.ErrMissingFile, true, "req malformed error"},
+		{adeia.ErrUnsupportedFileType, true, "req malformed error"},
 		{errors.New("test"), false, "some other error"},
 	}
 	for _, tc := range testcases {
+		tc := tc
 		t.Run(tc.msg, func(t *testing.T) {
 			t.Parallel()
 			got := isReqMalformedErr(tc.in)
@@ -163,7 +167,8 @@ func TestRespond(t *testing.T) {
 			ErrorCode:  "TEST_ERROR_CODE",
 			Message:    "test error",
 		}
-		err := RespondWithErr(w, payload)
+		r := httptest.NewRequest(http.MethodGet, "/1", nil)
+		err := RespondWithErr(w, r, payload)
 		resp := w.Result()
 		got, _ := ioutil.ReadAll(resp.Body)
 
@@ -174,6 +179,81 @@ func TestRespond(t *testing.T) {
 	})
 }
 
+func TestRespondWithErr(t *testing.T) {
+	payload := adeia.ErrValidationFailed.AddValidationErr("foo", "bar")
+
+	testcases := []struct {
+		accept      string
+		contentType string
+		msg         string
+	}{
+		{"", "application/json", "no accept header"},
+		{"application/json", "application/json", "accept json"},
+		{"application/problem+json", "application/problem+json", "accept problem+json"},
+		{"application/json, application/problem+json", "application/problem+json", "accept both"},
+		{"application/json, application/problem+json;q=0.5", "application/json", "json preferred"},
+		{"application/problem+json;q=0", "application/json", "problem+json not acceptable"},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.msg, func(t *testing.T) {
+			t.Parallel()
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/1", nil)
+			if tc.accept != "" {
+				r.Header.Set("Accept", tc.accept)
+			}
+			err := RespondWithErr(w, r, payload)
+
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+			assert.Equal(t, tc.contentType, w.Header().Get("Content-Type"))
+		})
+	}
+
+	t.Run("problem details", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/1", nil)
+		r.Header.Set("Accept", "application/problem+json")
+		_ = RespondWithErr(w, r, payload.Msg("Name is missing"))
+
+		entry, _ := errs.Lookup(payload.ErrorCode)
+		want := `{"type":"` + entry.DocsURL + `","title":"Validation failed for some fields","status":422,` +
+			`"detail":"Name is missing","code":"VALIDATION_FAILED","validation_errors":{"foo":"bar"}}`
+		assert.JSONEq(t, want, w.Body.String())
+	})
+
+	t.Run("translate default message", func(t *testing.T) {
+		t.Parallel()
+		c, err := i18n.Load("../../../web/locales")
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/1", nil)
+		r = r.WithContext(i18n.NewContext(r.Context(), i18n.NewLocalizer(c, "ta")))
+
+		_ = RespondWithErr(w, r, adeia.ErrResourceNotFound)
+		assert.Contains(t, w.Body.String(), `"message":"கோரிய வளம் கிடைக்கவில்லை"`)
+
+		w = httptest.NewRecorder()
+		_ = RespondWithErr(w, r, adeia.ErrResourceNotFound.Msg("foo"))
+		assert.Contains(t, w.Body.String(), `"message":"foo"`, "custom messages are not translated")
+	})
+
+	t.Run("problem details for unregistered error", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/1", nil)
+		r.Header.Set("Accept", "application/problem+json")
+		_ = RespondWithErr(w, r, errs.ResponseError{StatusCode: http.StatusTeapot, ErrorCode: "FOO"})
+
+		want := `{"type":"about:blank","title":"I'm a teapot","status":418,"code":"FOO"}`
+		assert.JSONEq(t, want, w.Body.String())
+	})
+}
+
 func TestDecode(t *testing.T) {
 	t.Run("request malformed error", func(t *testing.T) {
 		t.Parallel()
@@ -191,11 +271,50 @@ foo:
 		}
 	})
 
+	t.Run("validation failed", func(t *testing.T) {
+		t.Parallel()
+
+		type body struct {
+			Foo string `json:"foo" validate:"required"`
+			Baz int    `json:"baz" validate:"max=5"`
+		}
+
+		var got body
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/1", strings.NewReader(`{"baz":10}`))
+		err := Decode(w, r, &got)
+
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "malformed request body")
+		}
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.JSONEq(t, `{"error":{"code":"VALIDATION_FAILED","message":"Validation failed for some fields",`+
+			`"validation_errors":{"foo":"This field is required","baz":"Must be at most 5"}}}`, w.Body.String())
+	})
+
+	t.Run("invalid rules", func(t *testing.T) {
+		t.Parallel()
+
+		type body struct {
+			Foo string `json:"foo" validate:"foobar"`
+		}
+
+		var got body
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/1", strings.NewReader(`{"foo":"bar"}`))
+		err := Decode(w, r, &got)
+
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "cannot validate request body")
+		}
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+
 	t.Run("successful decode", func(t *testing.T) {
 		t.Parallel()
 
 		type body struct {
-			Foo string `json:"foo"`
+			Foo string `json:"foo" validate:"required"`
 			Baz int    `json:"baz"`
 		}
 
@@ -329,3 +448,31 @@ func TestDecodeJSONBody(t *testing.T) {
 		assert.Equal(t, want, got)
 	})
 }
+
+func TestRespondWithPage(t *testing.T) {
+	t.Run("without next cursor", func(t *testing.T) {
+		t.Parallel()
+
+		want := `{"data":["foo"]}`
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/v1/users?limit=1", nil)
+		err := RespondWithPage(w, r, http.StatusOK, []string{"foo"}, "")
+		got, _ := ioutil.ReadAll(w.Result().Body)
+
+		assert.Nil(t, err)
+		assert.Equal(t, want, string(got))
+	})
+
+	t.Run("with next cursor", func(t *testing.T) {
+		t.Parallel()
+
+		want := `{"data":["foo"],"meta":{"next_cursor":"abc","next":"/v1/users?cursor=abc\u0026limit=1"}}`
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/v1/users?limit=1&cursor=xyz", nil)
+		err := RespondWithPage(w, r, http.StatusOK, []string{"foo"}, "abc")
+		got, _ := ioutil.ReadAll(w.Result().Body)
+
+		assert.Nil(t, err)
+		assert.Equal(t, want, string(got))
+	})
+}
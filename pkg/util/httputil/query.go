@@ -0,0 +1,33 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package httputil
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"adeia"
+	"adeia/pkg/query"
+)
+
+// DecodeQuery parses the list query params of the request into a query.Spec,
+// allowing only the fields in opts. And if the params are invalid, an appropriate
+// error response is sent back, similar to Decode.
+func DecodeQuery(w http.ResponseWriter, r *http.Request, opts *query.Options) (*query.Spec, error) {
+	spec, err := query.Parse(r.URL.Query(), opts)
+	if err != nil {
+		re := adeia.ErrValidationFailed
+		var qErr *query.Error
+		if errors.As(err, &qErr) {
+			re = re.AddValidationErr(qErr.Param, qErr.Message)
+		}
+
+		_ = RespondWithErr(w, r, re)
+		return nil, fmt.Errorf("malformed query params: %v", err)
+	}
+
+	return spec, nil
+}
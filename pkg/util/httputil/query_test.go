@@ -0,0 +1,45 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"adeia/pkg/query"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeQuery(t *testing.T) {
+	opts := &query.Options{
+		Sortable:    map[string]string{"id": "id"},
+		DefaultSort: "id",
+	}
+
+	t.Run("invalid query params", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/1?sort=password", nil)
+		_, err := DecodeQuery(w, r, opts)
+
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "malformed query params")
+		}
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(t, w.Body.String(), `"sort":"Cannot sort by password"`)
+	})
+
+	t.Run("successful decode", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/1?limit=5", nil)
+		got, err := DecodeQuery(w, r, opts)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 5, got.Limit)
+	})
+}
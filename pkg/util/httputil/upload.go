@@ -0,0 +1,146 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package httputil
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+
+	"adeia"
+	"adeia/pkg/errs"
+	"adeia/pkg/util/constants"
+	"adeia/pkg/util/crypto"
+
+	"github.com/golang/gddo/httputil/header"
+)
+
+// Upload represents a file that was uploaded as part of a multipart/form-data request.
+type Upload struct {
+	// Filename is the name of the file, as sent by the client.
+	Filename string
+
+	// ContentType is the content-type of the file, sniffed from its contents. The
+	// content-type sent by the client is never trusted.
+	ContentType string
+
+	// Size is the size of the file (in bytes).
+	Size int64
+
+	// Checksum is the hex-encoded SHA256 checksum of the file.
+	Checksum string
+
+	// Content is the contents of the file.
+	Content []byte
+}
+
+// DecodeMultipartFile reads the file sent in the form field of a multipart/form-data
+// request. The content-type of the file is sniffed from its contents and must be one
+// of allowedTypes (all types are allowed when allowedTypes is empty). An error is
+// returned when the file cannot be read.
+func DecodeMultipartFile(w http.ResponseWriter, r *http.Request, field string, allowedTypes ...string) (*Upload, error) {
+	return decodeMultipartFile(w, r, field, constants.MaxUploadSize, allowedTypes)
+}
+
+func decodeMultipartFile(
+	w http.ResponseWriter,
+	r *http.Request,
+	field string,
+	maxUploadSize int64,
+	allowedTypes []string,
+) (*Upload, error) {
+	value, _ := header.ParseValueAndParams(r.Header, "Content-Type")
+	if value != "multipart/form-data" {
+		// request body is not multipart
+		return nil, adeia.ErrInvalidRequestBody.Msg("Request body must be multipart/form-data")
+	}
+
+	// set max body size; err is returned when body exceeds this size
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, adeia.ErrInvalidRequestBody.Msg("Request body must be multipart/form-data")
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			// no more parts, and we haven't found the file
+			return nil, adeia.ErrMissingFile
+		} else if err != nil {
+			return nil, multipartErr(err)
+		}
+
+		if part.FormName() != field || part.FileName() == "" {
+			continue
+		}
+
+		content, err := ioutil.ReadAll(part)
+		if err != nil {
+			return nil, multipartErr(err)
+		}
+		if len(content) == 0 {
+			return nil, adeia.ErrMissingFile
+		}
+
+		contentType, _, _ := mime.ParseMediaType(http.DetectContentType(content))
+		if !isAllowedType(contentType, allowedTypes) {
+			return nil, adeia.ErrUnsupportedFileType
+		}
+
+		return &Upload{
+			Filename:    part.FileName(),
+			ContentType: contentType,
+			Size:        int64(len(content)),
+			Checksum:    crypto.EncodeHex(crypto.Hash(content)),
+			Content:     content,
+		}, nil
+	}
+}
+
+func multipartErr(err error) error {
+	if strings.HasSuffix(err.Error(), statusRequestEntityTooLargeMessage) {
+		// request body is too large
+		return adeia.ErrRequestBodyTooLarge
+	}
+	// badly formed multipart body
+	return adeia.ErrInvalidRequestBody.Msg("Request body must be multipart/form-data")
+}
+
+func isAllowedType(contentType string, allowedTypes []string) bool {
+	if len(allowedTypes) == 0 {
+		return true
+	}
+
+	for _, t := range allowedTypes {
+		if contentType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// DecodeFile is a wrapper around DecodeMultipartFile that reads the file in the form
+// field. And if DecodeMultipartFile returns an error, an appropriate error response is
+// sent back, similar to Decode.
+func DecodeFile(w http.ResponseWriter, r *http.Request, field string, allowedTypes ...string) (*Upload, error) {
+	u, err := DecodeMultipartFile(w, r, field, allowedTypes...)
+	if err != nil {
+		if isReqMalformedErr(err) {
+			_ = RespondWithErr(w, r, err.(errs.ResponseError))
+			return nil, fmt.Errorf("malformed request body: %v", err)
+		}
+
+		// some other error
+		_ = RespondWithErr(w, r, adeia.ErrParseReqBodyFailed)
+		return nil, fmt.Errorf("cannot parse request body: %v", err)
+	}
+
+	return u, nil
+}
@@ -0,0 +1,117 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package httputil
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"adeia"
+	"adeia/pkg/util/crypto"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newMultipartRequest(t *testing.T, field, filename string, content []byte) *http.Request {
+	var b bytes.Buffer
+	mw := multipart.NewWriter(&b)
+	_ = mw.WriteField("note", "foobar")
+	if filename != "" {
+		fw, err := mw.CreateFormFile(field, filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = fw.Write(content)
+	}
+	_ = mw.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/1", &b)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestDecodeMultipartFile(t *testing.T) {
+	pdf := []byte("%PDF-1.4\nfoobar")
+
+	t.Run("request body is not multipart", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/1", strings.NewReader(`{"foo":"bar"}`))
+		r.Header.Set("Content-Type", "application/json")
+		_, err := DecodeMultipartFile(w, r, "file")
+
+		assert.EqualError(t, err, adeia.ErrInvalidRequestBody.Error())
+	})
+
+	t.Run("file is missing", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		r := newMultipartRequest(t, "file", "", nil)
+		_, err := DecodeMultipartFile(w, r, "file")
+
+		assert.EqualError(t, err, adeia.ErrMissingFile.Error())
+	})
+
+	t.Run("file is in a different field", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		r := newMultipartRequest(t, "other", "cert.pdf", pdf)
+		_, err := DecodeMultipartFile(w, r, "file")
+
+		assert.EqualError(t, err, adeia.ErrMissingFile.Error())
+	})
+
+	t.Run("file is too large", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		r := newMultipartRequest(t, "file", "cert.pdf", bytes.Repeat(pdf, 100))
+		_, err := decodeMultipartFile(w, r, "file", 512, nil)
+
+		assert.EqualError(t, err, adeia.ErrRequestBodyTooLarge.Error())
+	})
+
+	t.Run("file type is not allowed", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		r := newMultipartRequest(t, "file", "cert.pdf", []byte("just some text"))
+		_, err := DecodeMultipartFile(w, r, "file", "application/pdf", "image/png")
+
+		assert.EqualError(t, err, adeia.ErrUnsupportedFileType.Error())
+	})
+
+	t.Run("successful decode", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		r := newMultipartRequest(t, "file", "cert.pdf", pdf)
+		got, err := DecodeMultipartFile(w, r, "file", "application/pdf")
+
+		assert.Nil(t, err)
+		assert.Equal(t, &Upload{
+			Filename:    "cert.pdf",
+			ContentType: "application/pdf",
+			Size:        int64(len(pdf)),
+			Checksum:    crypto.EncodeHex(crypto.Hash(pdf)),
+			Content:     pdf,
+		}, got)
+	})
+}
+
+func TestDecodeFile(t *testing.T) {
+	t.Run("request malformed error", func(t *testing.T) {
+		t.Parallel()
+		w := httptest.NewRecorder()
+		r := newMultipartRequest(t, "file", "", nil)
+		_, err := DecodeFile(w, r, "file")
+
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "malformed request body")
+		}
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -91,6 +91,7 @@ func TestNewEscaper(t *testing.T) {
 	}
 
 	for _, tc := range testcases {
+		tc := tc
 		t.Run(tc.msg, func(t *testing.T) {
 			t.Parallel()
 			e := NewEscaper(tc.prefix, tc.chars...)
@@ -0,0 +1,402 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const tagName = "validate"
+
+// Errors is a map of validation errors, with keys as fields (named after their
+// `json` tag) and the corresponding error messages as values. The messages are
+// stable, so that clients can rely on them.
+type Errors map[string]string
+
+// rule validates a field. v is the (non-pointer) value of the field, p is the
+// parsed value after the "=" in the tag and parent is the struct that the field
+// is in. An empty string is returned if the field is valid.
+type rule func(v reflect.Value, p *param, parent reflect.Value) string
+
+// param is the parameter of a rule, parsed when the rules of a struct type are
+// compiled, so that invalid parameters are reported before validating any values.
+type param struct {
+	raw string
+
+	// n is the number of min and max.
+	n int
+
+	// field is the other field of gtefield and ltefield.
+	field reflect.StructField
+}
+
+// parsers parse the parameters of the rules that need one.
+var parsers = map[string]func(t reflect.Type, p *param) error{
+	"min":      parseNumber,
+	"max":      parseNumber,
+	"gtefield": parseField,
+	"ltefield": parseField,
+}
+
+// rules is the list of supported rules.
+//
+// Rules are specified in the `validate` struct tag, separated by commas, like
+// `validate:"required,max=120"`. Apart from required, rules are skipped for fields
+// that are empty.
+var rules = map[string]rule{
+	"required": required,
+	"email":    email,
+	"alnum":    alnum,
+	"locale":   locale,
+	"timezone": timezone,
+	"min":      minimum,
+	"max":      maximum,
+	"oneof":    oneof,
+	"gtefield": gtefield,
+	"ltefield": ltefield,
+}
+
+// boundRule is a rule in a `validate` tag, along with its parsed parameter.
+type boundRule struct {
+	name string
+	fn   rule
+	p    *param
+}
+
+// field is a field of a struct that has a `validate` tag.
+type field struct {
+	index int
+	name  string
+	rules []boundRule
+}
+
+// cache maps struct types to their compiled fields, so that their tags are
+// parsed only once.
+var cache sync.Map
+
+// Check compiles the rules in the `validate` struct tags of the struct (or pointer
+// to a struct) v, and returns an error if they are invalid, like when a rule is
+// unknown. It is meant to be called on startup, so that invalid rules are caught
+// before any request is validated.
+func Check(v interface{}) error {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	_, err := compile(t)
+	return err
+}
+
+// Struct validates the fields of the struct (or pointer to a struct) v, using the
+// rules in the `validate` struct tags. Only the first failing rule of a field is
+// reported. nil Errors are returned if all fields are valid. An error is returned
+// if the rules are invalid.
+func Struct(v interface{}) (Errors, error) {
+	s := reflect.Indirect(reflect.ValueOf(v))
+	if s.Kind() != reflect.Struct {
+		return nil, nil
+	}
+
+	fields, err := compile(s.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	var errs Errors
+	for _, f := range fields {
+		if msg := validateField(s.Field(f.index), f.rules, s); msg != "" {
+			if errs == nil {
+				errs = make(Errors)
+			}
+			errs[f.name] = msg
+		}
+	}
+	return errs, nil
+}
+
+// compile parses the `validate` tags of the struct type t. The result is cached.
+func compile(t reflect.Type) ([]field, error) {
+	if fields, ok := cache.Load(t); ok {
+		return fields.([]field), nil
+	}
+
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get(tagName)
+		if tag == "" || tag == "-" {
+			continue
+		}
+
+		f := field{index: i, name: fieldName(t.Field(i))}
+		for _, r := range strings.Split(tag, ",") {
+			name, raw := r, ""
+			if j := strings.Index(r, "="); j != -1 {
+				name, raw = r[:j], r[j+1:]
+			}
+
+			fn, ok := rules[name]
+			if !ok {
+				return nil, fmt.Errorf("validation: unknown rule %q on %v.%v", name, t, t.Field(i).Name)
+			}
+			p := &param{raw: raw}
+			if parse, ok := parsers[name]; ok {
+				if err := parse(t, p); err != nil {
+					return nil, fmt.Errorf("validation: invalid %v rule on %v.%v: %v", name, t, t.Field(i).Name, err)
+				}
+			}
+			f.rules = append(f.rules, boundRule{name, fn, p})
+		}
+		fields = append(fields, f)
+	}
+
+	cache.Store(t, fields)
+	return fields, nil
+}
+
+func parseNumber(_ reflect.Type, p *param) (err error) {
+	if p.n, err = strconv.Atoi(p.raw); err != nil {
+		return fmt.Errorf("%q is not a number", p.raw)
+	}
+	return nil
+}
+
+func parseField(t reflect.Type, p *param) error {
+	f, ok := t.FieldByName(p.raw)
+	if !ok {
+		return fmt.Errorf("unknown field %q", p.raw)
+	}
+	p.field = f
+	return nil
+}
+
+func validateField(f reflect.Value, rules []boundRule, parent reflect.Value) string {
+	for f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			f = reflect.Value{}
+			break
+		}
+		f = f.Elem()
+	}
+
+	for _, r := range rules {
+		if r.name != "required" && isEmpty(f) {
+			// optional fields are validated only when present
+			return ""
+		}
+		if msg := r.fn(f, r.p, parent); msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
+
+// fieldName returns the name of the field as used in the request body, which is
+// the name in its `json` tag.
+func fieldName(f reflect.StructField) string {
+	if name := strings.Split(f.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+		return name
+	}
+	return f.Name
+}
+
+func isEmpty(v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+	if v.Kind() == reflect.String {
+		return strings.TrimSpace(v.String()) == ""
+	}
+	return v.IsZero()
+}
+
+func required(v reflect.Value, _ *param, _ reflect.Value) string {
+	if isEmpty(v) {
+		return "This field is required"
+	}
+	return ""
+}
+
+func email(v reflect.Value, _ *param, _ reflect.Value) string {
+	s := v.String()
+	if a, err := mail.ParseAddress(s); err != nil || a.Address != s {
+		return "Must be a valid email address"
+	}
+	return ""
+}
+
+func alnum(v reflect.Value, _ *param, _ reflect.Value) string {
+	for _, r := range v.String() {
+		if !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && !(r >= '0' && r <= '9') {
+			return "Must only contain letters and digits"
+		}
+	}
+	return ""
+}
+
+// locale checks if the field is a locale (a BCP 47 language tag) like "en" or
+// "ta-IN". Underscores are accepted as separators too, like "ta_IN".
+func locale(v reflect.Value, _ *param, _ reflect.Value) string {
+	const msg = "Must be a valid locale"
+
+	// the language is 2-3 letters, followed by subtags (like region) of 2-8
+	// letters or digits
+	parts := strings.Split(strings.ReplaceAll(v.String(), "_", "-"), "-")
+	for i, p := range parts {
+		if (i == 0 && (len(p) < 2 || len(p) > 3)) || (i > 0 && (len(p) < 2 || len(p) > 8)) {
+			return msg
+		}
+		for _, r := range p {
+			isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+			if !isLetter && (i == 0 || !(r >= '0' && r <= '9')) {
+				return msg
+			}
+		}
+	}
+	return ""
+}
+
+// timezone checks if the field is an IANA time zone name, like "Asia/Kolkata".
+func timezone(v reflect.Value, _ *param, _ reflect.Value) string {
+	// LoadLocation treats "" and "Local" specially, but they are not time zone names
+	if s := v.String(); s == "Local" {
+		return "Must be a valid time zone"
+	} else if _, err := time.LoadLocation(s); err != nil {
+		return "Must be a valid time zone"
+	}
+	return ""
+}
+
+func minimum(v reflect.Value, p *param, _ reflect.Value) string {
+	n := p.n
+	switch v.Kind() {
+	case reflect.String:
+		if utf8.RuneCountInString(v.String()) < n {
+			return fmt.Sprintf("Must be at least %d characters long", n)
+		}
+	case reflect.Slice, reflect.Map:
+		if v.Len() < n {
+			return fmt.Sprintf("Must contain at least %d items", n)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() < int64(n) {
+			return fmt.Sprintf("Must be at least %d", n)
+		}
+	}
+	return ""
+}
+
+func maximum(v reflect.Value, p *param, _ reflect.Value) string {
+	n := p.n
+	switch v.Kind() {
+	case reflect.String:
+		if utf8.RuneCountInString(v.String()) > n {
+			return fmt.Sprintf("Must be at most %d characters long", n)
+		}
+	case reflect.Slice, reflect.Map:
+		if v.Len() > n {
+			return fmt.Sprintf("Must contain at most %d items", n)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() > int64(n) {
+			return fmt.Sprintf("Must be at most %d", n)
+		}
+	}
+	return ""
+}
+
+// oneof checks if the field is one of the space-separated options. For slices,
+// each of the elements is checked.
+func oneof(v reflect.Value, p *param, _ reflect.Value) string {
+	options := strings.Fields(p.raw)
+	isOption := func(v reflect.Value) bool {
+		s := fmt.Sprint(v.Interface())
+		for _, o := range options {
+			if s == o {
+				return true
+			}
+		}
+		return false
+	}
+
+	if v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			if !isOption(v.Index(i)) {
+				return "Must only contain: " + strings.Join(options, ", ")
+			}
+		}
+		return ""
+	}
+	if !isOption(v) {
+		return "Must be one of: " + strings.Join(options, ", ")
+	}
+	return ""
+}
+
+// gtefield checks if the field is greater than or equal to (for dates, not before)
+// another field of the same type in the struct.
+func gtefield(v reflect.Value, p *param, parent reflect.Value) string {
+	other, name := otherField(parent, p.field)
+	if c, ok := compare(v, other); ok && c < 0 {
+		return "Must not be before " + name
+	}
+	return ""
+}
+
+// ltefield checks if the field is less than or equal to (for dates, not after)
+// another field of the same type in the struct.
+func ltefield(v reflect.Value, p *param, parent reflect.Value) string {
+	other, name := otherField(parent, p.field)
+	if c, ok := compare(v, other); ok && c > 0 {
+		return "Must not be after " + name
+	}
+	return ""
+}
+
+func otherField(parent reflect.Value, f reflect.StructField) (reflect.Value, string) {
+	return reflect.Indirect(parent.FieldByIndex(f.Index)), fieldName(f)
+}
+
+// compare compares a and b, returning -1, 0 or 1. ok is false when they cannot be
+// compared, like when b is empty.
+func compare(a, b reflect.Value) (c int, ok bool) {
+	if isEmpty(b) || a.Type() != b.Type() {
+		return 0, false
+	}
+
+	if t, isTime := a.Interface().(time.Time); isTime {
+		u := b.Interface().(time.Time)
+		switch {
+		case t.Before(u):
+			return -1, true
+		case t.After(u):
+			return 1, true
+		}
+		return 0, true
+	}
+
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch {
+		case a.Int() < b.Int():
+			return -1, true
+		case a.Int() > b.Int():
+			return 1, true
+		}
+		return 0, true
+	case reflect.String:
+		return strings.Compare(a.String(), b.String()), true
+	}
+	return 0, false
+}
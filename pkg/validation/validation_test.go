@@ -0,0 +1,208 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package validation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStruct(t *testing.T) {
+	t.Run("not a struct", func(t *testing.T) {
+		t.Parallel()
+		assert.Nil(t, mustStruct(t, nil))
+		assert.Nil(t, mustStruct(t, "foo"))
+	})
+
+	t.Run("valid struct", func(t *testing.T) {
+		t.Parallel()
+		v := struct {
+			Name  string `json:"name" validate:"required,max=5"`
+			Email string `json:"email" validate:"email"`
+			Other string
+		}{Name: "foo"}
+		assert.Nil(t, mustStruct(t, &v))
+	})
+
+	t.Run("field names are taken from json tags", func(t *testing.T) {
+		t.Parallel()
+		v := struct {
+			Name  string `json:"full_name,omitempty" validate:"required"`
+			Email string `validate:"required"`
+		}{}
+		assert.Equal(t, Errors{
+			"full_name": "This field is required",
+			"Email":     "This field is required",
+		}, mustStruct(t, v))
+	})
+
+	t.Run("only the first failing rule is reported", func(t *testing.T) {
+		t.Parallel()
+		v := struct {
+			EmpID string `json:"employee_id" validate:"alnum,min=6"`
+		}{"a-b"}
+		assert.Equal(t, Errors{"employee_id": "Must only contain letters and digits"}, mustStruct(t, v))
+	})
+
+	t.Run("invalid rules", func(t *testing.T) {
+		testcases := []struct {
+			in  interface{}
+			msg string
+		}{
+			{struct {
+				F string `validate:"foobar"`
+			}{}, "unknown rule"},
+			{struct {
+				F string `validate:"max=foo"`
+			}{}, "invalid number"},
+			{struct {
+				F time.Time `validate:"gtefield=Foo"`
+			}{}, "unknown field"},
+		}
+		for _, tc := range testcases {
+			tc := tc
+			t.Run(tc.msg, func(t *testing.T) {
+				t.Parallel()
+				assert.Error(t, Check(tc.in))
+				errs, err := Struct(tc.in)
+				assert.Error(t, err)
+				assert.Nil(t, errs)
+			})
+		}
+	})
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+	type valid struct {
+		From time.Time `validate:"required"`
+		To   time.Time `validate:"gtefield=From"`
+	}
+	assert.NoError(t, Check(valid{}))
+	assert.NoError(t, Check(&valid{}))
+	assert.NoError(t, Check("foo"))
+}
+
+// mustStruct validates v, failing the test if its rules are invalid.
+func mustStruct(t *testing.T, v interface{}) Errors {
+	t.Helper()
+	errs, err := Struct(v)
+	if err != nil {
+		t.Fatalf("invalid rules: %v", err)
+	}
+	return errs
+}
+
+func TestRules(t *testing.T) {
+	from := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+	str := "foo"
+
+	testcases := []struct {
+		in   interface{}
+		want string
+		msg  string
+	}{
+		{struct {
+			F string `validate:"required"`
+		}{"  "}, "This field is required", "required: blank string"},
+		{struct {
+			F *string `validate:"required"`
+		}{}, "This field is required", "required: nil pointer"},
+		{struct {
+			F *string `validate:"required,max=2"`
+		}{&str}, "Must be at most 2 characters long", "pointers are dereferenced"},
+		{struct {
+			F string `validate:"email"`
+		}{"foo"}, "Must be a valid email address", "email: invalid"},
+		{struct {
+			F string `validate:"email"`
+		}{"Foo <foo@example.com>"}, "Must be a valid email address", "email: with name"},
+		{struct {
+			F string `validate:"email"`
+		}{"foo@example.com"}, "", "email: valid"},
+		{struct {
+			F string `validate:"email"`
+		}{""}, "", "email: empty optional field"},
+		{struct {
+			F string `validate:"locale"`
+		}{"ta-IN"}, "", "locale: with region"},
+		{struct {
+			F string `validate:"locale"`
+		}{"pt_BR"}, "", "locale: with underscore"},
+		{struct {
+			F string `validate:"locale"`
+		}{"english"}, "Must be a valid locale", "locale: too long"},
+		{struct {
+			F string `validate:"locale"`
+		}{"en-"}, "Must be a valid locale", "locale: empty subtag"},
+		{struct {
+			F string `validate:"min=4"`
+		}{"foo"}, "Must be at least 4 characters long", "min: string"},
+		{struct {
+			F string `validate:"max=2"`
+		}{"äöü"}, "Must be at most 2 characters long", "max: counts runes"},
+		{struct {
+			F int `validate:"min=1"`
+		}{-1}, "Must be at least 1", "min: int"},
+		{struct {
+			F int `validate:"max=10"`
+		}{11}, "Must be at most 10", "max: int"},
+		{struct {
+			F []string `validate:"max=1"`
+		}{[]string{"a", "b"}}, "Must contain at most 1 items", "max: slice"},
+		{struct {
+			F string `validate:"oneof=sick casual"`
+		}{"foo"}, "Must be one of: sick, casual", "oneof: invalid"},
+		{struct {
+			F string `validate:"oneof=sick casual"`
+		}{"sick"}, "", "oneof: valid"},
+		{struct {
+			F []string `validate:"oneof=sick casual"`
+		}{[]string{"sick", "foo"}}, "Must only contain: sick, casual", "oneof: invalid slice element"},
+		{struct {
+			F []string `validate:"oneof=sick casual"`
+		}{[]string{"sick", "casual"}}, "", "oneof: valid slice"},
+		{struct {
+			F string `validate:"timezone"`
+		}{"Asia/Kolkata"}, "", "timezone: valid"},
+		{struct {
+			F string `validate:"timezone"`
+		}{"Mars/Olympus"}, "Must be a valid time zone", "timezone: invalid"},
+		{struct {
+			F string `validate:"timezone"`
+		}{"Local"}, "Must be a valid time zone", "timezone: local"},
+		{struct {
+			From time.Time `json:"from"`
+			F    time.Time `validate:"gtefield=From"`
+		}{to, from}, "Must not be before from", "gtefield: date before"},
+		{struct {
+			From time.Time `json:"from"`
+			F    time.Time `validate:"gtefield=From"`
+		}{from, from}, "", "gtefield: same date"},
+		{struct {
+			To time.Time `json:"to"`
+			F  time.Time `validate:"ltefield=To"`
+		}{from, to}, "Must not be after to", "ltefield: date after"},
+		{struct {
+			To *time.Time `json:"to"`
+			F  *time.Time `validate:"ltefield=To"`
+		}{nil, &to}, "", "ltefield: other field is empty"},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.msg, func(t *testing.T) {
+			t.Parallel()
+			got := mustStruct(t, tc.in)
+			if tc.want == "" {
+				assert.Nil(t, got)
+				return
+			}
+			assert.Equal(t, tc.want, got["F"])
+		})
+	}
+}
@@ -0,0 +1,11 @@
+CREATE TABLE attachments
+(
+    id           SERIAL PRIMARY KEY,
+    user_id      integer REFERENCES users (id),
+    key          varchar(255) UNIQUE NOT NULL,
+    filename     varchar(255) NOT NULL,
+    content_type varchar(255) NOT NULL,
+    size         bigint       NOT NULL,
+    checksum     char(64)     NOT NULL,
+    created_at   timestamp    NOT NULL DEFAULT (now() AT TIME ZONE 'utc')
+);
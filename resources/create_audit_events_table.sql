@@ -0,0 +1,34 @@
+CREATE TABLE audit_events
+(
+    id          SERIAL PRIMARY KEY,
+    prev_hash   bytea UNIQUE NOT NULL,
+    hash        bytea UNIQUE NOT NULL,
+    actor       CITEXT       NOT NULL DEFAULT '',
+    action      varchar(64)  NOT NULL,
+    target_type varchar(64)  NOT NULL DEFAULT '',
+    target_id   CITEXT       NOT NULL DEFAULT '',
+    before      json,
+    after       json,
+    ip          varchar(45)  NOT NULL DEFAULT '',
+    request_id  varchar(128) NOT NULL DEFAULT '',
+    created_at  timestamp    NOT NULL
+);
+
+CREATE INDEX audit_events_actor_idx ON audit_events (actor);
+CREATE INDEX audit_events_target_idx ON audit_events (target_type, target_id);
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);
+
+-- the audit log is append-only; tampering by someone who can bypass this is still
+-- detected by verifying the hash chain
+CREATE FUNCTION reject_audit_event_change() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE OR TRUNCATE
+    ON audit_events
+    FOR EACH STATEMENT
+EXECUTE PROCEDURE reject_audit_event_change();
@@ -0,0 +1,11 @@
+CREATE TABLE pending_notifications
+(
+    id         SERIAL PRIMARY KEY,
+    user_id    integer REFERENCES users (id),
+    email      varchar(120) NOT NULL,
+    name       text         NOT NULL,
+    locale     varchar(35)  NOT NULL,
+    template   varchar(64)  NOT NULL,
+    data       json,
+    created_at timestamp    NOT NULL
+);
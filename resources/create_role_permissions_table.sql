@@ -0,0 +1,6 @@
+CREATE TABLE role_permissions
+(
+    role_id    integer REFERENCES roles (id),
+    permission varchar(64) NOT NULL,
+    PRIMARY KEY (role_id, permission)
+);
@@ -1,5 +1,6 @@
 CREATE TABLE roles
 (
-    id   SERIAL PRIMARY KEY,
-    name varchar(255) UNIQUE NOT NULL
+    id          SERIAL PRIMARY KEY,
+    name        varchar(255) UNIQUE NOT NULL,
+    require_2fa boolean             NOT NULL DEFAULT FALSE
 );
@@ -3,5 +3,8 @@ CREATE TABLE sessions
     id                    SERIAL PRIMARY KEY,
     user_id               integer REFERENCES users (id),
     refresh_token         bytea UNIQUE NOT NULL,
-    refresh_token_expires timestamp    NOT NULL
+    refresh_token_expires timestamp    NOT NULL,
+    created_at            timestamp    NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+    ip                    varchar(45)  NOT NULL DEFAULT '',
+    user_agent            text         NOT NULL DEFAULT ''
 );
@@ -0,0 +1,8 @@
+CREATE TABLE user_preferences
+(
+    user_id               integer PRIMARY KEY REFERENCES users (id),
+    notification_channels text[]       NOT NULL DEFAULT '{email}',
+    email_digest          boolean      NOT NULL DEFAULT FALSE,
+    locale                varchar(35)  NOT NULL DEFAULT 'en',
+    time_zone             varchar(64)  NOT NULL DEFAULT 'UTC'
+);
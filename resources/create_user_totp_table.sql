@@ -0,0 +1,17 @@
+CREATE TABLE user_totp
+(
+    user_id        integer PRIMARY KEY REFERENCES users (id),
+    secret         bytea     NOT NULL,
+    confirmed_at   timestamp,
+    last_used_step bigint    NOT NULL DEFAULT 0
+);
+
+CREATE TABLE recovery_codes
+(
+    id        SERIAL PRIMARY KEY,
+    user_id   integer REFERENCES users (id),
+    code_hash bytea     NOT NULL,
+    used_at   timestamp
+);
+
+CREATE INDEX recovery_codes_user_id_idx ON recovery_codes (user_id);
@@ -1,10 +1,19 @@
 CREATE TABLE users
 (
-    id           SERIAL PRIMARY KEY,
-    employee_id  CITEXT UNIQUE       NOT NULL,
-    name         text                NOT NULL,
-    email        varchar(120) UNIQUE NOT NULL,
-    password     varchar(128)        NOT NULL,
-    designation  varchar(255)        NOT NULL,
-    is_activated boolean DEFAULT FALSE
+    id             SERIAL PRIMARY KEY,
+    employee_id    CITEXT              NOT NULL,
+    name           text                NOT NULL,
+    email          varchar(120)        NOT NULL,
+    password       varchar(128)        NOT NULL,
+    designation    varchar(255)        NOT NULL,
+    department     varchar(255)        NOT NULL,
+    is_activated   boolean DEFAULT FALSE,
+    role_id        integer REFERENCES roles (id),
+    deactivated_at timestamp,
+    deleted_at     timestamp
 );
+
+-- uniqueness only applies to users that are not (soft) deleted, so that the
+-- history of deleted users is preserved
+CREATE UNIQUE INDEX users_employee_id_key ON users (employee_id) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX users_email_key ON users (email) WHERE deleted_at IS NULL;
@@ -0,0 +1,33 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package adeia
+
+import "context"
+
+// Role represents a role, that a User can be assigned. Roles also carry the
+// security policies of their Users.
+type Role struct {
+	// ID is the auto-incremented primary key of the Role.
+	ID int `db:"id" json:"id"`
+
+	// Name is the unique name of the Role, like "admin".
+	Name string `db:"name" json:"name"`
+
+	// Require2FA represents whether Users with the Role must use two-factor
+	// authentication to log in.
+	Require2FA bool `db:"require_2fa" json:"require_2fa"`
+}
+
+// RoleRepo is the interface for all the repository functions on the Role model.
+type RoleRepo interface {
+	GetByID(ctx context.Context, id int) (*Role, error)
+	GetPermissions(ctx context.Context, id int) ([]string, error)
+	UpdateRequire2FA(ctx context.Context, r *Role, require2FA bool) error
+}
+
+// RoleService is the interface for all the business rules on the Role model.
+type RoleService interface {
+	UpdateRole(ctx context.Context, id int, require2FA bool) (*Role, error)
+}
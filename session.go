@@ -0,0 +1,99 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package adeia
+
+import (
+	"context"
+	"time"
+)
+
+// Session represents a login session of a User. A Session lasts as long as its
+// refresh token is valid, and is used to issue short-lived access tokens.
+type Session struct {
+	// ID is the auto-incremented primary key of the Session.
+	ID int `db:"id" json:"id"`
+
+	// UserID is the ID of the User that the Session belongs to.
+	UserID int `db:"user_id" json:"-"`
+
+	// RefreshToken is the hash of the refresh token of the Session. Only the hash
+	// is stored, so that a leaked database cannot be used to hijack Sessions.
+	RefreshToken []byte `db:"refresh_token" json:"-"`
+
+	// RefreshTokenExpires is the time at which the refresh token, and so the
+	// Session, expires.
+	RefreshTokenExpires time.Time `db:"refresh_token_expires" json:"expires_at"`
+
+	// CreatedAt is the time at which the User logged in.
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+
+	// IP is the IP address that the User logged in from.
+	IP string `db:"ip" json:"ip"`
+
+	// UserAgent is the user-agent of the client that the User logged in from.
+	UserAgent string `db:"user_agent" json:"user_agent"`
+
+	// Current represents whether the Session is the one that the request was
+	// made with.
+	Current bool `db:"-" json:"current"`
+}
+
+// AuthTokens represents the tokens that are issued on login.
+type AuthTokens struct {
+	// AccessToken is a short-lived token that is sent with each request, in the
+	// Authorization header.
+	AccessToken string `json:"access_token,omitempty"`
+
+	// TokenType is the type of the AccessToken. It is always "Bearer".
+	TokenType string `json:"token_type,omitempty"`
+
+	// ExpiresIn is the number of seconds after which the AccessToken (or the
+	// MFAToken) expires.
+	ExpiresIn int `json:"expires_in"`
+
+	// RefreshToken is a long-lived token that is used to get a new AccessToken.
+	RefreshToken string `json:"refresh_token,omitempty"`
+
+	// MFAToken is a partial token, that is issued instead of the other tokens when
+	// the User must complete a second step of login. It can only be used for that step.
+	MFAToken string `json:"mfa_token,omitempty"`
+
+	// MFAStep is the second step of login that the MFAToken is for; one of
+	// MFAStepVerify or MFAStepEnrol.
+	MFAStep string `json:"mfa_step,omitempty"`
+}
+
+// SessionRepo is the interface for all the repository functions on the Session model.
+type SessionRepo interface {
+	DeleteAllByUserID(ctx context.Context, userID int, exceptID int) (rowsAffected int64, err error)
+	DeleteAllWithoutMFAByRoleID(ctx context.Context, roleID int) (rowsAffected int64, err error)
+	DeleteByIDAndUserID(ctx context.Context, id, userID int) (rowsAffected int64, err error)
+	GetAllByUserID(ctx context.Context, userID int) ([]*Session, error)
+	GetByID(ctx context.Context, id int) (*Session, error)
+	GetByRefreshToken(ctx context.Context, refreshToken []byte) (*Session, error)
+	Insert(ctx context.Context, s *Session) (lastInsertID int, err error)
+	UpdateRefreshToken(ctx context.Context, s *Session, refreshToken []byte, expires time.Time) (rowsAffected int64, err error)
+}
+
+// AuthService is the interface for all the business rules of authentication.
+type AuthService interface {
+	ActivateAccount(ctx context.Context, token, password string) error
+	Authenticate(ctx context.Context, accessToken string) (*User, *Session, error)
+	BeginLoginTOTPEnrolment(ctx context.Context, mfaToken string) (*TOTPEnrolment, error)
+	BeginTOTPEnrolment(ctx context.Context, u *User) (*TOTPEnrolment, error)
+	ChangePassword(ctx context.Context, u *User, s *Session, currentPassword, newPassword string) error
+	ConfirmLoginTOTPEnrolment(ctx context.Context, mfaToken, code, ip, userAgent string) (*TOTPConfirmation, error)
+	ConfirmTOTPEnrolment(ctx context.Context, u *User, code string) (*TOTPConfirmation, error)
+	DisableTOTP(ctx context.Context, u *User, code string) error
+	GetSessions(ctx context.Context, u *User, current *Session) ([]*Session, error)
+	Login(ctx context.Context, email, password, ip, userAgent string) (*AuthTokens, error)
+	Logout(ctx context.Context, s *Session) error
+	Permissions(ctx context.Context, u *User) ([]string, error)
+	Refresh(ctx context.Context, refreshToken string) (*AuthTokens, error)
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+	RevokeSession(ctx context.Context, u *User, id int) error
+	VerifyLogin(ctx context.Context, mfaToken, code, ip, userAgent string) (*AuthTokens, error)
+}
@@ -0,0 +1,70 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package adeia
+
+import (
+	"context"
+	"time"
+)
+
+// Steps of a login that requires a second factor.
+const (
+	// MFAStepVerify is the step where the User verifies a TOTP or recovery code.
+	MFAStepVerify = "verify"
+
+	// MFAStepEnrol is the step where the User, whose Role requires two-factor
+	// authentication, must enrol in it before logging in.
+	MFAStepEnrol = "enrol"
+)
+
+// UserTOTP represents the TOTP (time-based one-time password) secret of a User,
+// that is used for two-factor authentication.
+type UserTOTP struct {
+	// UserID is the ID of the User that the secret belongs to.
+	UserID int `db:"user_id"`
+
+	// Secret is the shared secret, that is also stored in the User's authenticator app.
+	Secret []byte `db:"secret"`
+
+	// ConfirmedAt is the time at which the User confirmed the enrolment, by entering
+	// a valid code. Two-factor authentication is enabled only once it is confirmed.
+	ConfirmedAt *time.Time `db:"confirmed_at"`
+
+	// LastUsedStep is the time step of the last accepted code, so that codes cannot
+	// be replayed.
+	LastUsedStep int64 `db:"last_used_step"`
+}
+
+// TOTPEnrolment represents a new, unconfirmed TOTP secret, to be added to an
+// authenticator app.
+type TOTPEnrolment struct {
+	// Secret is the base32-encoded secret, for entering manually.
+	Secret string `json:"secret"`
+
+	// URI is the otpauth:// URI of the secret, for showing as a QR code.
+	URI string `json:"uri"`
+}
+
+// TOTPConfirmation represents the result of confirming a TOTP enrolment.
+type TOTPConfirmation struct {
+	// RecoveryCodes are single-use codes, that can be used instead of a TOTP code
+	// when the authenticator app is lost. They are only shown once.
+	RecoveryCodes []string `json:"recovery_codes"`
+
+	// Tokens are the tokens of the new session, when the enrolment was part of login.
+	Tokens *AuthTokens `json:"tokens,omitempty"`
+}
+
+// TOTPRepo is the interface for all the repository functions on the UserTOTP model,
+// and the recovery codes.
+type TOTPRepo interface {
+	Confirm(ctx context.Context, t *UserTOTP, step int64) error
+	DeleteByUserID(ctx context.Context, userID int) error
+	GetByUserID(ctx context.Context, userID int) (*UserTOTP, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID int, hashes [][]byte) error
+	UpdateLastUsedStep(ctx context.Context, t *UserTOTP, step int64) (rowsAffected int64, err error)
+	Upsert(ctx context.Context, t *UserTOTP) error
+	UseRecoveryCode(ctx context.Context, userID int, hash []byte) (rowsAffected int64, err error)
+}
@@ -4,7 +4,14 @@
 
 package adeia
 
-import "context"
+import (
+	"context"
+	"io"
+	"time"
+
+	"adeia/pkg/errs"
+	"adeia/pkg/query"
+)
 
 // User represents the User model.
 type User struct {
@@ -32,19 +39,121 @@ type User struct {
 	// Designation represents the designation of the User.
 	Designation string `db:"designation" json:"designation"`
 
+	// Department represents the department that the User belongs to.
+	Department string `db:"department" json:"department"`
+
+	// RoleID is the ID of the Role of the User, if the User has been assigned one.
+	RoleID *int `db:"role_id" json:"role_id,omitempty"`
+
 	// IsActivated represents whether the User account is activated or not.
 	IsActivated bool `db:"is_activated" json:"is_activated"`
+
+	// DeactivatedAt represents the time at which the User was deactivated. It is nil
+	// for Users that were never deactivated. Deactivated Users cannot activate their
+	// account again using an activation token.
+	DeactivatedAt *time.Time `db:"deactivated_at" json:"deactivated_at,omitempty"`
+
+	// DeletedAt represents the time at which the User was (soft) deleted. It is nil
+	// for Users that are not deleted. Deleted Users are kept around, so that their
+	// history is preserved.
+	DeletedAt *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+
+	// Preferences represents the preferences of the User. They are stored separately,
+	// and so are only present when loaded explicitly.
+	Preferences *UserPreferences `db:"-" json:"-"`
+}
+
+// Statuses of a row in a UserImportReport.
+const (
+	UserImportCreated   = "created"
+	UserImportDuplicate = "duplicate"
+	UserImportInvalid   = "invalid"
+)
+
+// UserImportRow represents the result of importing a single row of a CSV file
+// of Users.
+type UserImportRow struct {
+	// Row is the line number of the row in the CSV file (the header is line 1).
+	Row int `json:"row"`
+
+	// Status is one of UserImportCreated, UserImportDuplicate or UserImportInvalid.
+	Status string `json:"status"`
+
+	// EmployeeID is the employee ID of the User in the row (generated, if it was empty).
+	EmployeeID string `json:"employee_id,omitempty"`
+
+	// Email is the email of the User in the row.
+	Email string `json:"email,omitempty"`
+
+	// Error describes why the row was not created.
+	Error *errs.ResponseError `json:"error,omitempty"`
+}
+
+// UserImportReport represents the per-row report of importing a CSV file of Users.
+type UserImportReport struct {
+	// DryRun represents whether the import was a dry-run. Nothing is created in a
+	// dry-run, and the created rows are only those that would have been created.
+	DryRun bool `json:"dry_run"`
+
+	Created   int `json:"created"`
+	Duplicate int `json:"duplicate"`
+	Invalid   int `json:"invalid"`
+
+	// ActivationEmailsSent is the no. of created Users that were sent an
+	// activation email. It is less than Created if sending some of them failed.
+	ActivationEmailsSent int `json:"activation_emails_sent"`
+
+	Rows []*UserImportRow `json:"rows"`
+}
+
+// UserQueryOptions represents the fields that a list of Users can be sorted and
+// filtered on.
+var UserQueryOptions = &query.Options{
+	Sortable: map[string]string{
+		"id":          "id",
+		"employee_id": "employee_id",
+		"name":        "name",
+		"email":       "email",
+		"designation": "designation",
+		"department":  "department",
+	},
+	Filterable: map[string]query.Field{
+		"designation":  {Column: "designation"},
+		"department":   {Column: "department"},
+		"is_activated": {Column: "is_activated", Type: query.Bool},
+	},
+	DefaultSort: "id",
 }
 
 // UserRepo is the interface for all the repository functions on the User model.
 type UserRepo interface {
+	Deactivate(ctx context.Context, u *User) error
+	DeleteByEmpID(ctx context.Context, empID string) (rowsAffected int64, err error)
+	GetAll(ctx context.Context, spec *query.Spec) (users []*User, nextCursor string, err error)
+	GetAllInclDeleted(ctx context.Context, spec *query.Spec) (users []*User, nextCursor string, err error)
+	GetDeletedByEmpID(ctx context.Context, empID string) (*User, error)
 	GetByEmail(ctx context.Context, email string) (*User, error)
+	GetByEmpID(ctx context.Context, empID string) (*User, error)
+	GetByID(ctx context.Context, id int) (*User, error)
 	Insert(ctx context.Context, u *User) (lastInsertID int, err error)
+	Restore(ctx context.Context, u *User) error
+	UpdatePasswordAndIsActivated(ctx context.Context, u *User, password string, isActivated bool) error
+	UpdateProfile(ctx context.Context, u *User, name, designation, department string) error
 }
 
 // UserService is the interface for all the business rules on the User model.
 type UserService interface {
-	CreateUser(ctx context.Context, name, email, empID, designation string) (*User, error)
+	CreateUser(ctx context.Context, name, email, empID, designation, department string) (*User, error)
+	DeactivateUser(ctx context.Context, empID string) (*User, error)
+	DeleteUser(ctx context.Context, empID string) error
+	GetAllUsers(ctx context.Context, spec *query.Spec, inclDeleted bool) (users []*User, nextCursor string, err error)
+	GetUserByEmpID(ctx context.Context, empID string) (*User, error)
+	GetUserPreferences(ctx context.Context, empID string) (*UserPreferences, error)
+	ImportUsers(ctx context.Context, csv io.Reader, dryRun bool) (*UserImportReport, error)
+	RestoreUser(ctx context.Context, empID string) (*User, error)
+	UnlockUser(ctx context.Context, empID string) error
+	UpdateUser(ctx context.Context, empID, name, designation, department string) (*User, error)
+	UpdateUserPreferences(ctx context.Context, empID string, patch *UserPreferencesPatch) (*UserPreferences, error)
 }
 
 // UserOpt represents the optional function to modify the User.
@@ -78,6 +187,20 @@ func WithDesignation(d string) UserOpt {
 	}
 }
 
+// WithDepartment is an UserOpt to set the Department of the User.
+func WithDepartment(d string) UserOpt {
+	return func(u *User) {
+		u.Department = d
+	}
+}
+
+// WithPreferences is an UserOpt to set the Preferences of the User.
+func WithPreferences(p *UserPreferences) UserOpt {
+	return func(u *User) {
+		u.Preferences = p
+	}
+}
+
 // WithActivation is an UserOpt to mark the User as activated.
 func WithActivation() UserOpt {
 	return func(u *User) {
@@ -98,6 +221,7 @@ func NewUser(opts ...UserOpt) *User {
 	u := &User{
 		IsActivated: false,
 		Password:    "",
+		Preferences: NewUserPreferences(),
 	}
 	for _, opt := range opts {
 		opt(u)
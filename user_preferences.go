@@ -0,0 +1,129 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package adeia
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"adeia/pkg/i18n"
+)
+
+// Notification channels, that a User can receive notifications through.
+const (
+	NotificationChannelEmail = "email"
+)
+
+// NotificationChannels is the list of all notification channels.
+var NotificationChannels = []string{NotificationChannelEmail}
+
+// DefaultTimeZone is the time zone of a User, unless they choose otherwise.
+const DefaultTimeZone = "UTC"
+
+// UserPreferences represents the profile and notification preferences of a User.
+type UserPreferences struct {
+	// UserID is the ID of the User that the preferences belong to.
+	UserID int `db:"user_id" json:"-"`
+
+	// Channels is the list of channels that the User receives notifications through.
+	// The User receives no notifications if it is empty.
+	Channels Channels `db:"notification_channels" json:"notification_channels"`
+
+	// EmailDigest represents whether the User receives a periodic digest email,
+	// instead of an email for each notification.
+	EmailDigest bool `db:"email_digest" json:"email_digest"`
+
+	// Locale represents the preferred locale of the User, like "ta-IN". Messages to
+	// the User are translated to this locale.
+	Locale string `db:"locale" json:"locale"`
+
+	// TimeZone represents the time zone of the User, as an IANA time zone name, like
+	// "Asia/Kolkata".
+	TimeZone string `db:"time_zone" json:"time_zone"`
+}
+
+// NewUserPreferences creates new UserPreferences with the defaults: notifications
+// are sent by email, without a digest, in English, and times are in UTC.
+func NewUserPreferences() *UserPreferences {
+	return &UserPreferences{
+		Channels:    Channels{NotificationChannelEmail},
+		EmailDigest: false,
+		Locale:      i18n.DefaultLocale,
+		TimeZone:    DefaultTimeZone,
+	}
+}
+
+// Location returns the time zone of the UserPreferences, that times in messages
+// to the User are shown in. It returns UTC if p is nil, or if the time zone
+// cannot be loaded.
+func (p *UserPreferences) Location() *time.Location {
+	if p == nil {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(p.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// UserPreferencesPatch represents a partial update of UserPreferences. Fields
+// that are nil are left unchanged, and so are empty Locales and TimeZones.
+type UserPreferencesPatch struct {
+	Channels    *[]string
+	EmailDigest *bool
+	Locale      *string
+	TimeZone    *string
+}
+
+// Channels is a list of notification channels, stored as a Postgres text array.
+type Channels []string
+
+// Has checks if the channel is present in Channels.
+func (c Channels) Has(channel string) bool {
+	for _, ch := range c {
+		if ch == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// Value implements the driver.Valuer interface, converting Channels to a Postgres
+// array literal, like "{email}". Channels are plain identifiers, so they need no
+// quoting.
+func (c Channels) Value() (driver.Value, error) {
+	return "{" + strings.Join(c, ",") + "}", nil
+}
+
+// Scan implements the sql.Scanner interface, parsing a Postgres array literal.
+func (c *Channels) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Channels", src)
+	}
+
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+	*c = Channels{}
+	if s != "" {
+		*c = strings.Split(s, ",")
+	}
+	return nil
+}
+
+// UserPreferencesRepo is the interface for all the repository functions on the
+// UserPreferences model.
+type UserPreferencesRepo interface {
+	GetByUserID(ctx context.Context, userID int) (*UserPreferences, error)
+	Upsert(ctx context.Context, p *UserPreferences) error
+}
@@ -0,0 +1,86 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/. */
+
+package adeia
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewUserPreferences(t *testing.T) {
+	t.Parallel()
+	want := &UserPreferences{
+		Channels:    Channels{"email"},
+		EmailDigest: false,
+		Locale:      "en",
+		TimeZone:    "UTC",
+	}
+	assert.Equal(t, want, NewUserPreferences())
+}
+
+func TestUserPreferences_Location(t *testing.T) {
+	t.Parallel()
+	var p *UserPreferences
+	assert.Equal(t, time.UTC, p.Location())
+	assert.Equal(t, time.UTC, (&UserPreferences{TimeZone: "Mars/Olympus"}).Location())
+	assert.Equal(t, "Asia/Kolkata", (&UserPreferences{TimeZone: "Asia/Kolkata"}).Location().String())
+}
+
+func TestChannels_Has(t *testing.T) {
+	t.Parallel()
+	c := Channels{"email"}
+	assert.True(t, c.Has("email"))
+	assert.False(t, c.Has("sms"))
+}
+
+func TestChannels_Value(t *testing.T) {
+	testcases := []struct {
+		in   Channels
+		want string
+		msg  string
+	}{
+		{Channels{}, "{}", "empty"},
+		{Channels{"email"}, "{email}", "single channel"},
+		{Channels{"email", "sms"}, "{email,sms}", "multiple channels"},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.msg, func(t *testing.T) {
+			t.Parallel()
+			got, err := tc.in.Value()
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestChannels_Scan(t *testing.T) {
+	testcases := []struct {
+		in   interface{}
+		want Channels
+		msg  string
+	}{
+		{"{}", Channels{}, "empty"},
+		{"{email}", Channels{"email"}, "single channel"},
+		{[]byte("{email,sms}"), Channels{"email", "sms"}, "bytes"},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.msg, func(t *testing.T) {
+			t.Parallel()
+			var got Channels
+			assert.Nil(t, got.Scan(tc.in))
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	t.Run("unsupported type", func(t *testing.T) {
+		t.Parallel()
+		var got Channels
+		assert.Error(t, got.Scan(10))
+	})
+}
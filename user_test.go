@@ -46,6 +46,24 @@ func TestWithDesignation(t *testing.T) {
 	assert.Equal(t, want, u.Designation)
 }
 
+func TestWithDepartment(t *testing.T) {
+	t.Parallel()
+	want := "foobar"
+	opt := WithDepartment(want)
+	u := &User{}
+	opt(u)
+	assert.Equal(t, want, u.Department)
+}
+
+func TestWithPreferences(t *testing.T) {
+	t.Parallel()
+	want := &UserPreferences{Locale: "ta-IN"}
+	opt := WithPreferences(want)
+	u := &User{}
+	opt(u)
+	assert.Equal(t, want, u.Preferences)
+}
+
 func TestWithIsActivated(t *testing.T) {
 	t.Parallel()
 	opt := WithActivation()
@@ -69,9 +87,10 @@ func TestNewUser(t *testing.T) {
 		want := &User{
 			IsActivated: false,
 			Password:    "",
+			Preferences: NewUserPreferences(),
 		}
 		got := NewUser()
-		assert.Equal(t, want, got, "default user should not be activated and have empty password")
+		assert.Equal(t, want, got, "default user should not be activated, have empty password and default preferences")
 	})
 
 	t.Run("with opts", func(t *testing.T) {
@@ -80,10 +99,12 @@ func TestNewUser(t *testing.T) {
 			IsActivated: true,
 			Password:    "",
 			Email:       "foobar",
+			Preferences: &UserPreferences{Locale: "ta"},
 		}
 		got := NewUser(
 			WithActivation(),
 			WithEmail("foobar"),
+			WithPreferences(&UserPreferences{Locale: "ta"}),
 		)
 		assert.Equal(t, want, got)
 	})